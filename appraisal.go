@@ -0,0 +1,165 @@
+package cc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const prefixAppraisal = "Appraisal"
+
+const roleAppraiser = "appraiser"
+
+// Appraisal is a valuation of a House submitted by a licensed appraiser
+type Appraisal struct {
+	Id          string //transaction Id
+	HouseId     string
+	AppraiserId string
+	Value       int64
+	Currency    string
+	Method      string    //e.g. sales comparison, income, cost
+	Date        time.Time //date of the valuation
+	ReportHash  string    //hex SHA-256 of the appraisal report
+	Timestamp   time.Time
+}
+
+// isSHA256 tells whether s is a hex encoded SHA-256 digest
+func isSHA256(s string) bool {
+	digest, err := hex.DecodeString(s)
+	return err == nil && len(digest) == 32
+}
+
+// Records an appraisal of a House. Appraisers only.
+func (t *HouseContractCC) SubmitAppraisal(stub shim.ChaincodeStubInterface,
+	goappraisal *Appraisal) error {
+	logger := shim.NewLogger("SubmitAppraisal")
+	logger.Infof("SubmitAppraisal: appraisal = %+v", goappraisal)
+
+	appraiserId, err := requireRole(stub, logger, roleAppraiser)
+	if err != nil {
+		return err
+	}
+
+	found, err := t.CheckHouse(stub, goappraisal.HouseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if !found {
+		mes := fmt.Sprintf("House with Id = %s does not exist", goappraisal.HouseId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	goconfig, err := getConfig(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if goappraisal.Currency == "" && len(goconfig.Currencies) > 0 {
+		goappraisal.Currency = goconfig.Currencies[0]
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	var mes string
+	switch {
+	case goappraisal.Value <= 0:
+		mes = fmt.Sprintf("appraised value must be positive: %d", goappraisal.Value)
+	case !goconfig.AllowsCurrency(goappraisal.Currency):
+		mes = fmt.Sprintf("currency %s is not allowed", goappraisal.Currency)
+	case strings.TrimSpace(goappraisal.Method) == "":
+		mes = "an appraisal method is required"
+	case goappraisal.Date.IsZero() || goappraisal.Date.After(now):
+		mes = fmt.Sprintf("invalid appraisal date: %s", goappraisal.Date)
+	case !isSHA256(goappraisal.ReportHash):
+		mes = fmt.Sprintf("report hash is not a hex SHA-256 digest: %q", goappraisal.ReportHash)
+	}
+	if mes != "" {
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	goappraisal.Id = stub.GetTxID()
+	goappraisal.AppraiserId = appraiserId
+	goappraisal.ReportHash = strings.ToLower(goappraisal.ReportHash)
+	goappraisal.Timestamp = now
+
+	err = putDoc(stub, prefixAppraisal, []string{goappraisal.HouseId, goappraisal.Id}, goappraisal)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Lists the appraisals of a House by valuation date, oldest first
+func (t *HouseContractCC) ListAppraisals(stub shim.ChaincodeStubInterface,
+	houseId string) ([]*Appraisal, error) {
+	logger := shim.NewLogger("ListAppraisals")
+	logger.Infof("ListAppraisals: House Id = %s", houseId)
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixAppraisal, []string{houseId})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	goappraisals := []*Appraisal{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		goappraisal := new(Appraisal)
+		err = unmarshalDoc(prefixAppraisal, kv.Value, goappraisal)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		goappraisals = append(goappraisals, goappraisal)
+	}
+
+	sort.Slice(goappraisals, func(i, j int) bool {
+		if goappraisals[i].Date.Equal(goappraisals[j].Date) {
+			return goappraisals[i].Timestamp.Before(goappraisals[j].Timestamp)
+		}
+		return goappraisals[i].Date.Before(goappraisals[j].Date)
+	})
+
+	logger.Infof("%d %s found", len(goappraisals), "Appraisal")
+	return goappraisals, nil
+}
+
+// Returns the appraisal of a House with the most recent valuation date
+func (t *HouseContractCC) GetLatestValuation(stub shim.ChaincodeStubInterface,
+	houseId string) (*Appraisal, error) {
+	logger := shim.NewLogger("GetLatestValuation")
+	logger.Infof("GetLatestValuation: House Id = %s", houseId)
+
+	goappraisals, err := t.ListAppraisals(stub, houseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if len(goappraisals) == 0 {
+		mes := fmt.Sprintf("House with Id = %s has no appraisal", houseId)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	return goappraisals[len(goappraisals)-1], nil
+}
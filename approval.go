@@ -0,0 +1,473 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	prefixApproval        = "Approval"
+	prefixApprovalSubject = "ApprovalSubject" //index: subject -> pending approval
+)
+
+const roleNotary = "notary"
+
+const (
+	approvalPending  = "pending"
+	approvalApproved = "approved" //all signers approved, no longer revocable
+	approvalExecuted = "executed"
+)
+
+// subjectTransferHouse is the Approval subject gating TransferHouse
+const subjectTransferHouse = "TransferHouse"
+
+// Signer is a required signature of an Approval: either a given Id (or a
+// signatory of it) or any holder of a Role
+type Signer struct {
+	Party      string //e.g. seller, buyer, notary
+	Id         string
+	Role       string
+	ApprovedBy string
+	ApprovedAt time.Time
+}
+
+// Approval collects the signatures required before Subject may take effect
+type Approval struct {
+	Id          string //transaction Id of the request
+	Subject     string
+	SubjectArgs []string
+	Price       int64 //agreed price of a transfer
+	Signers     []*Signer
+	Status      string
+	RequestedBy string
+	Expiry      time.Time
+	Timestamp   time.Time
+}
+
+func (a *Approval) complete() bool {
+	for _, signer := range a.Signers {
+		if signer.ApprovedBy == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func putApproval(stub shim.ChaincodeStubInterface, goapproval *Approval) error {
+	return putDoc(stub, prefixApproval, []string{goapproval.Id}, goapproval)
+}
+
+func getApproval(stub shim.ChaincodeStubInterface, id string) (*Approval, error) {
+	key, err := stub.CreateCompositeKey(prefixApproval, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return nil, fmt.Errorf("Approval with Id = %s was not found", id)
+	}
+
+	goapproval := new(Approval)
+	err = unmarshalDoc(prefixApproval, jsonBytes, goapproval)
+	if err != nil {
+		return nil, err
+	}
+	return goapproval, nil
+}
+
+// subjectApproval returns the latest Approval requested for a subject, or nil
+func subjectApproval(stub shim.ChaincodeStubInterface, subject string,
+	args []string) (*Approval, error) {
+	key, err := stub.CreateCompositeKey(prefixApprovalSubject, append([]string{subject}, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if id == nil {
+		return nil, nil
+	}
+	return getApproval(stub, string(id))
+}
+
+// requiresTransferApproval tells whether transferring gohouse needs an
+// Approval: co-owned Houses always do, so that every holder signs
+func requiresTransferApproval(goconfig *Config, gohouse *House) (bool, error) {
+	if len(gohouse.Shares) > 0 {
+		return true, nil
+	}
+	if goconfig.HighValueTransferThreshold <= 0 {
+		return false, nil
+	}
+
+	price, err := parsePrice(gohouse)
+	if err != nil {
+		return false, err
+	}
+	return price > goconfig.HighValueTransferThreshold, nil
+}
+
+// transferApproval returns the completed Approval for transferring gohouse
+// from sellerId to buyerId, failing if there is none
+func transferApproval(stub shim.ChaincodeStubInterface, gohouse *House,
+	sellerId string, buyerId string) (*Approval, error) {
+	goapproval, err := subjectApproval(stub, subjectTransferHouse, []string{gohouse.Id, buyerId})
+	if err != nil {
+		return nil, err
+	}
+	if goapproval == nil || goapproval.Status == approvalExecuted {
+		return nil, fmt.Errorf("transfer of House with Id = %s to %s requires an approval",
+			gohouse.Id, buyerId)
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		return nil, err
+	}
+	if !now.Before(goapproval.Expiry) {
+		return nil, fmt.Errorf("Approval with Id = %s expired at %s", goapproval.Id, goapproval.Expiry)
+	}
+	if goapproval.Status != approvalApproved {
+		return nil, fmt.Errorf("Approval with Id = %s is still missing signatures", goapproval.Id)
+	}
+	price, err := parsePrice(gohouse)
+	if err != nil {
+		return nil, err
+	}
+	if price != goapproval.Price {
+		return nil, fmt.Errorf("House with Id = %s is priced at %d, not the %d agreed in Approval with Id = %s",
+			gohouse.Id, price, goapproval.Price, goapproval.Id)
+	}
+	for _, signer := range goapproval.Signers {
+		if signer.Party == "seller" && !holdsHouse(gohouse, signer.Id) {
+			return nil, fmt.Errorf("Approval with Id = %s was signed by a former owner", goapproval.Id)
+		}
+	}
+	return goapproval, nil
+}
+
+// checkPriceNotAgreed fails if the Price of current would change while a
+// transfer of it at that price awaits or holds an Approval
+func checkPriceNotAgreed(stub shim.ChaincodeStubInterface, current *House, gohouse *House) error {
+	if gohouse.Price == current.Price {
+		return nil
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixApprovalSubject,
+		[]string{subjectTransferHouse, current.Id})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		goapproval, err := getApproval(stub, string(kv.Value))
+		if err != nil {
+			return err
+		}
+		if goapproval.Status != approvalExecuted && now.Before(goapproval.Expiry) {
+			return fmt.Errorf("the Price of House with Id = %s is agreed in Approval with Id = %s",
+				current.Id, goapproval.Id)
+		}
+	}
+	return nil
+}
+
+// approvalParties lists the Owners who signed an Approval as seller or buyer
+func approvalParties(goapproval *Approval) []string {
+	parties := []string{}
+	for _, signer := range goapproval.Signers {
+		if signer.Id != "" && signer.ApprovedBy != "" {
+			parties = append(parties, signer.Id)
+		}
+	}
+	return parties
+}
+
+// Requests the approval of every seller, the buyer and a notary for transferring a House
+func (t *HouseContractCC) RequestTransferApproval(stub shim.ChaincodeStubInterface,
+	houseId string, newownerId string) (*Approval, error) {
+	logger := shim.NewLogger("RequestTransferApproval")
+	logger.Infof("RequestTransferApproval: House Id = %s, new Owner Id = %s", houseId, newownerId)
+
+	gohouse, err := t.GetHouse(stub, houseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	found, err := t.CheckOwner(stub, newownerId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if !found {
+		mes := fmt.Sprintf("new Owner with Id = %s was not found", newownerId)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	seller := false
+	for _, share := range holders(gohouse) {
+		holder, err := t.actsFor(stub, invokerId, share.OwnerId, gohouse, operationTransfer)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		seller = seller || holder
+	}
+	buyer, err := t.actsFor(stub, invokerId, newownerId, gohouse, operationTransfer)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if !seller && !buyer {
+		mes := fmt.Sprintf("%s is neither seller nor buyer of House with Id = %s", invokerId, houseId)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	subjectArgs := []string{houseId, newownerId}
+	current, err := subjectApproval(stub, subjectTransferHouse, subjectArgs)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if current != nil && current.Status != approvalExecuted && now.Before(current.Expiry) {
+		mes := fmt.Sprintf("Approval with Id = %s is already open for this transfer", current.Id)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	goconfig, err := getConfig(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	price, err := parsePrice(gohouse)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	// every holder of a co-owned House signs as a seller, save a holder
+	// buying out the others
+	signers := []*Signer{}
+	for _, share := range holders(gohouse) {
+		if share.OwnerId != newownerId {
+			signers = append(signers, &Signer{Party: "seller", Id: share.OwnerId})
+		}
+	}
+	signers = append(signers,
+		&Signer{Party: "buyer", Id: newownerId},
+		&Signer{Party: "notary", Role: roleNotary})
+
+	goapproval := &Approval{
+		Id:          stub.GetTxID(),
+		Subject:     subjectTransferHouse,
+		SubjectArgs: subjectArgs,
+		Price:       price,
+		Signers:     signers,
+		Status:      approvalPending,
+		RequestedBy: invokerId,
+		Expiry:      now.Add(time.Duration(goconfig.TransferExpiryHours) * time.Hour),
+		Timestamp:   now,
+	}
+
+	err = putApproval(stub, goapproval)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	key, err := stub.CreateCompositeKey(prefixApprovalSubject,
+		append([]string{subjectTransferHouse}, subjectArgs...))
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	err = stub.PutState(key, []byte(goapproval.Id))
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return goapproval, nil
+}
+
+// Signs an Approval in the first open slot the invoker qualifies for
+func (t *HouseContractCC) Approve(stub shim.ChaincodeStubInterface, id string) error {
+	logger := shim.NewLogger("Approve")
+	logger.Infof("Approve: Approval Id = %s", id)
+
+	goapproval, err := getApproval(stub, id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if goapproval.Status != approvalPending || !now.Before(goapproval.Expiry) {
+		mes := fmt.Sprintf("Approval with Id = %s is no longer open", id)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	// signatories sign for corporate parties within their limit
+	var gohouse *House
+	if goapproval.Subject == subjectTransferHouse {
+		gohouse, err = t.GetHouse(stub, goapproval.SubjectArgs[0])
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+	}
+
+	var slot *Signer
+	for _, signer := range goapproval.Signers {
+		if signer.ApprovedBy == invokerId {
+			mes := fmt.Sprintf("%s has already approved Approval with Id = %s", invokerId, id)
+			logger.Warning(mes)
+			return errors.New(mes)
+		}
+		if slot != nil || signer.ApprovedBy != "" {
+			continue
+		}
+		if signer.Id != "" {
+			qualified, err := t.actsFor(stub, invokerId, signer.Id, gohouse, operationTransfer)
+			if err != nil {
+				logger.Warning(err.Error())
+				return err
+			}
+			if qualified {
+				slot = signer
+			}
+		}
+		if signer.Role != "" {
+			qualified, err := hasRole(stub, invokerId, signer.Role)
+			if err != nil {
+				logger.Warning(err.Error())
+				return err
+			}
+			if qualified {
+				slot = signer
+			}
+		}
+	}
+	if slot == nil {
+		mes := fmt.Sprintf("%s is not a required signer of Approval with Id = %s", invokerId, id)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	slot.ApprovedBy = invokerId
+	slot.ApprovedAt = now
+	if goapproval.complete() {
+		goapproval.Status = approvalApproved
+	}
+
+	err = putApproval(stub, goapproval)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Withdraws the invoker's signature while the Approval is still pending
+func (t *HouseContractCC) RevokeApproval(stub shim.ChaincodeStubInterface, id string) error {
+	logger := shim.NewLogger("RevokeApproval")
+	logger.Infof("RevokeApproval: Approval Id = %s", id)
+
+	goapproval, err := getApproval(stub, id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if goapproval.Status != approvalPending {
+		mes := fmt.Sprintf("Approval with Id = %s is %s and can no longer be revoked", id, goapproval.Status)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	revoked := false
+	for _, signer := range goapproval.Signers {
+		if signer.ApprovedBy == invokerId {
+			signer.ApprovedBy = ""
+			signer.ApprovedAt = time.Time{}
+			revoked = true
+		}
+	}
+	if !revoked {
+		mes := fmt.Sprintf("%s has not approved Approval with Id = %s", invokerId, id)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	err = putApproval(stub, goapproval)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (t *HouseContractCC) GetApproval(stub shim.ChaincodeStubInterface, id string) (*Approval, error) {
+	logger := shim.NewLogger("GetApproval")
+	logger.Infof("GetApproval: Approval Id = %s", id)
+
+	goapproval, err := getApproval(stub, id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return goapproval, nil
+}
@@ -0,0 +1,99 @@
+package cc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Calling convention of Invoke: Ids, names and other string parameters are
+// passed as plain strings (1, not "1"), timestamps as plain RFC 3339 strings,
+// numbers and booleans as JSON literals, and records such as Owner and House
+// as JSON objects or arrays.
+//
+// The older form that JSON-quotes string parameters ("\"1\"") is still
+// accepted, with a warning, until Config.RejectQuotedArgs ends the
+// deprecation window.
+
+const quotedArgWarning = "argument %d is a JSON-quoted string; pass it plain, the quoted form is deprecated"
+
+// Envelope is the payload of every response once Config.ResponseEnvelope is
+// set. Data holds what the function returns, null for functions that return
+// nothing.
+type Envelope struct {
+	Function string
+	TxId     string
+	Data     json.RawMessage
+	Error    string   `json:",omitempty"`
+	Warnings []string `json:",omitempty"`
+}
+
+// isQuoted tells the deprecated JSON-quoted string form of an argument
+func isQuoted(arg string) bool {
+	if len(arg) < 2 || arg[0] != '"' || arg[len(arg)-1] != '"' {
+		return false
+	}
+	var s string
+	return json.Unmarshal([]byte(arg), &s) == nil
+}
+
+// decodeArg decodes an Invoke argument into v following the calling
+// convention
+func decodeArg(arg string, v interface{}) error {
+	if isQuoted(arg) {
+		return json.Unmarshal([]byte(arg), v)
+	}
+	switch p := v.(type) {
+	case *string:
+		*p = arg
+		return nil
+	case *time.Time:
+		return p.UnmarshalText([]byte(arg))
+	}
+	return json.Unmarshal([]byte(arg), v)
+}
+
+// checkArgs enforces the end of the deprecation window of quoted arguments
+// and otherwise returns the warnings for them
+func checkArgs(logger *shim.ChaincodeLogger, goconfig *Config, args []string) ([]string, error) {
+	warnings := []string{}
+	for i, arg := range args {
+		if !isQuoted(arg) {
+			continue
+		}
+		if goconfig.RejectQuotedArgs {
+			mes := fmt.Sprintf("argument %d is a JSON-quoted string; quoted arguments are no longer accepted", i+1)
+			logger.Warning(mes)
+			return nil, errors.New(mes)
+		}
+		warnings = append(warnings, fmt.Sprintf(quotedArgWarning, i+1))
+	}
+	return warnings, nil
+}
+
+// envelope wraps a response in an Envelope
+func envelope(stub shim.ChaincodeStubInterface, function string, res pb.Response, warnings []string) pb.Response {
+	goenvelope := &Envelope{Function: function, TxId: stub.GetTxID(), Data: json.RawMessage("null"), Warnings: warnings}
+	if res.Status >= shim.ERRORTHRESHOLD {
+		goenvelope.Error = res.Message
+	} else if json.Valid(res.Payload) {
+		goenvelope.Data = res.Payload
+	} else if len(res.Payload) > 0 {
+		jsonpayload, err := json.Marshal(string(res.Payload))
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		goenvelope.Data = jsonpayload
+	}
+
+	jsonenvelope, err := json.Marshal(goenvelope)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	res.Payload = jsonenvelope
+	return res
+}
@@ -0,0 +1,118 @@
+package cc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const prefixHouseEdit = "HouseEdit"
+
+const (
+	editKindUpdate     = "update"
+	editKindCorrection = "correction"
+)
+
+// FieldChange records the old and new value of a single House field
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// HouseEdit is the audit record written for every edit of a House
+type HouseEdit struct {
+	HouseId    string
+	Kind       string //update (owner), correction (registrar), estate-lock or inheritance
+	EditorId   string
+	OnBehalfOf string `json:",omitempty"` //the Owner an agent or signatory edited for
+	Reason     string
+	Changes    []FieldChange
+	TxId       string
+	Timestamp  time.Time
+}
+
+// diffHouse lists the fields that differ between two versions of a House
+func diffHouse(old *House, new *House) []FieldChange {
+	changes := []FieldChange{}
+	if old.Address != new.Address {
+		changes = append(changes, FieldChange{"Address", old.Address, new.Address})
+	}
+	if old.OwnerId != new.OwnerId {
+		changes = append(changes, FieldChange{"OwnerId", old.OwnerId, new.OwnerId})
+	}
+	if old.Price != new.Price {
+		changes = append(changes, FieldChange{"Price", old.Price, new.Price})
+	}
+	if formatShares(old.Shares) != formatShares(new.Shares) {
+		changes = append(changes, FieldChange{"Shares", formatShares(old.Shares), formatShares(new.Shares)})
+	}
+	if !old.Timestamp.Equal(new.Timestamp) {
+		changes = append(changes, FieldChange{
+			"Timestamp",
+			old.Timestamp.Format(time.RFC3339Nano),
+			new.Timestamp.Format(time.RFC3339Nano),
+		})
+	}
+	return changes
+}
+
+// getTxTime returns the transaction timestamp as a time.Time
+func getTxTime(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ptypes.Timestamp(ts)
+}
+
+func addHouseEdit(stub shim.ChaincodeStubInterface, goedit *HouseEdit) error {
+	now, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	goedit.TxId = stub.GetTxID()
+	goedit.Timestamp = now
+
+	return putDoc(stub, prefixHouseEdit, []string{goedit.HouseId, goedit.TxId}, goedit)
+}
+
+// Lists the edits of a House, oldest first
+func (t *HouseContractCC) ListHouseEdits(stub shim.ChaincodeStubInterface,
+	houseId string) ([]*HouseEdit, error) {
+	logger := shim.NewLogger("ListHouseEdits")
+	logger.Infof("ListHouseEdits: House Id = %s", houseId)
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixHouseEdit, []string{houseId})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	goedits := []*HouseEdit{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		goedit := new(HouseEdit)
+		err = unmarshalDoc(prefixHouseEdit, kv.Value, goedit)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		goedits = append(goedits, goedit)
+	}
+
+	sort.Slice(goedits, func(i, j int) bool {
+		return goedits[i].Timestamp.Before(goedits[j].Timestamp)
+	})
+
+	logger.Infof("%d %s found", len(goedits), "HouseEdit")
+	return goedits, nil
+}
@@ -0,0 +1,280 @@
+package cc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// maxBatchSize bounds the number of items registered in one transaction
+const maxBatchSize = 1000
+
+// BatchItemError reports why a single batch item was rejected
+type BatchItemError struct {
+	Index int
+	Id    string
+	Error string
+}
+
+// BatchReport is the outcome of a batch registration. Nothing is written
+// unless Failed is empty and DryRun is false.
+type BatchReport struct {
+	DryRun bool
+	Total  int
+	Added  int
+	Failed []*BatchItemError
+}
+
+// batch validates the items of one batch against the ledger and against the
+// items accepted before them. GetState does not see writes of the running
+// transaction, so the accepted items are tracked here.
+type batch struct {
+	t      *HouseContractCC
+	stub   shim.ChaincodeStubInterface
+	owners map[string]*Owner
+	houses map[string]bool
+}
+
+func newBatch(t *HouseContractCC, stub shim.ChaincodeStubInterface) *batch {
+	return &batch{
+		t:      t,
+		stub:   stub,
+		owners: map[string]*Owner{},
+		houses: map[string]bool{},
+	}
+}
+
+func (b *batch) validateOwner(goowner *Owner) error {
+	if goowner == nil {
+		return errors.New("Owner is null")
+	}
+	if goowner.Id == "" {
+		return errors.New("Owner Id is empty")
+	}
+	if err := checkOwnerType(goowner); err != nil {
+		return err
+	}
+	if err := checkOwnerOrg(goowner); err != nil {
+		return err
+	}
+	if b.owners[goowner.Id] != nil {
+		return fmt.Errorf("an Owner with Id = %s appears more than once in the batch", goowner.Id)
+	}
+
+	found, err := b.t.CheckOwner(b.stub, goowner.Id)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("an Owner with Id = %s already exists", goowner.Id)
+	}
+
+	b.owners[goowner.Id] = goowner
+	return nil
+}
+
+// ownerOrg returns the organization of an Owner of the batch or the ledger
+func (b *batch) ownerOrg(ownerId string) (string, error) {
+	if goowner := b.owners[ownerId]; goowner != nil {
+		return goowner.Org, nil
+	}
+	goowner, err := b.t.GetOwner(b.stub, ownerId)
+	if err != nil {
+		return "", err
+	}
+	return goowner.Org, nil
+}
+
+func (b *batch) validateHouse(gohouse *House) error {
+	if gohouse == nil {
+		return errors.New("House is null")
+	}
+	if gohouse.Id == "" {
+		return errors.New("House Id is empty")
+	}
+	if b.houses[gohouse.Id] {
+		return fmt.Errorf("House with Id = %s appears more than once in the batch", gohouse.Id)
+	}
+	// co-ownership only comes from estates
+	gohouse.Shares = nil
+	if _, err := parsePrice(gohouse); err != nil {
+		return err
+	}
+
+	found, err := b.t.CheckHouse(b.stub, gohouse.Id)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("House with Id = %s already exists", gohouse.Id)
+	}
+
+	if b.owners[gohouse.OwnerId] == nil {
+		ok, err := b.t.ValidateHouse(b.stub, gohouse)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("Owner with Id = %s of House with Id = %s does not exist",
+				gohouse.OwnerId, gohouse.Id)
+		}
+	}
+
+	b.houses[gohouse.Id] = true
+	return nil
+}
+
+func checkBatchSize(logger *shim.ChaincodeLogger, size int) error {
+	if size > maxBatchSize {
+		mes := fmt.Sprintf("batch too large: %d given, at most %d allowed", size, maxBatchSize)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	return nil
+}
+
+// Adds Owners all at once. With dryRun the Owners are only validated.
+func (t *HouseContractCC) AddOwnersBatch(stub shim.ChaincodeStubInterface,
+	goowners []*Owner, dryRun bool) (*BatchReport, error) {
+	logger := shim.NewLogger("AddOwnersBatch")
+	logger.Infof("AddOwnersBatch: %d Owners, dry run = %t", len(goowners), dryRun)
+
+	if err := checkBatchSize(logger, len(goowners)); err != nil {
+		return nil, err
+	}
+
+	b := newBatch(t, stub)
+	report := &BatchReport{DryRun: dryRun, Total: len(goowners), Failed: []*BatchItemError{}}
+	for i, goowner := range goowners {
+		if err := b.validateOwner(goowner); err != nil {
+			id := ""
+			if goowner != nil {
+				id = goowner.Id
+			}
+			report.Failed = append(report.Failed, &BatchItemError{i, id, err.Error()})
+		}
+	}
+
+	if len(report.Failed) > 0 {
+		mes := fmt.Sprintf("%d of %d Owners are invalid", len(report.Failed), len(goowners))
+		logger.Warning(mes)
+		if dryRun {
+			return report, nil
+		}
+		return report, errors.New(mes)
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	for _, goowner := range goowners {
+		err := putOwner(stub, goowner)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+	}
+	report.Added = len(goowners)
+
+	logger.Infof("%d %s added", report.Added, "Owner")
+	return report, nil
+}
+
+// Adds Houses all at once. The optional goowners are registered first, so
+// the Houses may belong to them. With dryRun everything is only validated.
+func (t *HouseContractCC) AddHousesBatch(stub shim.ChaincodeStubInterface,
+	gohouses []*House, dryRun bool, goowners []*Owner) (*BatchReport, error) {
+	logger := shim.NewLogger("AddHousesBatch")
+	logger.Infof("AddHousesBatch: %d Houses, %d Owners, dry run = %t",
+		len(gohouses), len(goowners), dryRun)
+
+	if err := checkBatchSize(logger, len(gohouses)+len(goowners)); err != nil {
+		return nil, err
+	}
+
+	// Owners come first in the item numbering, then Houses
+	b := newBatch(t, stub)
+	total := len(goowners) + len(gohouses)
+	report := &BatchReport{DryRun: dryRun, Total: total, Failed: []*BatchItemError{}}
+	for i, goowner := range goowners {
+		if err := b.validateOwner(goowner); err != nil {
+			id := ""
+			if goowner != nil {
+				id = goowner.Id
+			}
+			report.Failed = append(report.Failed, &BatchItemError{i, id, err.Error()})
+		}
+	}
+	for i, gohouse := range gohouses {
+		if err := b.validateHouse(gohouse); err != nil {
+			id := ""
+			if gohouse != nil {
+				id = gohouse.Id
+			}
+			report.Failed = append(report.Failed,
+				&BatchItemError{len(goowners) + i, id, err.Error()})
+		}
+	}
+
+	if len(report.Failed) > 0 {
+		mes := fmt.Sprintf("%d of %d items are invalid", len(report.Failed), total)
+		logger.Warning(mes)
+		if dryRun {
+			return report, nil
+		}
+		return report, errors.New(mes)
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	for _, goowner := range goowners {
+		err := putOwner(stub, goowner)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+	}
+	for _, gohouse := range gohouses {
+		err := putHouse(stub, gohouse)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		org, err := b.ownerOrg(gohouse.OwnerId)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		err = setHouseEndorsement(stub, gohouse.Id, org)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+	}
+	report.Added = total
+
+	logger.Infof("%d %s added", report.Added, "item")
+	return report, nil
+}
+
+// batchResponse returns the report as the payload, or as the error message
+// if the batch was rejected, so that clients get the per-item errors.
+func batchResponse(report *BatchReport, err error) pb.Response {
+	if report == nil {
+		return shim.Error(err.Error())
+	}
+
+	jsonreport, jerr := json.Marshal(report)
+	if jerr != nil {
+		return shim.Error(jerr.Error())
+	}
+	if err != nil {
+		return shim.Error(string(jsonreport))
+	}
+
+	return shim.Success(jsonreport)
+}
@@ -0,0 +1,490 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	prefixBroker     = "Broker"
+	prefixListing    = "Listing"
+	prefixReceivable = "Receivable"
+)
+
+// fees in Config.FeePercentages paid to the brokers of a sale
+const (
+	feeSellerCommission = "seller-commission"
+	feeBuyerCommission  = "buyer-commission"
+)
+
+const (
+	listingActive    = "active"
+	listingSold      = "sold"
+	listingWithdrawn = "withdrawn"
+)
+
+const (
+	sideSeller = "seller"
+	sideBuyer  = "buyer"
+)
+
+// Broker is a licensed real-estate broker
+type Broker struct {
+	Id            string //enrollment ID
+	Name          string
+	LicenseNo     string
+	LicenseExpiry time.Time
+	RegisteredBy  string
+	Timestamp     time.Time
+}
+
+// Licensed tells whether the broker's license is valid at now
+func (b *Broker) Licensed(now time.Time) bool {
+	return now.Before(b.LicenseExpiry)
+}
+
+// Listing offers a House for sale, optionally through a broker
+type Listing struct {
+	Id          string //transaction Id
+	HouseId     string
+	SellerId    string
+	AskingPrice int64
+	BrokerId    string
+	Status      string
+	ListedBy    string
+	Timestamp   time.Time
+	ClosedAt    time.Time
+	TransferId  string
+}
+
+// SaleBrokers names the brokers on each side of a transfer
+type SaleBrokers struct {
+	SellerBrokerId string
+	BuyerBrokerId  string
+}
+
+// Receivable is a broker commission owed by a party of a transfer
+type Receivable struct {
+	BrokerId    string
+	TransferId  string
+	Side        string //seller or buyer
+	HouseId     string
+	PayerId     string
+	Price       int64
+	RatePercent float64
+	Amount      int64
+	Timestamp   time.Time
+}
+
+func getBroker(stub shim.ChaincodeStubInterface, id string) (*Broker, error) {
+	key, err := stub.CreateCompositeKey(prefixBroker, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return nil, fmt.Errorf("Broker with Id = %s was not found", id)
+	}
+
+	gobroker := new(Broker)
+	err = unmarshalDoc(prefixBroker, jsonBytes, gobroker)
+	if err != nil {
+		return nil, err
+	}
+	return gobroker, nil
+}
+
+// checkLicensed fails unless brokerId is a registered broker with a valid license
+func checkLicensed(stub shim.ChaincodeStubInterface, brokerId string) error {
+	gobroker, err := getBroker(stub, brokerId)
+	if err != nil {
+		return err
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	if !gobroker.Licensed(now) {
+		return fmt.Errorf("the license of Broker with Id = %s expired at %s",
+			brokerId, gobroker.LicenseExpiry.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func putListing(stub shim.ChaincodeStubInterface, golisting *Listing) error {
+	return putDoc(stub, prefixListing, []string{golisting.HouseId, golisting.Id}, golisting)
+}
+
+// listListings lists the Listings of a House, or of all Houses if houseId is empty
+func listListings(stub shim.ChaincodeStubInterface, houseId string) ([]*Listing, error) {
+	attributes := []string{}
+	if houseId != "" {
+		attributes = append(attributes, houseId)
+	}
+	iter, err := stub.GetStateByPartialCompositeKey(prefixListing, attributes)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	golistings := []*Listing{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		golisting := new(Listing)
+		err = unmarshalDoc(prefixListing, kv.Value, golisting)
+		if err != nil {
+			return nil, err
+		}
+		golistings = append(golistings, golisting)
+	}
+	return golistings, nil
+}
+
+// activeListing returns the active Listing of a House, or nil
+func activeListing(stub shim.ChaincodeStubInterface, houseId string) (*Listing, error) {
+	golistings, err := listListings(stub, houseId)
+	if err != nil {
+		return nil, err
+	}
+	for _, golisting := range golistings {
+		if golisting.Status == listingActive {
+			return golisting, nil
+		}
+	}
+	return nil, nil
+}
+
+// saleBrokers resolves the brokers of the sale of a House: those named, or
+// for the seller side the broker of the active Listing. Every broker must be
+// licensed. The active Listing is returned as well, if there is one.
+func saleBrokers(stub shim.ChaincodeStubInterface, houseId string,
+	gobrokers *SaleBrokers) (*SaleBrokers, *Listing, error) {
+	golisting, err := activeListing(stub, houseId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sides := &SaleBrokers{}
+	if gobrokers != nil {
+		*sides = *gobrokers
+	}
+	if sides.SellerBrokerId == "" && golisting != nil {
+		sides.SellerBrokerId = golisting.BrokerId
+	}
+
+	for _, brokerId := range []string{sides.SellerBrokerId, sides.BuyerBrokerId} {
+		if brokerId == "" {
+			continue
+		}
+		if err := checkLicensed(stub, brokerId); err != nil {
+			return nil, nil, err
+		}
+	}
+	return sides, golisting, nil
+}
+
+// settleBrokers closes the Listing of the transferred House, if any, and
+// records the commissions owed to the brokers of the sale
+func settleBrokers(stub shim.ChaincodeStubInterface, receipt *TransferReceipt,
+	sides *SaleBrokers, golisting *Listing) ([]*Receivable, error) {
+	goconfig, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	if golisting != nil {
+		golisting.Status = listingSold
+		golisting.ClosedAt = now
+		golisting.TransferId = stub.GetTxID()
+		err = putListing(stub, golisting)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	goreceivables := []*Receivable{}
+	for _, side := range []struct {
+		brokerId string
+		name     string
+		payerId  string
+		fee      string
+	}{
+		{sides.SellerBrokerId, sideSeller, receipt.SellerId, feeSellerCommission},
+		{sides.BuyerBrokerId, sideBuyer, receipt.BuyerId, feeBuyerCommission},
+	} {
+		if side.brokerId == "" {
+			continue
+		}
+
+		goreceivable := &Receivable{
+			BrokerId:    side.brokerId,
+			TransferId:  stub.GetTxID(),
+			Side:        side.name,
+			HouseId:     receipt.HouseId,
+			PayerId:     side.payerId,
+			Price:       receipt.Price,
+			RatePercent: goconfig.FeePercentages[side.fee],
+			Timestamp:   now,
+		}
+		goreceivable.Amount = percentOf(goreceivable.Price, goreceivable.RatePercent)
+
+		err = putDoc(stub, prefixReceivable,
+			[]string{goreceivable.BrokerId, goreceivable.TransferId, goreceivable.Side}, goreceivable)
+		if err != nil {
+			return nil, err
+		}
+		goreceivables = append(goreceivables, goreceivable)
+	}
+	return goreceivables, nil
+}
+
+// Registers a licensed broker. Registrar only.
+func (t *HouseContractCC) RegisterBroker(stub shim.ChaincodeStubInterface,
+	gobroker *Broker) error {
+	logger := shim.NewLogger("RegisterBroker")
+	logger.Infof("RegisterBroker: broker = %+v", gobroker)
+
+	registrarId, err := requireRole(stub, logger, roleRegistrar)
+	if err != nil {
+		return err
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	var mes string
+	switch {
+	case gobroker.Id == "":
+		mes = "Broker Id is empty"
+	case strings.TrimSpace(gobroker.LicenseNo) == "":
+		mes = "a license number is required"
+	case !gobroker.Licensed(now):
+		mes = fmt.Sprintf("the license has expired: %s", gobroker.LicenseExpiry)
+	}
+	if mes != "" {
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	// registering again renews the license
+	gobroker.RegisteredBy = registrarId
+	gobroker.Timestamp = now
+	err = putDoc(stub, prefixBroker, []string{gobroker.Id}, gobroker)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (t *HouseContractCC) GetBroker(stub shim.ChaincodeStubInterface, id string) (*Broker, error) {
+	logger := shim.NewLogger("GetBroker")
+	logger.Infof("GetBroker: Id = %s", id)
+
+	gobroker, err := getBroker(stub, id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return gobroker, nil
+}
+
+// Offers a House for sale, optionally through a broker. Owner or agent only.
+func (t *HouseContractCC) ListHouseForSale(stub shim.ChaincodeStubInterface,
+	houseId string, askingPrice int64, brokerId string) (*Listing, error) {
+	logger := shim.NewLogger("ListHouseForSale")
+	logger.Infof("ListHouseForSale: House Id = %s, asking price = %d, broker = %s",
+		houseId, askingPrice, brokerId)
+
+	gohouse, err := t.GetHouse(stub, houseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if err := checkNotFrozen(stub, houseId); err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if err := checkEstateLock(stub, gohouse); err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	invokerId, err := t.requireActsFor(stub, logger, gohouse.OwnerId, gohouse, operationListForSale)
+	if err != nil {
+		return nil, err
+	}
+
+	if askingPrice <= 0 {
+		mes := fmt.Sprintf("asking price must be positive: %d", askingPrice)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+	if brokerId != "" {
+		if err := checkLicensed(stub, brokerId); err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+	}
+
+	current, err := activeListing(stub, houseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if current != nil {
+		mes := fmt.Sprintf("House with Id = %s is already listed for sale", houseId)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	golisting := &Listing{
+		Id:          stub.GetTxID(),
+		HouseId:     houseId,
+		SellerId:    gohouse.OwnerId,
+		AskingPrice: askingPrice,
+		BrokerId:    brokerId,
+		Status:      listingActive,
+		ListedBy:    invokerId,
+		Timestamp:   now,
+	}
+	err = putListing(stub, golisting)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return golisting, nil
+}
+
+// Takes a House off the market. Owner or agent only.
+func (t *HouseContractCC) WithdrawListing(stub shim.ChaincodeStubInterface, houseId string) error {
+	logger := shim.NewLogger("WithdrawListing")
+	logger.Infof("WithdrawListing: House Id = %s", houseId)
+
+	gohouse, err := t.GetHouse(stub, houseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if _, err := t.requireActsFor(stub, logger, gohouse.OwnerId, gohouse, operationListForSale); err != nil {
+		return err
+	}
+
+	golisting, err := activeListing(stub, houseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if golisting == nil {
+		mes := fmt.Sprintf("House with Id = %s is not listed for sale", houseId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	golisting.Status = listingWithdrawn
+	golisting.ClosedAt = now
+	err = putListing(stub, golisting)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Lists the active Listings a broker handles, oldest first
+func (t *HouseContractCC) ListBrokerListings(stub shim.ChaincodeStubInterface,
+	brokerId string) ([]*Listing, error) {
+	logger := shim.NewLogger("ListBrokerListings")
+	logger.Infof("ListBrokerListings: Broker Id = %s", brokerId)
+
+	golistings, err := listListings(stub, "")
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	active := []*Listing{}
+	for _, golisting := range golistings {
+		if golisting.BrokerId == brokerId && golisting.Status == listingActive {
+			active = append(active, golisting)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Timestamp.Before(active[j].Timestamp)
+	})
+
+	logger.Infof("%d %s found", len(active), "Listing")
+	return active, nil
+}
+
+// Lists the closed deals of a broker with the commission owed, oldest first
+func (t *HouseContractCC) ListBrokerDeals(stub shim.ChaincodeStubInterface,
+	brokerId string) ([]*Receivable, error) {
+	logger := shim.NewLogger("ListBrokerDeals")
+	logger.Infof("ListBrokerDeals: Broker Id = %s", brokerId)
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixReceivable, []string{brokerId})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	goreceivables := []*Receivable{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		goreceivable := new(Receivable)
+		err = unmarshalDoc(prefixReceivable, kv.Value, goreceivable)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		goreceivables = append(goreceivables, goreceivable)
+	}
+
+	sort.Slice(goreceivables, func(i, j int) bool {
+		return goreceivables[i].Timestamp.Before(goreceivables[j].Timestamp)
+	})
+
+	logger.Infof("%d %s found", len(goreceivables), "Receivable")
+	return goreceivables, nil
+}
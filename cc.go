@@ -15,8 +15,36 @@ import (
 const prefixOwner = "Owner"
 const prefixHouse = "House"
 
+const (
+	ownerTypePerson    = "person"
+	ownerTypeCorporate = "corporate"
+)
+
 type Owner struct {
-	Id string //식별자
+	Id   string //식별자
+	Type string `json:",omitempty"` //person (default) or corporate
+	Org  string `json:",omitempty"` //MSP ID of the organization the Owner belongs to
+}
+
+// IsCorporate tells corporate owners from natural persons
+func (o *Owner) IsCorporate() bool {
+	return o.Type == ownerTypeCorporate
+}
+
+func checkOwnerType(goowner *Owner) error {
+	switch goowner.Type {
+	case "", ownerTypePerson, ownerTypeCorporate:
+		return nil
+	}
+	return fmt.Errorf("unknown Owner type: %q", goowner.Type)
+}
+
+// checkOwnerOrg fails if the organization of an Owner is not given
+func checkOwnerOrg(goowner *Owner) error {
+	if goowner.Org == "" {
+		return fmt.Errorf("the Org of Owner with Id = %s is empty", goowner.Id)
+	}
+	return nil
 }
 
 type House struct {
@@ -25,21 +53,91 @@ type House struct {
 	OwnerId   string
 	Price     string
 	Timestamp time.Time
+	Shares    []*Share `json:",omitempty"` //co-owners; OwnerId is the largest of them
 }
 
 type HouseContract interface {
 	AddOwner(shim.ChaincodeStubInterface, *Owner) error
 	CheckOwner(shim.ChaincodeStubInterface, string) (bool, error)
+	GetOwner(shim.ChaincodeStubInterface, string) (*Owner, error)
 	ListOwners(shim.ChaincodeStubInterface) ([]*Owner, error)
+	AddOwnersBatch(shim.ChaincodeStubInterface, []*Owner, bool) (*BatchReport, error)
 
 	AddHouse(shim.ChaincodeStubInterface, *House) error
+	AddHousesBatch(shim.ChaincodeStubInterface, []*House, bool, []*Owner) (*BatchReport, error)
 	CheckHouse(shim.ChaincodeStubInterface, string) (bool, error)
 	ValidateHouse(shim.ChaincodeStubInterface, *House) (bool, error)
 	GetHouse(shim.ChaincodeStubInterface, string) (*House, error)
 	UpdateHouse(shim.ChaincodeStubInterface, *House) error
+	CorrectHouse(shim.ChaincodeStubInterface, *House, string) error
 	ListHouses(shim.ChaincodeStubInterface) ([]*House, error)
+	ListHouseEdits(shim.ChaincodeStubInterface, string) ([]*HouseEdit, error)
 
 	TransferHouse(shim.ChaincodeStubInterface, string, string) error
+	TransferHouseWithBrokers(shim.ChaincodeStubInterface, string, string, *SaleBrokers) error
+	RequestTransferApproval(shim.ChaincodeStubInterface, string, string) (*Approval, error)
+	Approve(shim.ChaincodeStubInterface, string) error
+	RevokeApproval(shim.ChaincodeStubInterface, string) error
+	GetApproval(shim.ChaincodeStubInterface, string) (*Approval, error)
+
+	UpdateTaxSchedule(shim.ChaincodeStubInterface, *TaxSchedule) error
+	GetTaxSchedule(shim.ChaincodeStubInterface) (*TaxSchedule, error)
+	ListOwnerTransferReceipts(shim.ChaincodeStubInterface, string) ([]*TransferReceipt, error)
+	ListTransferReceipts(shim.ChaincodeStubInterface, time.Time, time.Time) ([]*TransferReceipt, error)
+
+	SubmitAppraisal(shim.ChaincodeStubInterface, *Appraisal) error
+	ListAppraisals(shim.ChaincodeStubInterface, string) ([]*Appraisal, error)
+	GetLatestValuation(shim.ChaincodeStubInterface, string) (*Appraisal, error)
+
+	AttachDocument(shim.ChaincodeStubInterface, string, string, string, string, string) error
+	VerifyDocument(shim.ChaincodeStubInterface, string, string) (*Document, error)
+	GetHouseDetails(shim.ChaincodeStubInterface, string) (*HouseDetails, error)
+
+	FreezeHouse(shim.ChaincodeStubInterface, string, string, time.Time) error
+	UnfreezeHouse(shim.ChaincodeStubInterface, string, string) error
+	ListHolds(shim.ChaincodeStubInterface, string) ([]*Hold, error)
+
+	RecordDeath(shim.ChaincodeStubInterface, string, string) error
+	DistributeEstate(shim.ChaincodeStubInterface, string, *DistributionPlan) error
+	GetEstate(shim.ChaincodeStubInterface, string) (*Estate, error)
+
+	RegisterBroker(shim.ChaincodeStubInterface, *Broker) error
+	GetBroker(shim.ChaincodeStubInterface, string) (*Broker, error)
+	ListHouseForSale(shim.ChaincodeStubInterface, string, int64, string) (*Listing, error)
+	WithdrawListing(shim.ChaincodeStubInterface, string) error
+	ListBrokerListings(shim.ChaincodeStubInterface, string) ([]*Listing, error)
+	ListBrokerDeals(shim.ChaincodeStubInterface, string) ([]*Receivable, error)
+
+	ProposeLease(shim.ChaincodeStubInterface, *Lease) error
+	AcceptLease(shim.ChaincodeStubInterface, string) error
+	GetLease(shim.ChaincodeStubInterface, string) (*Lease, error)
+	RecordRentPayment(shim.ChaincodeStubInterface, string, int64) (*RentPayment, error)
+	ConfirmRentPayment(shim.ChaincodeStubInterface, string, string) (*RentPayment, error)
+	ListRentPayments(shim.ChaincodeStubInterface, string) ([]*RentPayment, error)
+	GetRentSchedule(shim.ChaincodeStubInterface, string) ([]*Installment, error)
+	ListTenantArrears(shim.ChaincodeStubInterface, string) ([]*Arrears, error)
+	ListHouseArrears(shim.ChaincodeStubInterface, string) ([]*Arrears, error)
+
+	SetSignatory(shim.ChaincodeStubInterface, *Signatory) error
+	GrantDelegation(shim.ChaincodeStubInterface, string, string, *DelegationScope, time.Time) error
+	RevokeDelegation(shim.ChaincodeStubInterface, string, string) error
+	ListDelegations(shim.ChaincodeStubInterface, string) ([]*Delegation, error)
+	RemoveSignatory(shim.ChaincodeStubInterface, string, string) error
+	ListSignatories(shim.ChaincodeStubInterface, string) ([]*Signatory, error)
+	ListSignatoryChanges(shim.ChaincodeStubInterface, string) ([]*SignatoryChange, error)
+
+	ListStaleKeys(shim.ChaincodeStubInterface, string, string, int) (*StaleKeys, error)
+	MigrateBatch(shim.ChaincodeStubInterface, []string) (*MigrationResult, error)
+	GetMigrationStatus(shim.ChaincodeStubInterface) ([]*MigrationStatus, error)
+
+	GetRegistryDigest(shim.ChaincodeStubInterface) (*RegistryDigest, error)
+	ExportRegistry(shim.ChaincodeStubInterface) (*RegistryExport, error)
+
+	UpdateConfig(shim.ChaincodeStubInterface, *Config) error
+	GetConfig(shim.ChaincodeStubInterface) (*Config, error)
+	GrantRole(shim.ChaincodeStubInterface, *Role) error
+	RevokeRole(shim.ChaincodeStubInterface, *Role) error
+	Enroll(shim.ChaincodeStubInterface, []*Enrollment) error
 }
 
 type HouseContractCC struct {
@@ -60,6 +158,46 @@ func checkLen(logger *shim.ChaincodeLogger, expected int, args []string) error {
 
 func (t *HouseContractCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
 	logger := shim.NewLogger("housecontract")
+
+	// a JSON Config replaces the stored one; without it an upgrade keeps the
+	// stored Config and a fresh instantiation starts from the defaults
+	_, args := stub.GetFunctionAndParameters()
+	if len(args) > 0 {
+		goconfig, err := parseConfig([]byte(args[0]))
+		if err != nil {
+			logger.Warning(err.Error())
+			return shim.Error(err.Error())
+		}
+		err = putConfig(stub, goconfig)
+		if err != nil {
+			logger.Warning(err.Error())
+			return shim.Error(err.Error())
+		}
+		logger.Info("config replaced")
+	} else {
+		found, err := hasConfig(stub)
+		if err != nil {
+			logger.Warning(err.Error())
+			return shim.Error(err.Error())
+		}
+		if !found {
+			err = putConfig(stub, defaultConfig())
+			if err != nil {
+				logger.Warning(err.Error())
+				return shim.Error(err.Error())
+			}
+			logger.Info("default config stored")
+		}
+	}
+
+	// Houses stored before the OwnerHouse index are looked up by scanning
+	// until they are migrated
+	err := recordHouseIndex(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return shim.Error(err.Error())
+	}
+
 	logger.Info("chaincode initialized")
 	return shim.Success([]byte{})
 }
@@ -75,6 +213,36 @@ func (t *HouseContractCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 	logger.Infof("function name = %s", function)
 	logger.Infof("args  = %s", args)
 
+	goconfig, err := getConfig(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return shim.Error(err.Error())
+	}
+	if !goconfig.Enabled(function) {
+		mes := fmt.Sprintf("method %s is disabled", function)
+		logger.Warning(mes)
+		return shim.Error(mes)
+	}
+
+	warnings, err := checkArgs(logger, goconfig, args)
+	if err != nil {
+		res := shim.Error(err.Error())
+		if goconfig.ResponseEnvelope {
+			return envelope(stub, function, res, nil)
+		}
+		return res
+	}
+
+	res := t.dispatch(stub, logger, function, args)
+	if goconfig.ResponseEnvelope {
+		return envelope(stub, function, res, warnings)
+	}
+	return res
+}
+
+// dispatch decodes the arguments of an Invoke function and runs it
+func (t *HouseContractCC) dispatch(stub shim.ChaincodeStubInterface, logger *shim.ChaincodeLogger,
+	function string, args []string) pb.Response {
 	switch function {
 	case "AddOwner":
 		if err := checkLen(logger, 1, args); err != nil {
@@ -82,7 +250,7 @@ func (t *HouseContractCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		}
 
 		goowner := new(Owner)
-		err := json.Unmarshal([]byte(args[0]), goowner)
+		err := decodeArg(args[0], goowner)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
@@ -94,6 +262,29 @@ func (t *HouseContractCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 
 		return shim.Success([]byte{})
 
+	case "GetOwner":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goowner, err := t.GetOwner(stub, ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonowner, err := json.Marshal(goowner)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonowner)
+
 	case "ListOwners":
 		goowners, err := t.ListOwners(stub)
 		if err != nil {
@@ -107,13 +298,34 @@ func (t *HouseContractCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 
 		return shim.Success(jsonowners)
 
+	case "AddOwnersBatch":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goowners := []*Owner{}
+		err := decodeArg(args[0], &goowners)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var dryRun bool
+		if len(args) > 1 {
+			err = decodeArg(args[1], &dryRun)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+
+		return batchResponse(t.AddOwnersBatch(stub, goowners, dryRun))
+
 	case "AddHouse":
 		if err := checkLen(logger, 1, args); err != nil {
 			return shim.Error(err.Error())
 		}
 
 		gohouse := new(House)
-		err := json.Unmarshal([]byte(args[0]), gohouse)
+		err := decodeArg(args[0], gohouse)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
@@ -125,6 +337,35 @@ func (t *HouseContractCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 
 		return shim.Success([]byte{})
 
+	case "AddHousesBatch":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gohouses := []*House{}
+		err := decodeArg(args[0], &gohouses)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var dryRun bool
+		if len(args) > 1 {
+			err = decodeArg(args[1], &dryRun)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+
+		goowners := []*Owner{}
+		if len(args) > 2 {
+			err = decodeArg(args[2], &goowners)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+
+		return batchResponse(t.AddHousesBatch(stub, gohouses, dryRun, goowners))
+
 	case "ListHouses":
 		gohouses, err := t.ListHouses(stub)
 		if err != nil {
@@ -139,9 +380,12 @@ func (t *HouseContractCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		return shim.Success(jsonhouses)
 
 	case "ListOwnerIdHouses":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
 
 		var ownerId string
-		err := json.Unmarshal([]byte(args[0]), &ownerId)
+		err := decodeArg(args[0], &ownerId)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
@@ -164,12 +408,12 @@ func (t *HouseContractCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		}
 
 		var houseId string
-		err := json.Unmarshal([]byte(args[0]), &houseId)
+		err := decodeArg(args[0], &houseId)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
 
-		gohouse, err := t.GetHouse(stub, houseId)
+		gohouse, err := t.GetHouseDetails(stub, houseId)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
@@ -187,7 +431,7 @@ func (t *HouseContractCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		}
 
 		gohouse := new(House)
-		err := json.Unmarshal([]byte(args[0]), gohouse)
+		err := decodeArg(args[0], gohouse)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
@@ -199,199 +443,1289 @@ func (t *HouseContractCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 
 		return shim.Success([]byte{})
 
-	case "TransferHouse":
+	case "CorrectHouse":
 		if err := checkLen(logger, 2, args); err != nil {
 			return shim.Error(err.Error())
 		}
 
-		var houseId, newownerId string
-		err := json.Unmarshal([]byte(args[0]), &houseId)
+		gohouse := new(House)
+		err := decodeArg(args[0], gohouse)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
 
-		err = json.Unmarshal([]byte(args[1]), &newownerId)
+		var reason string
+		err = decodeArg(args[1], &reason)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
 
-		err = t.TransferHouse(stub, houseId, newownerId)
+		err = t.CorrectHouse(stub, gohouse, reason)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
-		return shim.Success([]byte{})
-	}
 
-	mes := fmt.Sprintf("Unknown method: %s", function)
-	logger.Warning(mes)
-	return shim.Error(mes)
-}
+		return shim.Success([]byte{})
 
-func (t *HouseContractCC) AddOwner(stub shim.ChaincodeStubInterface,
-	goowner *Owner) error {
-	logger := shim.NewLogger("AddOwner")
-	logger.Infof("AddOwner:  Id = %s", goowner.Id)
+	case "ListHouseEdits":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
 
-	found, err := t.CheckOwner(stub, goowner.Id)
-	if err != nil {
-		logger.Warning(err.Error())
-		return err
-	}
-	if found {
-		mes := fmt.Sprintf("an Owner with Id = %s alerady exists", goowner.Id)
-		logger.Warning(mes)
-		return errors.New(mes)
-	}
+		var houseId string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	jsonowner, err := json.Marshal(goowner)
-	if err != nil {
-		logger.Warning(err.Error())
-		return err
-	}
+		goedits, err := t.ListHouseEdits(stub, houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	key, err := stub.CreateCompositeKey(prefixOwner, []string{goowner.Id})
-	if err != nil {
-		logger.Warning(err.Error())
-		return err
-	}
+		jsonedits, err := json.Marshal(goedits)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	err = stub.PutState(key, jsonowner)
-	if err != nil {
-		logger.Warning(err.Error())
-		return err
-	}
+		return shim.Success(jsonedits)
 
-	return nil
-}
+	case "TransferHouse":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
 
-func (t *HouseContractCC) CheckOwner(stub shim.ChaincodeStubInterface,
-	id string) (bool, error) {
-	logger := shim.NewLogger("CheckOwner")
-	logger.Infof("CheckOwner:  Id = %s", id)
+		var houseId, newownerId string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	// creates a composite key
-	key, err := stub.CreateCompositeKey(prefixOwner, []string{id})
-	if err != nil {
-		logger.Warning(err.Error())
-		return false, err
-	}
+		err = decodeArg(args[1], &newownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	// loads from the State DB
-	jsonBytes, err := stub.GetState(key)
-	if err != nil {
-		logger.Warning(err.Error())
-		return false, err
-	}
+		// the brokers of the sale are optional
+		var gobrokers *SaleBrokers
+		if len(args) > 2 {
+			gobrokers = new(SaleBrokers)
+			err = decodeArg(args[2], gobrokers)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
 
-	// returns successfully
-	return jsonBytes != nil, nil
-}
+		err = t.TransferHouseWithBrokers(stub, houseId, newownerId, gobrokers)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte{})
+	case "UpdateTaxSchedule":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
 
-// Lists Owners
-func (t *HouseContractCC) ListOwners(stub shim.ChaincodeStubInterface) ([]*Owner,
-	error) {
-	logger := shim.NewLogger("ListOwners")
-	logger.Info("ListOwners")
+		goschedule := new(TaxSchedule)
+		err := decodeArg(args[0], goschedule)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	// executes a range query, which returns an iterator
-	iter, err := stub.GetStateByPartialCompositeKey(prefixOwner, []string{})
-	if err != nil {
-		logger.Warning(err.Error())
-		return nil, err
-	}
+		err = t.UpdateTaxSchedule(stub, goschedule)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	// will close the iterator when returned from this method
-	defer iter.Close()
-	goowners := []*Owner{}
+		return shim.Success([]byte{})
 
-	// loops over the iterator
-	for iter.HasNext() {
-		kv, err := iter.Next()
+	case "GetTaxSchedule":
+		goschedule, err := t.GetTaxSchedule(stub)
 		if err != nil {
-			logger.Warning(err.Error())
-			return nil, err
+			return shim.Error(err.Error())
 		}
-		goowner := new(Owner)
-		err = json.Unmarshal(kv.Value, goowner)
+
+		jsonschedule, err := json.Marshal(goschedule)
 		if err != nil {
-			logger.Warning(err.Error())
-			return nil, err
+			return shim.Error(err.Error())
 		}
-		logger.Infof("Owner Id = %s", goowner.Id)
-		goowners = append(goowners, goowner)
-	}
-
-	logger.Infof("%d %s found", len(goowners), "Owner")
 
-	return goowners, nil
-}
+		return shim.Success(jsonschedule)
 
-func (t *HouseContractCC) AddHouse(stub shim.ChaincodeStubInterface,
-	gohouse *House) error {
-	logger := shim.NewLogger("AddHouse")
-	logger.Infof("AddHouse:  Id = %s", gohouse.Id)
+	case "ListOwnerTransferReceipts":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
 
-	key, err := stub.CreateCompositeKey(prefixHouse, []string{gohouse.Id})
-	if err != nil {
-		logger.Warning(err.Error())
-		return err
-	}
+		var ownerId string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	found, err := t.CheckHouse(stub, gohouse.Id)
-	if err != nil {
-		logger.Warning(err.Error())
-		return err
-	}
-	if found {
-		mes := fmt.Sprintf("House with Id = %s already exists", gohouse.Id)
-		logger.Warning(mes)
-		return errors.New(mes)
-	}
+		receipts, err := t.ListOwnerTransferReceipts(stub, ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	ok, err := t.ValidateHouse(stub, gohouse)
-	if err != nil {
-		logger.Warning(err.Error())
-		return err
-	}
-	if !ok {
-		mes := "Validation of the House failed"
-		logger.Warning(mes)
-		return errors.New(mes)
-	}
+		jsonreceipts, err := json.Marshal(receipts)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-	jsonhouse, err := json.Marshal(gohouse)
-	if err != nil {
-		logger.Warning(err.Error())
-		return err
-	}
+		return shim.Success(jsonreceipts)
 
-	err = stub.PutState(key, jsonhouse)
-	if err != nil {
-		logger.Warning(err.Error())
-		return err
-	}
+	case "ListTransferReceipts":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
 
-	return nil
-}
+		var from, to time.Time
+		err := decodeArg(args[0], &from)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 
-func (t *HouseContractCC) CheckHouse(stub shim.ChaincodeStubInterface, id string) (bool,
-	error) {
-	logger := shim.NewLogger("CheckHouse")
-	logger.Infof("CheckHouse: Id = %s", id)
+		err = decodeArg(args[1], &to)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		receipts, err := t.ListTransferReceipts(stub, from, to)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonreceipts, err := json.Marshal(receipts)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonreceipts)
+
+	case "SubmitAppraisal":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goappraisal := new(Appraisal)
+		err := decodeArg(args[0], goappraisal)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.SubmitAppraisal(stub, goappraisal)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "ListAppraisals":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goappraisals, err := t.ListAppraisals(stub, houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonappraisals, err := json.Marshal(goappraisals)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonappraisals)
+
+	case "GetLatestValuation":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goappraisal, err := t.GetLatestValuation(stub, houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonappraisal, err := json.Marshal(goappraisal)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonappraisal)
+
+	case "AttachDocument":
+		if err := checkLen(logger, 5, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId, docType, sha256, uri, mimeType string
+		for i, v := range []*string{&houseId, &docType, &sha256, &uri, &mimeType} {
+			err := decodeArg(args[i], v)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+
+		err := t.AttachDocument(stub, houseId, docType, sha256, uri, mimeType)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "VerifyDocument":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId, sha256 string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = decodeArg(args[1], &sha256)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		godocument, err := t.VerifyDocument(stub, houseId, sha256)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsondocument, err := json.Marshal(godocument)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsondocument)
+
+	case "RequestTransferApproval":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId, newownerId string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = decodeArg(args[1], &newownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goapproval, err := t.RequestTransferApproval(stub, houseId, newownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonapproval, err := json.Marshal(goapproval)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonapproval)
+
+	case "Approve", "RevokeApproval":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var id string
+		err := decodeArg(args[0], &id)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		if function == "Approve" {
+			err = t.Approve(stub, id)
+		} else {
+			err = t.RevokeApproval(stub, id)
+		}
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "GetApproval":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var id string
+		err := decodeArg(args[0], &id)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goapproval, err := t.GetApproval(stub, id)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonapproval, err := json.Marshal(goapproval)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonapproval)
+
+	case "FreezeHouse":
+		if err := checkLen(logger, 3, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId, orderRef string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = decodeArg(args[1], &orderRef)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var until time.Time
+		err = decodeArg(args[2], &until)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.FreezeHouse(stub, houseId, orderRef, until)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "UnfreezeHouse":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId, orderRef string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = decodeArg(args[1], &orderRef)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.UnfreezeHouse(stub, houseId, orderRef)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "ListHolds":
+		// without a House Id the holds on all Houses are listed
+		var houseId string
+		if len(args) > 0 {
+			err := decodeArg(args[0], &houseId)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+
+		goholds, err := t.ListHolds(stub, houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonholds, err := json.Marshal(goholds)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonholds)
+
+	case "RecordDeath":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId, certificateHash string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = decodeArg(args[1], &certificateHash)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.RecordDeath(stub, ownerId, certificateHash)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "DistributeEstate":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goplan := new(DistributionPlan)
+		err = decodeArg(args[1], goplan)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.DistributeEstate(stub, ownerId, goplan)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "GetEstate":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goestate, err := t.GetEstate(stub, ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonestate, err := json.Marshal(goestate)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonestate)
+
+	case "SetSignatory":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gosignatory := new(Signatory)
+		err := decodeArg(args[0], gosignatory)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.SetSignatory(stub, gosignatory)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "RemoveSignatory":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId, id string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = decodeArg(args[1], &id)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.RemoveSignatory(stub, ownerId, id)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "ListSignatories":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gosignatories, err := t.ListSignatories(stub, ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonsignatories, err := json.Marshal(gosignatories)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonsignatories)
+
+	case "ListSignatoryChanges":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gochanges, err := t.ListSignatoryChanges(stub, ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonchanges, err := json.Marshal(gochanges)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonchanges)
+
+	case "GrantDelegation":
+		if err := checkLen(logger, 4, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId, agentId string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = decodeArg(args[1], &agentId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goscope := new(DelegationScope)
+		err = decodeArg(args[2], goscope)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var expiry time.Time
+		err = decodeArg(args[3], &expiry)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.GrantDelegation(stub, ownerId, agentId, goscope, expiry)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "RevokeDelegation":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId, agentId string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = decodeArg(args[1], &agentId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.RevokeDelegation(stub, ownerId, agentId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "ListDelegations":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var ownerId string
+		err := decodeArg(args[0], &ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		godelegations, err := t.ListDelegations(stub, ownerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsondelegations, err := json.Marshal(godelegations)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsondelegations)
+
+	case "RegisterBroker":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gobroker := new(Broker)
+		err := decodeArg(args[0], gobroker)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.RegisterBroker(stub, gobroker)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "GetBroker":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var id string
+		err := decodeArg(args[0], &id)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gobroker, err := t.GetBroker(stub, id)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonbroker, err := json.Marshal(gobroker)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonbroker)
+
+	case "ListHouseForSale":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var askingPrice int64
+		err = decodeArg(args[1], &askingPrice)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		// the broker is optional
+		var brokerId string
+		if len(args) > 2 {
+			err = decodeArg(args[2], &brokerId)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+
+		golisting, err := t.ListHouseForSale(stub, houseId, askingPrice, brokerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonlisting, err := json.Marshal(golisting)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonlisting)
+
+	case "WithdrawListing":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.WithdrawListing(stub, houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "ListBrokerListings":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var brokerId string
+		err := decodeArg(args[0], &brokerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		golistings, err := t.ListBrokerListings(stub, brokerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonlistings, err := json.Marshal(golistings)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonlistings)
+
+	case "ListBrokerDeals":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var brokerId string
+		err := decodeArg(args[0], &brokerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goreceivables, err := t.ListBrokerDeals(stub, brokerId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonreceivables, err := json.Marshal(goreceivables)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonreceivables)
+
+	case "ProposeLease":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		golease := new(Lease)
+		err := decodeArg(args[0], golease)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.ProposeLease(stub, golease)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "AcceptLease":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var leaseId string
+		err := decodeArg(args[0], &leaseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.AcceptLease(stub, leaseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "GetLease":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var id string
+		err := decodeArg(args[0], &id)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		golease, err := t.GetLease(stub, id)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonlease, err := json.Marshal(golease)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonlease)
+
+	case "RecordRentPayment":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var leaseId string
+		err := decodeArg(args[0], &leaseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var amount int64
+		err = decodeArg(args[1], &amount)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gopayment, err := t.RecordRentPayment(stub, leaseId, amount)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonpayment, err := json.Marshal(gopayment)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonpayment)
+
+	case "ConfirmRentPayment":
+		if err := checkLen(logger, 2, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var leaseId string
+		err := decodeArg(args[0], &leaseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var txId string
+		err = decodeArg(args[1], &txId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gopayment, err := t.ConfirmRentPayment(stub, leaseId, txId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonpayment, err := json.Marshal(gopayment)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonpayment)
+
+	case "ListRentPayments":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var leaseId string
+		err := decodeArg(args[0], &leaseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gopayments, err := t.ListRentPayments(stub, leaseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonpayments, err := json.Marshal(gopayments)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonpayments)
+
+	case "GetRentSchedule":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var leaseId string
+		err := decodeArg(args[0], &leaseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goinstallments, err := t.GetRentSchedule(stub, leaseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsoninstallments, err := json.Marshal(goinstallments)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsoninstallments)
+
+	case "ListTenantArrears":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var tenantId string
+		err := decodeArg(args[0], &tenantId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goarrears, err := t.ListTenantArrears(stub, tenantId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonarrears, err := json.Marshal(goarrears)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonarrears)
+
+	case "ListHouseArrears":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var houseId string
+		err := decodeArg(args[0], &houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goarrears, err := t.ListHouseArrears(stub, houseId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonarrears, err := json.Marshal(goarrears)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonarrears)
+
+	case "ListStaleKeys":
+		if err := checkLen(logger, 3, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var docType string
+		err := decodeArg(args[0], &docType)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var bookmark string
+		err = decodeArg(args[1], &bookmark)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var pageSize int
+		err = decodeArg(args[2], &pageSize)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		stale, err := t.ListStaleKeys(stub, docType, bookmark, pageSize)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonstale, err := json.Marshal(stale)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonstale)
+
+	case "MigrateBatch":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		keys := []string{}
+		err := decodeArg(args[0], &keys)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		result, err := t.MigrateBatch(stub, keys)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonresult, err := json.Marshal(result)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonresult)
+
+	case "GetMigrationStatus":
+		statuses, err := t.GetMigrationStatus(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonstatuses, err := json.Marshal(statuses)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonstatuses)
+	case "GetRegistryDigest":
+		godigest, err := t.GetRegistryDigest(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsondigest, err := json.Marshal(godigest)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsondigest)
+
+	case "ExportRegistry":
+		goexport, err := t.ExportRegistry(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonexport, err := json.Marshal(goexport)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonexport)
+
+	case "UpdateConfig":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goconfig, err := parseConfig([]byte(args[0]))
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.UpdateConfig(stub, goconfig)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "GetConfig":
+		goconfig, err := t.GetConfig(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		jsonconfig, err := json.Marshal(goconfig)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success(jsonconfig)
+
+	case "GrantRole", "RevokeRole":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		gorole := new(Role)
+		err := decodeArg(args[0], gorole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		if function == "GrantRole" {
+			err = t.GrantRole(stub, gorole)
+		} else {
+			err = t.RevokeRole(stub, gorole)
+		}
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+
+	case "Enroll":
+		if err := checkLen(logger, 1, args); err != nil {
+			return shim.Error(err.Error())
+		}
+
+		goenrollments := []*Enrollment{}
+		err := decodeArg(args[0], &goenrollments)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		err = t.Enroll(stub, goenrollments)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		return shim.Success([]byte{})
+	}
+
+	mes := fmt.Sprintf("Unknown method: %s", function)
+	logger.Warning(mes)
+	return shim.Error(mes)
+}
 
-	key, err := stub.CreateCompositeKey(prefixHouse, []string{id})
+func (t *HouseContractCC) AddOwner(stub shim.ChaincodeStubInterface,
+	goowner *Owner) error {
+	logger := shim.NewLogger("AddOwner")
+	logger.Infof("AddOwner:  Id = %s", goowner.Id)
+
+	err := RegisterOwner(NewLedgerStore(stub), goowner)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// putOwner stores an Owner without any checks
+func putOwner(stub shim.ChaincodeStubInterface, goowner *Owner) error {
+	return NewLedgerStore(stub).PutOwner(goowner)
+}
+
+func (t *HouseContractCC) CheckOwner(stub shim.ChaincodeStubInterface,
+	id string) (bool, error) {
+	logger := shim.NewLogger("CheckOwner")
+	logger.Infof("CheckOwner:  Id = %s", id)
+
+	goowner, err := NewLedgerStore(stub).GetOwner(id)
 	if err != nil {
 		logger.Warning(err.Error())
 		return false, err
 	}
 
-	jsonBytes, err := stub.GetState(key)
+	return goowner != nil, nil
+}
+
+func (t *HouseContractCC) GetOwner(stub shim.ChaincodeStubInterface,
+	id string) (*Owner, error) {
+	logger := shim.NewLogger("GetOwner")
+
+	goowner, err := NewLedgerStore(stub).GetOwner(id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if goowner == nil {
+		mes := fmt.Sprintf("Owner with Id = %s was not found", id)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	logger.Infof("Owner Id = %s", goowner.Id)
+	return goowner, nil
+}
+
+// Lists Owners
+func (t *HouseContractCC) ListOwners(stub shim.ChaincodeStubInterface) ([]*Owner,
+	error) {
+	logger := shim.NewLogger("ListOwners")
+	logger.Info("ListOwners")
+
+	goowners, err := NewLedgerStore(stub).ListOwners()
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	logger.Infof("%d %s found", len(goowners), "Owner")
+
+	return goowners, nil
+}
+
+func (t *HouseContractCC) AddHouse(stub shim.ChaincodeStubInterface,
+	gohouse *House) error {
+	logger := shim.NewLogger("AddHouse")
+	logger.Infof("AddHouse:  Id = %s", gohouse.Id)
+
+	store := NewLedgerStore(stub)
+	goowner, err := RegisterHouse(store, store, gohouse)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	err = setHouseEndorsement(stub, gohouse.Id, goowner.Org)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (t *HouseContractCC) CheckHouse(stub shim.ChaincodeStubInterface, id string) (bool,
+	error) {
+	logger := shim.NewLogger("CheckHouse")
+	logger.Infof("CheckHouse: Id = %s", id)
+
+	gohouse, err := NewLedgerStore(stub).GetHouse(id)
 	if err != nil {
 		logger.Warning(err.Error())
 		return false, err
 	}
 
-	return jsonBytes != nil, nil
+	return gohouse != nil, nil
 }
 
 func (t *HouseContractCC) ValidateHouse(stub shim.ChaincodeStubInterface,
@@ -412,49 +1746,67 @@ func (t *HouseContractCC) GetHouse(stub shim.ChaincodeStubInterface,
 	id string) (*House, error) {
 	logger := shim.NewLogger("GetHouse")
 
-	key, err := stub.CreateCompositeKey(prefixHouse, []string{id})
+	gohouse, err := NewLedgerStore(stub).GetHouse(id)
 	if err != nil {
 		logger.Warning(err.Error())
 		return nil, err
 	}
-
-	jsonBytes, err := stub.GetState(key)
-	if err != nil {
-		logger.Warning(err.Error())
-		return nil, err
-	}
-	if jsonBytes == nil {
+	if gohouse == nil {
 		mes := fmt.Sprintf("House with Id = %s was not found", id)
 		logger.Warning(mes)
 		return nil, errors.New(mes)
 	}
 
-	gohouse := new(House)
-	err = json.Unmarshal(jsonBytes, gohouse)
-	if err != nil {
-		logger.Warning(err.Error())
-		return nil, err
-	}
-
 	logger.Infof("House Id = %s, OwnerId = %s", gohouse.Id, gohouse.OwnerId)
 	return gohouse, nil
 }
 
+// Updates a House on behalf of its owner. Only the Price and Timestamp may
+// change; ownership changes go through TransferHouse and every other field
+// through a registrar's CorrectHouse.
 func (t *HouseContractCC) UpdateHouse(stub shim.ChaincodeStubInterface,
 	gohouse *House) error {
 	logger := shim.NewLogger("UpdateHouse")
 	logger.Infof("UpdateHouse: house = %+v", gohouse)
 
-	found, err := t.CheckHouse(stub, gohouse.Id)
+	current, err := t.GetHouse(stub, gohouse.Id)
 	if err != nil {
 		logger.Warning(err.Error())
 		return err
 	}
-	if !found {
-		mes := fmt.Sprintf("House with Id = %s does not exist", gohouse.Id)
+	if gohouse.OwnerId != current.OwnerId {
+		mes := fmt.Sprintf("the owner of House with Id = %s cannot be changed by UpdateHouse, use TransferHouse", gohouse.Id)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	if gohouse.Address != current.Address {
+		mes := fmt.Sprintf("the Address of House with Id = %s can only be corrected by a registrar", gohouse.Id)
 		logger.Warning(mes)
 		return errors.New(mes)
 	}
+	if err := checkNotFrozen(stub, gohouse.Id); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if err := checkEstateLock(stub, current); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if _, err := parsePrice(gohouse); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if err := checkPriceNotAgreed(stub, current, gohouse); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	// shares only change through transfers and estates
+	gohouse.Shares = current.Shares
+
+	invokerId, err := t.requireActsFor(stub, logger, current.OwnerId, current, operationUpdate)
+	if err != nil {
+		return err
+	}
 
 	ok, err := t.ValidateHouse(stub, gohouse)
 	if err != nil {
@@ -467,19 +1819,104 @@ func (t *HouseContractCC) UpdateHouse(stub shim.ChaincodeStubInterface,
 		return errors.New(mes)
 	}
 
-	key, err := stub.CreateCompositeKey(prefixHouse, []string{gohouse.Id})
+	changes := diffHouse(current, gohouse)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	err = putHouse(stub, gohouse)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	goedit := &HouseEdit{
+		HouseId:  gohouse.Id,
+		Kind:     editKindUpdate,
+		EditorId: invokerId,
+		Changes:  changes,
+	}
+	if invokerId != current.OwnerId {
+		goedit.OnBehalfOf = current.OwnerId
+	}
+	err = addHouseEdit(stub, goedit)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Corrects registry data of a House. Registrar only; the reason is recorded
+// in the edit history together with the changed fields.
+func (t *HouseContractCC) CorrectHouse(stub shim.ChaincodeStubInterface,
+	gohouse *House, reason string) error {
+	logger := shim.NewLogger("CorrectHouse")
+	logger.Infof("CorrectHouse: house = %+v, reason = %s", gohouse, reason)
+
+	if strings.TrimSpace(reason) == "" {
+		mes := "a reason is required to correct a House"
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	registrarId, err := requireRole(stub, logger, roleRegistrar)
+	if err != nil {
+		return err
+	}
+
+	current, err := t.GetHouse(stub, gohouse.Id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if gohouse.OwnerId != current.OwnerId {
+		mes := fmt.Sprintf("the owner of House with Id = %s cannot be changed by CorrectHouse, use TransferHouse", gohouse.Id)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	if _, err := parsePrice(gohouse); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if err := checkPriceNotAgreed(stub, current, gohouse); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	gohouse.Shares = current.Shares
+
+	ok, err := t.ValidateHouse(stub, gohouse)
 	if err != nil {
 		logger.Warning(err.Error())
 		return err
 	}
+	if !ok {
+		mes := "Validation of the House failed"
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	changes := diffHouse(current, gohouse)
+	if len(changes) == 0 {
+		mes := fmt.Sprintf("nothing to correct in House with Id = %s", gohouse.Id)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
 
-	jsonhouse, err := json.Marshal(gohouse)
+	err = putHouse(stub, gohouse)
 	if err != nil {
 		logger.Warning(err.Error())
 		return err
 	}
 
-	err = stub.PutState(key, jsonhouse)
+	err = addHouseEdit(stub, &HouseEdit{
+		HouseId:  gohouse.Id,
+		Kind:     editKindCorrection,
+		EditorId: registrarId,
+		Reason:   reason,
+		Changes:  changes,
+	})
 	if err != nil {
 		logger.Warning(err.Error())
 		return err
@@ -488,35 +1925,22 @@ func (t *HouseContractCC) UpdateHouse(stub shim.ChaincodeStubInterface,
 	return nil
 }
 
+// putHouse stores a House without any checks
+func putHouse(stub shim.ChaincodeStubInterface, gohouse *House) error {
+	return NewLedgerStore(stub).PutHouse(gohouse)
+}
+
 func (t *HouseContractCC) ListHouses(stub shim.ChaincodeStubInterface) ([]*House,
 	error) {
 	logger := shim.NewLogger("ListHouses")
 	logger.Info("ListHouses")
 
-	iter, err := stub.GetStateByPartialCompositeKey(prefixHouse, []string{})
+	gohouses, err := NewLedgerStore(stub).ListHouses()
 	if err != nil {
 		logger.Warning(err.Error())
 		return nil, err
 	}
 
-	defer iter.Close()
-
-	gohouses := []*House{}
-	for iter.HasNext() {
-		kv, err := iter.Next()
-		if err != nil {
-			logger.Warning(err.Error())
-			return nil, err
-		}
-		gohouse := new(House)
-		err = json.Unmarshal(kv.Value, gohouse)
-		if err != nil {
-			logger.Warning(err.Error())
-			return nil, err
-		}
-		gohouses = append(gohouses, gohouse)
-	}
-
 	logger.Infof("%d %s found", len(gohouses), "House")
 	return gohouses, nil
 }
@@ -526,60 +1950,174 @@ func (t *HouseContractCC) ListOwnerIdHouses(stub shim.ChaincodeStubInterface, ow
 	logger := shim.NewLogger("ListOwnerIdHouses")
 	logger.Info("ListOwnerIdHouses")
 
-	// executes a range query, which returns an iterator
-	iter, err := stub.GetStateByPartialCompositeKey(prefixHouse, []string{})
+	store := NewLedgerStore(stub)
+	var gohouses []*House
+	var err error
+	if strings.Index(ownerId, "admin") != -1 {
+		gohouses, err = store.ListHouses()
+	} else {
+		gohouses, err = store.ListOwnerHouses(ownerId)
+	}
 	if err != nil {
 		logger.Warning(err.Error())
 		return nil, err
 	}
 
-	// will close the iterator when returned from this method
-	defer iter.Close()
+	logger.Infof("%d %s found", len(gohouses), "House")
+	return gohouses, nil
+}
+
+func (t *HouseContractCC) TransferHouse(stub shim.ChaincodeStubInterface, houseId string,
+	newownerId string) error {
+	return t.TransferHouseWithBrokers(stub, houseId, newownerId, nil)
+}
+
+// Transfers a House and settles the commissions of the brokers of the sale.
+// Without a seller broker the broker of the active Listing is used.
+func (t *HouseContractCC) TransferHouseWithBrokers(stub shim.ChaincodeStubInterface, houseId string,
+	newownerId string, gobrokers *SaleBrokers) error {
+	logger := shim.NewLogger("TransferHouse")
+	logger.Infof("TransferHouse:  House Id = %s, new Owner Id = %s, brokers = %+v",
+		houseId, newownerId, gobrokers)
+
+	gohouse, err := t.GetHouse(stub, houseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if err := checkNotFrozen(stub, houseId); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if err := checkEstateLock(stub, gohouse); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if err := checkNotDeceased(stub, newownerId); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	sellerId := gohouse.OwnerId
+	seller, err := t.GetOwner(stub, sellerId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	// persons sell in person, corporations through their signatories, and
+	// either through an agent under a Delegation; whoever sells for the
+	// Owner is recorded as well
+	invokerId, err := t.requireActsFor(stub, logger, sellerId, gohouse, operationTransfer)
+	if err != nil {
+		return err
+	}
+	agentId := ""
+	if invokerId != sellerId {
+		agentId = invokerId
+	}
+
+	goconfig, err := getConfig(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	godocuments, err := transferDocuments(stub, gohouse.Id, goconfig.RequiredTransferDocuments)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
 
-	// loops over the iterator
-	gohouses := []*House{}
-	for iter.HasNext() {
-		kv, err := iter.Next()
+	required, err := requiresTransferApproval(goconfig, gohouse)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	var goapproval *Approval
+	if required {
+		goapproval, err = transferApproval(stub, gohouse, sellerId, newownerId)
 		if err != nil {
 			logger.Warning(err.Error())
-			return nil, err
+			return err
 		}
-		gohouse := new(House)
-		err = json.Unmarshal(kv.Value, gohouse)
+	}
+
+	// the co-owners of a House agree to its transfer by signing the Approval
+	consenting := []string{sellerId}
+	if goapproval != nil {
+		consenting = append(consenting, approvalParties(goapproval)...)
+	}
+	buyer, err := Reassign(NewLedgerStore(stub), gohouse, newownerId, consenting)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	sides, golisting, err := saleBrokers(stub, houseId, gobrokers)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	receipt, err := computeTransferReceipt(stub, gohouse, sellerId, buyer)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	receipt.AgentId = agentId
+
+	err = putHouse(stub, gohouse)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	// the buyer's organization takes over endorsing the House
+	if buyer.Org != seller.Org {
+		err = setHouseEndorsement(stub, gohouse.Id, buyer.Org)
 		if err != nil {
 			logger.Warning(err.Error())
-			return nil, err
-		}
-		if strings.Index(ownerId, "admin") != -1 {
-			gohouses = append(gohouses, gohouse)
-		} else {
-			if gohouse.OwnerId == ownerId {
-				gohouses = append(gohouses, gohouse)
-			}
+			return err
 		}
 	}
 
-	logger.Infof("%d %s found", len(gohouses), "House")
-	return gohouses, nil
-}
+	// the documents the transfer relied on are anchored to it
+	for _, godocument := range godocuments {
+		godocument.TransferId = stub.GetTxID()
+		receipt.Documents = append(receipt.Documents, godocument.SHA256)
+		err = putDocument(stub, godocument)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+	}
 
-func (t *HouseContractCC) TransferHouse(stub shim.ChaincodeStubInterface, houseId string,
-	newownerId string) error {
-	logger := shim.NewLogger("TransferHouse")
-	logger.Infof("TransferHouse:  House Id = %s, new Owner Id = %s", houseId, newownerId)
+	// an Approval is good for a single transfer
+	if goapproval != nil {
+		receipt.ApprovalId = goapproval.Id
+		goapproval.Status = approvalExecuted
+		err = putApproval(stub, goapproval)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+	}
 
-	gohouse, err := t.GetHouse(stub, houseId)
+	goreceivables, err := settleBrokers(stub, receipt, sides, golisting)
 	if err != nil {
 		logger.Warning(err.Error())
 		return err
 	}
+	for _, goreceivable := range goreceivables {
+		logger.Infof("%s commission of %s = %d", goreceivable.Side, goreceivable.BrokerId, goreceivable.Amount)
+	}
 
-	gohouse.OwnerId = newownerId
-
-	err = t.UpdateHouse(stub, gohouse)
+	err = addTransferReceipt(stub, receipt)
 	if err != nil {
 		logger.Warning(err.Error())
 		return err
 	}
+	logger.Infof("tax = %d, registry fee = %d", receipt.Tax, receipt.RegistryFee)
+
 	return nil
 }
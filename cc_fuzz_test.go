@@ -0,0 +1,93 @@
+//go:build go1.18
+// +build go1.18
+
+package cc_test
+
+import (
+	"encoding/json"
+	"housecontract/cc"
+	"housecontract/client"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// FuzzInvoke feeds arbitrary arguments to every Invoke function. Whatever the
+// arguments, Invoke must answer with a success or an error, never panic.
+func FuzzInvoke(f *testing.F) {
+	seeds := [][]string{
+		{}, {one}, {alice}, {house1}, {twoOwners}, {twoHousesBatch},
+		{one, bobid}, {house1d, reason}, {one, `"order-1"`, `"2030-01-01T00:00:00Z"`},
+		{`{"Id":"Kim","Org":"Org1MSP"}`, `3000`, `"Kim"`}, {`null`}, {`"`}, {`[]`, `{}`, ``, `0`},
+	}
+	for i := range client.Functions {
+		for _, seed := range seeds {
+			args := append(seed, "", "", "", "")
+			f.Add(uint8(i), uint8(len(seed)), args[0], args[1], args[2], args[3])
+		}
+	}
+
+	invoker := creator(f, "Admin")
+	f.Fuzz(func(t *testing.T, fn uint8, nargs uint8, a1, a2, a3, a4 string) {
+		function := client.Functions[int(fn)%len(client.Functions)]
+		args := []string{a1, a2, a3, a4}[:int(nargs)%5]
+
+		icc := &identityCC{creator: invoker}
+		stub := shim.NewMockStub("housecontract", icc)
+		res := stub.MockInit(util.GenerateUUID(), getBytes("init",
+			`{"Admins":["Admin"],"Registrars":["Admin"],"Orgs":{"Admin":"Org1MSP"}}`))
+		if res.Status != shim.OK {
+			t.Fatal(res.Message)
+		}
+		for _, setup := range [][][]byte{getBytes("AddOwner", alice), getBytes("AddOwner", bob),
+			getBytes("AddHouse", house1)} {
+			if res := stub.MockInvoke(util.GenerateUUID(), setup); res.Status != shim.OK {
+				t.Fatal(res.Message)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes(function, args...))
+		if res.Status != shim.OK && res.Status != shim.ERROR {
+			t.Errorf("%s%q: unexpected status %d", function, args, res.Status)
+		}
+	})
+}
+
+// FuzzHouseJSON checks that any House the chaincode accepts reads back the same
+func FuzzHouseJSON(f *testing.F) {
+	for _, seed := range []string{house1, house1b, house1d, `{"Id":"2","OwnerId":"Alice","Price":"2000","Shares":[{"OwnerId":"Bob","Numerator":1,"Denominator":0}]}`} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, jsonhouse string) {
+		stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+		stub.MockInit(util.GenerateUUID(), nil)
+		stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", jsonhouse))
+		if res.Status != shim.OK {
+			return
+		}
+		want := new(cc.House)
+		if err := json.Unmarshal([]byte(jsonhouse), want); err != nil {
+			t.Fatalf("AddHouse accepted %q: %s", jsonhouse, err)
+		}
+		// a new House has a single Owner whatever Shares it was sent with
+		want.Shares = nil
+		id, _ := json.Marshal(want.Id)
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", string(id)))
+		if res.Status != shim.OK {
+			t.Fatalf("GetHouse after AddHouse(%q): %s", jsonhouse, res.Message)
+		}
+		got := new(cc.House)
+		if err := json.Unmarshal(res.Payload, got); err != nil {
+			t.Fatal(err)
+		}
+		wantJSON, _ := json.Marshal(want)
+		gotJSON, _ := json.Marshal(got)
+		if string(wantJSON) != string(gotJSON) {
+			t.Errorf("stored %s, read back %s", wantJSON, gotJSON)
+		}
+	})
+}
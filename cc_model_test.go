@@ -0,0 +1,199 @@
+package cc_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"housecontract/cc"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/stretchr/testify/assert"
+)
+
+// registryModel is the reference model of Owners and Houses the chaincode is
+// checked against
+type registryModel struct {
+	owners map[string]bool
+	houses map[string]*cc.House
+}
+
+// modelOwnerIds are the Owner Ids the model test draws from; ListOwnerIdHouses
+// returns every House for Ids containing "admin", so none of them does
+var modelOwnerIds = []string{"Alice", "Bob", "Carol", "Dave"}
+
+var modelHouseIds = []string{"1", "2", "3", "4", "5"}
+
+var modelTimestamp = time.Date(2018, 1, 1, 12, 34, 56, 0, time.UTC)
+
+// OK1: random sequences of registry calls keep the chaincode in step with the
+// model and keep its invariants
+func TestRegistryModel_OK1(t *testing.T) {
+	creators := map[string][]byte{}
+	for _, id := range modelOwnerIds {
+		creators[id] = creator(t, id)
+	}
+
+	for run := 0; run < 20; run++ {
+		seed := int64(run)
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			runRegistryModel(t, rand.New(rand.NewSource(seed)), creators, 60)
+		})
+	}
+}
+
+func runRegistryModel(t *testing.T, r *rand.Rand, creators map[string][]byte, steps int) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if !assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		return
+	}
+	model := &registryModel{owners: map[string]bool{}, houses: map[string]*cc.House{}}
+	pick := func(ids []string) string { return ids[r.Intn(len(ids))] }
+
+	for step := 0; step < steps; step++ {
+		icc.creator = nil
+		var (
+			function string
+			args     []string
+			expectOK bool
+			apply    func()
+		)
+
+		switch r.Intn(4) {
+		case 0:
+			goowner := &cc.Owner{Id: pick(modelOwnerIds), Org: "Org1MSP"}
+			function, args = "AddOwner", []string{mustJSON(t, goowner)}
+			expectOK = !model.owners[goowner.Id]
+			apply = func() { model.owners[goowner.Id] = true }
+
+		case 1:
+			gohouse := &cc.House{
+				Id:        pick(modelHouseIds),
+				Address:   "seoul",
+				OwnerId:   pick(modelOwnerIds),
+				Price:     strconv.Itoa(1000 + r.Intn(9000)),
+				Timestamp: modelTimestamp,
+			}
+			function, args = "AddHouse", []string{mustJSON(t, gohouse)}
+			expectOK = model.houses[gohouse.Id] == nil && model.owners[gohouse.OwnerId]
+			apply = func() { model.houses[gohouse.Id] = gohouse }
+
+		case 2:
+			houseId := pick(modelHouseIds)
+			current := model.houses[houseId]
+			if current == nil {
+				continue
+			}
+			gohouse := *current
+			gohouse.Price = strconv.Itoa(1000 + r.Intn(9000))
+			invokerId := current.OwnerId
+			if r.Intn(3) == 0 {
+				invokerId = pick(modelOwnerIds)
+			}
+			icc.creator = creators[invokerId]
+			function, args = "UpdateHouse", []string{mustJSON(t, &gohouse)}
+			expectOK = invokerId == current.OwnerId
+			apply = func() { model.houses[houseId] = &gohouse }
+
+		case 3:
+			houseId, newownerId := pick(modelHouseIds), pick(modelOwnerIds)
+			current := model.houses[houseId]
+			invokerId := pick(modelOwnerIds)
+			if current != nil && r.Intn(3) != 0 {
+				invokerId = current.OwnerId
+			}
+			icc.creator = creators[invokerId]
+			function, args = "TransferHouse", []string{mustJSON(t, houseId), mustJSON(t, newownerId)}
+			expectOK = current != nil && invokerId == current.OwnerId && model.owners[newownerId]
+			apply = func() {
+				gohouse := *model.houses[houseId]
+				gohouse.OwnerId = newownerId
+				model.houses[houseId] = &gohouse
+			}
+		}
+
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes(function, args...))
+		ok := res.Status < shim.ERRORTHRESHOLD
+		if !assert.Equal(t, expectOK, ok, "step %d: %s%q: %s", step, function, args, res.Message) {
+			return
+		}
+		if ok {
+			apply()
+		}
+		if !checkRegistryInvariants(t, stub, model) {
+			t.Logf("after step %d: %s%q", step, function, args)
+			return
+		}
+	}
+}
+
+// checkRegistryInvariants compares the ledger with the model: every House has
+// an existing Owner and the Houses of the Owners partition ListHouses
+func checkRegistryInvariants(t *testing.T, stub *shim.MockStub, model *registryModel) bool {
+	goowners := []*cc.Owner{}
+	if !invokeJSON(t, stub, &goowners, "ListOwners") {
+		return false
+	}
+	owners := map[string]bool{}
+	for _, goowner := range goowners {
+		owners[goowner.Id] = true
+	}
+	if !assert.Equal(t, model.owners, owners) {
+		return false
+	}
+
+	gohouses := []*cc.House{}
+	if !invokeJSON(t, stub, &gohouses, "ListHouses") {
+		return false
+	}
+	houses := map[string]*cc.House{}
+	for _, gohouse := range gohouses {
+		if !assert.True(t, owners[gohouse.OwnerId], "House %s has no Owner %s", gohouse.Id, gohouse.OwnerId) {
+			return false
+		}
+		houses[gohouse.Id] = gohouse
+	}
+	if !assert.Equal(t, model.houses, houses) {
+		return false
+	}
+
+	partition := []string{}
+	for id := range owners {
+		owned := []*cc.House{}
+		if !invokeJSON(t, stub, &owned, "ListOwnerIdHouses", mustJSON(t, id)) {
+			return false
+		}
+		for _, gohouse := range owned {
+			if !assert.Equal(t, id, gohouse.OwnerId) {
+				return false
+			}
+			partition = append(partition, gohouse.Id)
+		}
+	}
+	all := []string{}
+	for id := range houses {
+		all = append(all, id)
+	}
+	sort.Strings(partition)
+	sort.Strings(all)
+	return assert.Equal(t, all, partition, "ListOwnerIdHouses does not partition ListHouses")
+}
+
+func invokeJSON(t *testing.T, stub *shim.MockStub, v interface{}, function string, args ...string) bool {
+	res := stub.MockInvoke(util.GenerateUUID(), getBytes(function, args...))
+	return assert.Condition(t, responseOK(res), "%s: %s", function, res.Message) &&
+		assert.NoError(t, json.Unmarshal(res.Payload, v))
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -1,18 +1,33 @@
 package cc_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"housecontract/cc"
+	"housecontract/digest"
+	"housecontract/memstub"
+	"housecontract/scenario"
+	"math/big"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/statebased"
+	"github.com/hyperledger/fabric/protos/msp"
 	pb "github.com/hyperledger/fabric/protos/peer"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
-	alice = `{"Id":"Alice"}`
-	bob   = `{"Id":"Bob"}`
+	alice = `{"Id":"Alice","Org":"Org1MSP"}`
+	bob   = `{"Id":"Bob","Org":"Org1MSP"}`
 
 	aliceid     = `"Alice"`
 	bobid       = `"Bob"`
@@ -24,6 +39,8 @@ const (
 
 	house1  = `{"Id":"1", "Address":"seoul", "OwnerId":"Alice","Price":"3000", "Timestamp":` + timestamp + `}`
 	house1b = `{"Id":"1", "Address":"seoul", "OwnerId":"Bob","Price":"3000", "Timestamp":` + timestamp + `}`
+	house1c = `{"Id":"1", "Address":"seoul", "OwnerId":"Alice","Price":"3500", "Timestamp":` + timestamp + `}`
+	house1d = `{"Id":"1", "Address":"incheon", "OwnerId":"Alice","Price":"3000", "Timestamp":` + timestamp + `}`
 	house2  = `{"Id":"2", "Address":"bucheon", "OwnerId":"Alice","Price":"2000", "Timestamp":` + timestamp + `}`
 
 	oneHouses = "[" + house1 + "]"
@@ -31,8 +48,96 @@ const (
 
 	one = `"1"`
 	two = `"2"`
+
+	twoHousesBatch = "[" + house1 + "," + house2 + "]"
+	dryRun         = `true`
+
+	reportHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	appraisal1 = `{"HouseId":"1","Value":3100,"Method":"sales comparison","Date":"2018-02-01T00:00:00Z","ReportHash":"` + reportHash + `"}`
+	appraisal2 = `{"HouseId":"1","Value":3300,"Method":"income","Date":"2018-03-01T00:00:00Z","ReportHash":"` + reportHash + `"}`
+
+	registrarConfig = `{"Registrars":["Registrar"],"Orgs":{"Registrar":"Org1MSP"}}`
+	adminConfig     = `{"Admins":["Admin"],"Orgs":{"Admin":"Org1MSP"}}`
+	reason          = `"typo in the land register"`
 )
 
+// contract1 are the AttachDocument arguments of a sales contract
+var contract1 = []string{"1", "sales-contract", reportHash, "https://docs.example.com/1", "application/pdf"}
+
+// identityStub reports a fixed creator, which shim.MockStub does not support
+type identityStub struct {
+	shim.ChaincodeStubInterface
+	creator []byte
+}
+
+func (s *identityStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+// identityCC runs HouseContractCC as the identity set in creator
+type identityCC struct {
+	cc.HouseContractCC
+	creator []byte
+}
+
+func (t *identityCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.HouseContractCC.Init(&identityStub{stub, t.creator})
+}
+
+func (t *identityCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.HouseContractCC.Invoke(&identityStub{stub, t.creator})
+}
+
+// putLegacy stores value as bare JSON, as before documents were versioned
+func putLegacy(stub *shim.MockStub, objectType string, id string, value string) {
+	stub.MockTransactionStart(util.GenerateUUID())
+	key, _ := stub.CreateCompositeKey(objectType, []string{id})
+	stub.PutState(key, []byte(value))
+	stub.MockTransactionEnd("")
+}
+
+// putMemLegacy stores value as bare JSON on a memstub, as before documents
+// were versioned
+func putMemLegacy(stub *memstub.Stub, objectType string, attributes []string, value string) {
+	key := "\x00" + objectType + "\x00"
+	for _, attribute := range attributes {
+		key += attribute + "\x00"
+	}
+	stub.State[key] = []byte(value)
+}
+
+// creator returns a serialized identity of Org1MSP whose certificate CN is id
+func creator(t testing.TB, id string) []byte {
+	return orgCreator(t, id, "Org1MSP")
+}
+
+// orgCreator returns a serialized identity of mspid whose certificate CN is id
+func orgCreator(t testing.TB, id string, mspid string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: id},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid := &msp.SerializedIdentity{
+		Mspid:   mspid,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+	bytes, err := proto.Marshal(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes
+}
+
 func responseOK(res pb.Response) func() bool {
 	return func() bool { return res.Status < shim.ERRORTHRESHOLD }
 }
@@ -50,6 +155,12 @@ func getBytes(function string, args ...string) [][]byte {
 	return bytes
 }
 
+// scenarios in testdata/scenarios, maintained by QA
+func TestScenarios(t *testing.T) {
+	scenario.RunFiles(t, func() shim.Chaincode { return new(cc.HouseContractCC) },
+		"testdata/scenarios/*")
+}
+
 // OK1: normal Init()
 func TestInit_OK1(t *testing.T) {
 	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
@@ -89,6 +200,22 @@ func TestAddOwner_NG2(t *testing.T) {
 	}
 }
 
+// NG3: the organization of an Owner must be given, not taken from the invoker
+func TestAddOwner_NG3(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		icc.creator = creator(t, "Alice")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", `{"Id":"Alice"}`))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "Org of Owner with Id = Alice is empty")
+		}
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwnersBatch", `[{"Id":"Alice"}]`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
 // OK1: success
 func TestAddOwner_OK(t *testing.T) {
 	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
@@ -139,6 +266,106 @@ func TestListOwners_OK2(t *testing.T) {
 	}
 }
 
+// OK1: two Owners at once
+func TestAddOwnersBatch_OK1(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwnersBatch", twoOwners))
+		if assert.Condition(t, responseOK(res)) {
+			report := new(cc.BatchReport)
+			if assert.NoError(t, json.Unmarshal(res.Payload, report)) {
+				assert.Equal(t, 2, report.Added)
+				assert.Empty(t, report.Failed)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwners"))
+		assert.Condition(t, responseOK(res))
+		assert.JSONEq(t, twoOwners, string(res.Payload))
+	}
+}
+
+// NG1: duplicates within the batch reject the whole batch
+func TestAddOwnersBatch_NG1(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(),
+			getBytes("AddOwnersBatch", "["+alice+","+bob+","+alice+"]"))
+		if assert.Condition(t, responseFail(res)) {
+			report := new(cc.BatchReport)
+			if assert.NoError(t, json.Unmarshal([]byte(res.Message), report)) &&
+				assert.Len(t, report.Failed, 1) {
+				assert.Equal(t, 2, report.Failed[0].Index)
+				assert.Equal(t, "Alice", report.Failed[0].Id)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwners"))
+		assert.Condition(t, responseOK(res))
+		assert.JSONEq(t, emptyOwners, string(res.Payload))
+	}
+}
+
+// OK2: dry run validates without writing
+func TestAddOwnersBatch_OK2(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwnersBatch", twoOwners, dryRun))
+		if assert.Condition(t, responseOK(res)) {
+			report := new(cc.BatchReport)
+			if assert.NoError(t, json.Unmarshal(res.Payload, report)) {
+				assert.True(t, report.DryRun)
+				assert.Equal(t, 0, report.Added)
+				assert.Len(t, report.Failed, 1)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwners"))
+		assert.Condition(t, responseOK(res))
+		assert.JSONEq(t, oneOwners, string(res.Payload))
+	}
+}
+
+// OK1: Houses with their Owner registered in the same batch
+func TestAddHousesBatch_OK1(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(),
+			getBytes("AddHousesBatch", twoHousesBatch, "false", oneOwners))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouses"))
+		assert.Condition(t, responseOK(res))
+		assert.JSONEq(t, twoHouses, string(res.Payload))
+	}
+}
+
+// NG1: Owner of the Houses does not exist
+func TestAddHousesBatch_NG1(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddHousesBatch", twoHousesBatch))
+		if assert.Condition(t, responseFail(res)) {
+			report := new(cc.BatchReport)
+			if assert.NoError(t, json.Unmarshal([]byte(res.Message), report)) {
+				assert.Len(t, report.Failed, 2)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouses"))
+		assert.Condition(t, responseOK(res))
+		assert.JSONEq(t, "[]", string(res.Payload))
+	}
+}
+
 // OK1: a single House
 func TestAddHouse_OK1(t *testing.T) {
 	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
@@ -159,6 +386,44 @@ func TestAddHouse_OK1(t *testing.T) {
 	}
 }
 
+// NG1: a House is only written with a Price that can be sold for
+func TestAddHouse_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		for _, price := range []string{`""`, `"3,000"`, `"-1"`, `"3000.5"`} {
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse",
+				`{"Id":"2","OwnerId":"Alice","Price":`+price+`}`))
+			assert.Condition(t, responseFail(res), price)
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHousesBatch",
+				`[{"Id":"2","OwnerId":"Alice","Price":`+price+`}]`))
+			assert.Condition(t, responseFail(res), price)
+
+			icc.creator = creator(t, "Alice")
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse",
+				`{"Id":"1","Address":"seoul","OwnerId":"Alice","Price":`+price+`}`))
+			if assert.Condition(t, responseFail(res), price) {
+				assert.Contains(t, res.Message, "invalid Price of House with Id = 1")
+			}
+			icc.creator = creator(t, "Registrar")
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("CorrectHouse",
+				`{"Id":"1","Address":"seoul","OwnerId":"Alice","Price":`+price+`}`, reason))
+			assert.Condition(t, responseFail(res), price)
+			icc.creator = nil
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouses"))
+		assert.Condition(t, responseOK(res))
+		assert.JSONEq(t, oneHouses, string(res.Payload))
+	}
+}
+
 // OK2: two Houses
 func TestListHouses_OK2(t *testing.T) {
 	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
@@ -179,23 +444,33 @@ func TestListHouses_OK2(t *testing.T) {
 	}
 }
 
-// OK1: change owner from Alice to Bob
+// OK1: owner changes the price
 func TestUpdateHouse_OK1(t *testing.T) {
-	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
 	if assert.NotNil(t, stub) &&
 		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
 		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
 		assert.Condition(t, responseOK(res))
-		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
-		assert.Condition(t, responseOK(res))
 		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
 		assert.Condition(t, responseOK(res))
-		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1b))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
 		assert.Condition(t, responseOK(res))
 
 		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", one))
 		if assert.Condition(t, responseOK(res)) {
-			assert.JSONEq(t, house1b, string(res.Payload))
+			assert.JSONEq(t, house1c, string(res.Payload))
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouseEdits", one))
+		if assert.Condition(t, responseOK(res)) {
+			edits := []*cc.HouseEdit{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &edits)) && assert.Len(t, edits, 1) {
+				assert.Equal(t, "Alice", edits[0].EditorId)
+				assert.Equal(t, []cc.FieldChange{{Field: "Price", Old: "3000", New: "3500"}}, edits[0].Changes)
+			}
 		}
 	}
 }
@@ -210,9 +485,155 @@ func TestUpdateHouse_NG1(t *testing.T) {
 	}
 }
 
+// NG2: ownership cannot be changed by UpdateHouse
+func TestUpdateHouse_NG2(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1b))
+		assert.Condition(t, responseFail(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", one))
+		if assert.Condition(t, responseOK(res)) {
+			assert.JSONEq(t, house1, string(res.Payload))
+		}
+	}
+}
+
+// NG3: only the owner may update
+func TestUpdateHouse_NG3(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// NG4: an owner's common name issued by another organization is not the owner
+func TestUpdateHouse_NG4(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = orgCreator(t, "Alice", "Org2MSP")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "Alice is enrolled with Org1MSP, not Org2MSP")
+		}
+		icc.creator = orgCreator(t, "Admin", "Org2MSP")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GrantRole",
+			`{"Id":"Alice","Role":"admin","Org":"Org2MSP"}`))
+		assert.Condition(t, responseFail(res))
+		icc.creator = creator(t, "Mallory")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "Mallory is not enrolled")
+		}
+
+		// a role is held by the enrolled identity only
+		icc.creator = creator(t, "Admin")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GrantRole",
+			`{"Id":"Alice","Role":"registrar","Org":"Org2MSP"}`))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
+		assert.Condition(t, responseOK(res), res.Message)
+	}
+}
+
+// OK1: registrar corrects the address with a reason
+func TestCorrectHouse_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("CorrectHouse", house1d, reason))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", one))
+		if assert.Condition(t, responseOK(res)) {
+			assert.JSONEq(t, house1d, string(res.Payload))
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouseEdits", one))
+		if assert.Condition(t, responseOK(res)) {
+			edits := []*cc.HouseEdit{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &edits)) && assert.Len(t, edits, 1) {
+				assert.Equal(t, "Registrar", edits[0].EditorId)
+				assert.Equal(t, "typo in the land register", edits[0].Reason)
+				assert.Equal(t, []cc.FieldChange{{Field: "Address", Old: "seoul", New: "incheon"}}, edits[0].Changes)
+			}
+		}
+	}
+}
+
+// NG1: a reason is mandatory
+func TestCorrectHouse_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("CorrectHouse", house1d, `""`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// NG2: only registrars may correct
+func TestCorrectHouse_NG2(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("CorrectHouse", house1d, reason))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
 // OK2: transfer from Alice to Bob
 func TestTransferHouse_OK1(t *testing.T) {
-	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
 	if assert.NotNil(t, stub) &&
 		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
 		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
@@ -222,6 +643,7 @@ func TestTransferHouse_OK1(t *testing.T) {
 
 		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
 		assert.Condition(t, responseOK(res))
+		icc.creator = creator(t, "Alice")
 		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
 		assert.Condition(t, responseOK(res))
 
@@ -266,3 +688,1576 @@ func TestTransferHouse_NG3(t *testing.T) {
 		assert.Condition(t, responseFail(res))
 	}
 }
+
+// OK1: legacy documents are upgraded when read, and migrated page by page.
+// Runs on memstub, which pages queries like a peer.
+func TestMigrateBatch_OK1(t *testing.T) {
+	stub := memstub.New("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		putMemLegacy(stub, "Owner", []string{"Alice"}, `{"Id":"Alice"}`)
+		putMemLegacy(stub, "House", []string{"1"}, house1)
+		putMemLegacy(stub, "House", []string{"2"},
+			`{"Id":"2", "Address":"bucheon", "OwnerId":"Alice","Price":"2,000", "Timestamp":`+timestamp+`}`)
+		putMemLegacy(stub, "House", []string{"3"},
+			`{"Id":"3", "Address":"suwon", "OwnerId":"Alice","Price":"about 1000", "Timestamp":`+timestamp+`}`)
+		putMemLegacy(stub, "RentPayment", []string{"L1", "tx1"},
+			`{"LeaseId":"L1","TxId":"tx1","Amount":1000,"RecordedBy":"Alice"}`)
+
+		// a price that reads as a whole amount is written as one, others are
+		// left for a registrar to correct
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", two))
+		if assert.Condition(t, responseOK(res)) {
+			assert.JSONEq(t, house2, string(res.Payload))
+		}
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", `"3"`))
+		if assert.Condition(t, responseOK(res)) {
+			assert.Contains(t, string(res.Payload), `"Price":"about 1000"`)
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetMigrationStatus"))
+		if assert.Condition(t, responseOK(res)) {
+			statuses := []*cc.MigrationStatus{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &statuses)) {
+				assert.Contains(t, statuses, &cc.MigrationStatus{DocType: "House", Version: 1, Current: false, Count: 3})
+				assert.Contains(t, statuses, &cc.MigrationStatus{DocType: "Owner", Version: 1, Current: false, Count: 1})
+			}
+		}
+
+		stub.Creator = creator(t, "Admin")
+		unenrolled := []string{}
+		for _, docType := range []string{"Owner", "House", "RentPayment"} {
+			bookmark := ""
+			for i := 0; i < 10; i++ {
+				res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListStaleKeys",
+					docType, bookmark, "2"))
+				if !assert.Condition(t, responseOK(res), res.Message) {
+					return
+				}
+				stale := new(cc.StaleKeys)
+				if !assert.NoError(t, json.Unmarshal(res.Payload, stale)) {
+					return
+				}
+				keys, _ := json.Marshal(stale.Keys)
+				res = stub.MockInvoke(util.GenerateUUID(), getBytes("MigrateBatch", string(keys)))
+				if !assert.Condition(t, responseOK(res), res.Message) {
+					return
+				}
+				result := new(cc.MigrationResult)
+				if assert.NoError(t, json.Unmarshal(res.Payload, result)) {
+					assert.Equal(t, len(stale.Keys), result.Migrated)
+					unenrolled = append(unenrolled, result.Unenrolled...)
+				}
+				if bookmark = stale.Bookmark; bookmark == "" {
+					break
+				}
+			}
+		}
+		assert.Equal(t, []string{"Alice"}, unenrolled)
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetMigrationStatus"))
+		if assert.Condition(t, responseOK(res)) {
+			statuses := []*cc.MigrationStatus{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &statuses)) {
+				for _, status := range statuses {
+					assert.True(t, status.Current, "%s version %d", status.DocType, status.Version)
+				}
+			}
+		}
+
+		// the migration files legacy Houses under their Owner
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwnerIdHouses", aliceid))
+		if assert.Condition(t, responseOK(res)) {
+			gohouses := []*cc.House{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &gohouses)) && assert.Len(t, gohouses, 3) {
+				assert.Equal(t, "2000", gohouses[1].Price)
+			}
+		}
+
+		// a payment recorded before confirmations counts as confirmed by its recorder
+		doc := struct{ Data *cc.RentPayment }{}
+		key := "\x00RentPayment\x00L1\x00tx1\x00"
+		if assert.NoError(t, json.Unmarshal(stub.State[key], &doc)) && assert.NotNil(t, doc.Data) {
+			assert.Equal(t, "Alice", doc.Data.ConfirmedBy)
+		}
+
+		// nothing is left to migrate
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListStaleKeys", `"House"`, `""`, "10"))
+		if assert.Condition(t, responseOK(res)) {
+			assert.JSONEq(t, `{"Keys":[],"Bookmark":""}`, string(res.Payload))
+		}
+	}
+}
+
+// NG1: only admins migrate, and only keys of documents
+func TestMigrateBatch_NG1(t *testing.T) {
+	stub := memstub.New("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		putMemLegacy(stub, "House", []string{"1"}, house1)
+		key, _ := json.Marshal([]string{"\x00House\x001\x00"})
+
+		stub.Creator = creator(t, "Alice")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("MigrateBatch", string(key)))
+		assert.Condition(t, responseFail(res))
+
+		stub.Creator = creator(t, "Admin")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("MigrateBatch", `["House"]`))
+		assert.Condition(t, responseFail(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListStaleKeys", `"Houses"`, `""`, "10"))
+		assert.Condition(t, responseFail(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListStaleKeys", `"House"`, `""`, "0"))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK2: the config survives an upgrade without arguments
+func TestInit_OK2(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(),
+			getBytes("init", `{"Currencies":["KRW","USD"]}`)))) {
+		res := stub.MockInit(util.GenerateUUID(), nil)
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetConfig"))
+		if assert.Condition(t, responseOK(res)) {
+			goconfig := new(cc.Config)
+			if assert.NoError(t, json.Unmarshal(res.Payload, goconfig)) {
+				assert.Equal(t, []string{"KRW", "USD"}, goconfig.Currencies)
+			}
+		}
+	}
+}
+
+// NG1: unknown config fields
+func TestInit_NG1(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) {
+		res := stub.MockInit(util.GenerateUUID(), getBytes("init", `{"Currency":["KRW"]}`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// NG2: approvals would expire as soon as they are requested, and bootstrap
+// admins need an organization
+func TestInit_NG2(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) {
+		res := stub.MockInit(util.GenerateUUID(), getBytes("init", `{"TransferExpiryHours":0}`))
+		assert.Condition(t, responseFail(res))
+		res = stub.MockInit(util.GenerateUUID(), getBytes("init", `{"Admins":["Admin"]}`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK1: admin switches a method off
+func TestUpdateConfig_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		icc.creator = creator(t, "Admin")
+		res := stub.MockInvoke(util.GenerateUUID(),
+			getBytes("UpdateConfig", `{"Admins":["Admin"],"Orgs":{"Admin":"Org1MSP"},"Features":{"AddOwner":false}}`))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// NG1: only admins may update the config
+func TestUpdateConfig_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		icc.creator = creator(t, "Alice")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateConfig", `{"Admins":["Alice"]}`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK1: a registrar gives Owners and roles stored before organizations were
+// recorded their organization, after which their holders can act again
+func TestEnroll_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		putLegacy(stub, "Owner", "Alice", `{"Id":"Alice"}`)
+		putLegacy(stub, "House", "1", house1)
+		stub.MockTransactionStart(util.GenerateUUID())
+		key, _ := stub.CreateCompositeKey("Role", []string{"admin", "Admin"})
+		stub.PutState(key, []byte(`{"Id":"Admin","Role":"admin"}`))
+		stub.MockTransactionEnd("")
+
+		icc.creator = creator(t, "Alice")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "Alice is not enrolled")
+		}
+		icc.creator = creator(t, "Admin")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("MigrateBatch", `["\u0000House\u00001\u0000"]`))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Enroll",
+			`[{"Id":"Alice","Org":"Org1MSP"},{"Id":"Admin","Org":"Org1MSP"}]`))
+		assert.Condition(t, responseOK(res), res.Message)
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetOwner", aliceid))
+		if assert.Condition(t, responseOK(res)) {
+			assert.JSONEq(t, alice, string(res.Payload))
+		}
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
+		assert.Condition(t, responseOK(res), res.Message)
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house2))
+		assert.Condition(t, responseOK(res), res.Message)
+		assert.Equal(t, []string{"Org1MSP"}, houseEndorsers(t, stub, "2"))
+
+		icc.creator = creator(t, "Admin")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("MigrateBatch", `["\u0000House\u00001\u0000"]`))
+		assert.Condition(t, responseOK(res), res.Message)
+	}
+}
+
+// NG1: only registrars enroll, and an Id stays with its organization
+func TestEnroll_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Enroll", `[{"Id":"Bob","Org":"Org1MSP"}]`))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Enroll", `[{"Id":"Alice","Org":"Org2MSP"}]`))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "Alice is enrolled with Org1MSP, not Org2MSP")
+		}
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Enroll", `[{"Id":"Bob"}]`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK1: tax and registry fee of a first home are recorded in a receipt
+func TestTransferHouse_OK2(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(),
+			getBytes("init", `{"Admins":["Admin"],"Orgs":{"Admin":"Org1MSP"},"FeePercentages":{"registry":0.5}}`)))) {
+		icc.creator = creator(t, "Admin")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("GrantRole", `{"Id":"Tax","Role":"taxauthority","Org":"Org1MSP"}`))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Tax")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateTaxSchedule",
+			`{"Brackets":[{"UpTo":5000,"RatePercent":1},{"UpTo":0,"RatePercent":3}],`+
+				`"FirstHomeMaxPrice":3000,"FirstHomeReductionPercent":50}`))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwnerTransferReceipts", bobid))
+		if assert.Condition(t, responseOK(res)) {
+			receipts := []*cc.TransferReceipt{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &receipts)) && assert.Len(t, receipts, 1) {
+				assert.Equal(t, "Alice", receipts[0].SellerId)
+				assert.True(t, receipts[0].FirstHome)
+				assert.Equal(t, int64(15), receipts[0].Tax)
+				assert.Equal(t, int64(15), receipts[0].RegistryFee)
+				assert.Equal(t, int64(30), receipts[0].Total)
+			}
+		}
+
+		from, _ := json.Marshal(time.Now().Add(-time.Hour))
+		to, _ := json.Marshal(time.Now().Add(time.Hour))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListTransferReceipts", string(from), string(to)))
+		if assert.Condition(t, responseOK(res)) {
+			receipts := []*cc.TransferReceipt{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &receipts)) {
+				assert.Len(t, receipts, 1)
+			}
+		}
+	}
+}
+
+// OK1: Houses stored before the Owner index are listed by Owner and count
+// against a first home until they are migrated into the index
+func TestListOwnerIdHouses_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	putLegacy(stub, "House", "1", house1)
+	putLegacy(stub, "House", "2",
+		`{"Id":"2", "Address":"bucheon", "OwnerId":"Bob","Price":"2000", "Timestamp":`+timestamp+`}`)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		icc.creator = creator(t, "Alice")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwnerIdHouses", aliceid))
+		assert.Condition(t, responseOK(res))
+		assert.JSONEq(t, oneHouses, string(res.Payload))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseOK(res), res.Message)
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwnerTransferReceipts", bobid))
+		if assert.Condition(t, responseOK(res)) {
+			receipts := []*cc.TransferReceipt{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &receipts)) && assert.Len(t, receipts, 1) {
+				assert.False(t, receipts[0].FirstHome)
+			}
+		}
+
+		// once the last legacy House is migrated, the index is complete
+		icc.creator = creator(t, "Admin")
+		key, _ := json.Marshal([]string{"\x00House\x002\x00"})
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("MigrateBatch", string(key)))
+		assert.Condition(t, responseOK(res), res.Message)
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwnerIdHouses", bobid))
+		if assert.Condition(t, responseOK(res)) {
+			gohouses := []*cc.House{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &gohouses)) && assert.Len(t, gohouses, 2) {
+				assert.Equal(t, "1", gohouses[0].Id)
+				assert.Equal(t, "2", gohouses[1].Id)
+			}
+		}
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwnerIdHouses", aliceid))
+		assert.Condition(t, responseOK(res))
+		assert.JSONEq(t, "[]", string(res.Payload))
+	}
+}
+
+// NG1: only the tax authority may update the schedule
+func TestUpdateTaxSchedule_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		icc.creator = creator(t, "Admin")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateTaxSchedule",
+			`{"Brackets":[{"UpTo":0,"RatePercent":3}]}`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK1: the latest valuation is the one with the most recent date
+func TestSubmitAppraisal_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		icc.creator = creator(t, "Admin")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("GrantRole", `{"Id":"Appraiser","Role":"appraiser","Org":"Org1MSP"}`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Appraiser")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("SubmitAppraisal", appraisal2))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("SubmitAppraisal", appraisal1))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListAppraisals", one))
+		if assert.Condition(t, responseOK(res)) {
+			goappraisals := []*cc.Appraisal{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &goappraisals)) {
+				assert.Len(t, goappraisals, 2)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetLatestValuation", one))
+		if assert.Condition(t, responseOK(res)) {
+			goappraisal := new(cc.Appraisal)
+			if assert.NoError(t, json.Unmarshal(res.Payload, goappraisal)) {
+				assert.Equal(t, int64(3300), goappraisal.Value)
+				assert.Equal(t, "KRW", goappraisal.Currency)
+				assert.Equal(t, "Appraiser", goappraisal.AppraiserId)
+			}
+		}
+	}
+}
+
+// NG1: only appraisers may submit appraisals
+func TestSubmitAppraisal_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("SubmitAppraisal", appraisal1))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK1: attached documents show on GetHouse and verify by hash
+func TestAttachDocument_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AttachDocument", contract1...))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", one))
+		if assert.Condition(t, responseOK(res)) {
+			details := new(cc.HouseDetails)
+			if assert.NoError(t, json.Unmarshal(res.Payload, details)) &&
+				assert.Len(t, details.Documents, 1) {
+				assert.Equal(t, "Alice", details.OwnerId)
+				assert.Equal(t, "sales-contract", details.Documents[0].DocType)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("VerifyDocument", one, `"`+reportHash+`"`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("VerifyDocument", two, `"`+reportHash+`"`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// NG4: a required document is missing for the transfer
+func TestTransferHouse_NG4(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(),
+			getBytes("init", `{"RequiredTransferDocuments":["sales-contract"]}`)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AttachDocument", contract1...))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("VerifyDocument", one, `"`+reportHash+`"`))
+		if assert.Condition(t, responseOK(res)) {
+			godocument := new(cc.Document)
+			if assert.NoError(t, json.Unmarshal(res.Payload, godocument)) {
+				assert.NotEmpty(t, godocument.TransferId)
+			}
+		}
+	}
+}
+
+// OK1: a high value transfer goes through once seller, buyer and notary approve
+func TestRequestTransferApproval_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(),
+			getBytes("init", `{"Admins":["Admin"],"Orgs":{"Admin":"Org1MSP"},"HighValueTransferThreshold":2500}`)))) {
+		icc.creator = creator(t, "Admin")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("GrantRole", `{"Id":"Notary","Role":"notary","Org":"Org1MSP"}`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RequestTransferApproval", one, bobid))
+		if !assert.Condition(t, responseOK(res)) {
+			return
+		}
+		goapproval := new(cc.Approval)
+		if !assert.NoError(t, json.Unmarshal(res.Payload, goapproval)) {
+			return
+		}
+		id, _ := json.Marshal(goapproval.Id)
+		assert.Equal(t, int64(3000), goapproval.Price)
+
+		// the agreed price cannot be edited below the threshold
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse",
+			`{"Id":"1", "Address":"seoul", "OwnerId":"Alice","Price":"2000", "Timestamp":`+timestamp+`}`))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "is agreed in Approval")
+		}
+
+		for _, signer := range []string{"Alice", "Bob"} {
+			icc.creator = creator(t, signer)
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("Approve", string(id)))
+			assert.Condition(t, responseOK(res))
+		}
+
+		// Bob withdraws and signs again while the notary is still missing
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RevokeApproval", string(id)))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseFail(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Approve", string(id)))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Notary")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Approve", string(id)))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RevokeApproval", string(id)))
+		assert.Condition(t, responseFail(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetApproval", string(id)))
+		if assert.Condition(t, responseOK(res)) &&
+			assert.NoError(t, json.Unmarshal(res.Payload, goapproval)) {
+			assert.Equal(t, "executed", goapproval.Status)
+			assert.Equal(t, "Notary", goapproval.Signers[2].ApprovedBy)
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwnerTransferReceipts", bobid))
+		if assert.Condition(t, responseOK(res)) {
+			receipts := []*cc.TransferReceipt{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &receipts)) && assert.Len(t, receipts, 1) {
+				assert.Equal(t, goapproval.Id, receipts[0].ApprovalId)
+			}
+		}
+	}
+}
+
+// NG1: only required signers may approve, and a notary must hold the role
+func TestApprove_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(),
+			getBytes("init", `{"HighValueTransferThreshold":2500}`)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Mallory")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RequestTransferApproval", one, bobid))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RequestTransferApproval", one, bobid))
+		if !assert.Condition(t, responseOK(res)) {
+			return
+		}
+		goapproval := new(cc.Approval)
+		if !assert.NoError(t, json.Unmarshal(res.Payload, goapproval)) {
+			return
+		}
+		id, _ := json.Marshal(goapproval.Id)
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Approve", string(id)))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Approve", string(id)))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "Mallory")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Approve", string(id)))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// houseEndorsers lists the organizations whose peers must endorse a House key
+func houseEndorsers(t *testing.T, stub shim.ChaincodeStubInterface, id string) []string {
+	key, _ := stub.CreateCompositeKey("House", []string{id})
+	policy, err := stub.GetStateValidationParameter(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy == nil {
+		return []string{}
+	}
+	ep, err := statebased.NewStateEP(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ep.ListOrgs()
+}
+
+// OK1: the owning organization endorses a House, also after a transfer. Runs
+// on memstub, which keeps key-level endorsement policies like a peer.
+func TestAddHouse_OK2(t *testing.T) {
+	stub := memstub.New("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		stub.Creator = orgCreator(t, "Alice", "Org1MSP")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		stub.Creator = orgCreator(t, "Bob", "Org2MSP")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", `{"Id":"Bob","Org":"Org2MSP"}`))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+		assert.Equal(t, []string{"Org1MSP"}, houseEndorsers(t, stub, "1"))
+
+		// the buyer cannot take the House for the seller
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "Bob may not transfer for Owner with Id = Alice")
+		}
+		assert.Equal(t, []string{"Org1MSP"}, houseEndorsers(t, stub, "1"))
+
+		stub.Creator = orgCreator(t, "Alice", "Org1MSP")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseOK(res), res.Message)
+		assert.Equal(t, []string{"Org2MSP"}, houseEndorsers(t, stub, "1"))
+	}
+}
+
+// OK2: Owners registered in the same batch set the policy of their Houses
+func TestAddHousesBatch_OK2(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddHousesBatch", twoHousesBatch, `false`,
+			`[{"Id":"Alice","Org":"Org3MSP"}]`))
+		assert.Condition(t, responseOK(res))
+		assert.Equal(t, []string{"Org3MSP"}, houseEndorsers(t, stub, "1"))
+		assert.Equal(t, []string{"Org3MSP"}, houseEndorsers(t, stub, "2"))
+	}
+}
+
+// OK3: Shares sent with a new House are dropped, so a Denominator of 0 cannot
+// break the estate of its Owner
+func TestAddHouse_OK3(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse",
+			`{"Id":"1","OwnerId":"Alice","Price":"3000","Shares":[{"OwnerId":"X","Numerator":1,"Denominator":0}]}`))
+		assert.Condition(t, responseOK(res), res.Message)
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHousesBatch",
+			`[{"Id":"2","OwnerId":"Alice","Price":"3000","Shares":[{"OwnerId":"X","Numerator":1,"Denominator":0}]}]`))
+		assert.Condition(t, responseOK(res), res.Message)
+
+		for _, id := range []string{one, two} {
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", id))
+			if assert.Condition(t, responseOK(res)) {
+				details := new(cc.HouseDetails)
+				if assert.NoError(t, json.Unmarshal(res.Payload, details)) {
+					assert.Equal(t, "Alice", details.OwnerId)
+					assert.Empty(t, details.Shares)
+				}
+			}
+		}
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RecordDeath", aliceid, `"`+reportHash+`"`))
+		assert.Condition(t, responseOK(res), res.Message)
+	}
+}
+
+// OK1: a frozen House cannot be updated or transferred until it is released
+func TestFreezeHouse_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		icc.creator = creator(t, "Admin")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("GrantRole", `{"Id":"Court","Role":"court","Org":"Org1MSP"}`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		until, _ := json.Marshal(time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second))
+		icc.creator = creator(t, "Court")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("FreezeHouse", one, `"2018-Ga-123"`, string(until)))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "is frozen by order 2018-Ga-123")
+		}
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseFail(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", one))
+		if assert.Condition(t, responseOK(res)) {
+			details := new(cc.HouseDetails)
+			if assert.NoError(t, json.Unmarshal(res.Payload, details)) && assert.Len(t, details.Holds, 1) {
+				assert.Equal(t, "Court", details.Holds[0].IssuedBy)
+			}
+		}
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHolds"))
+		if assert.Condition(t, responseOK(res)) {
+			goholds := []*cc.Hold{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &goholds)) {
+				assert.Len(t, goholds, 1)
+			}
+		}
+
+		icc.creator = creator(t, "Court")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UnfreezeHouse", one, `"2018-Ga-123"`))
+		assert.Condition(t, responseOK(res))
+
+		// the court may impose the released order again
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("FreezeHouse", one, `"2018-Ga-123"`, string(until)))
+		assert.Condition(t, responseOK(res), res.Message)
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "is frozen by order 2018-Ga-123")
+		}
+		icc.creator = creator(t, "Court")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UnfreezeHouse", one, `"2018-Ga-123"`))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseOK(res))
+	}
+}
+
+// NG1: only courts and regulators may freeze a House, and only into the future
+func TestFreezeHouse_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		icc.creator = creator(t, "Admin")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("GrantRole", `{"Id":"FSC","Role":"regulator","Org":"Org1MSP"}`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		until, _ := json.Marshal(time.Now().Add(24 * time.Hour))
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("FreezeHouse", one, `"order-1"`, string(until)))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "FSC")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("FreezeHouse", one, `"order-1"`, timestamp))
+		assert.Condition(t, responseFail(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("FreezeHouse", one, `"order-1"`, string(until)))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("FreezeHouse", one, `"order-1"`, string(until)))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "already in force")
+		}
+	}
+}
+
+// OK1: the Houses of a deceased Owner are locked, then pass to the heirs
+func TestDistributeEstate_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(),
+			getBytes("init", `{"Registrars":["Registrar"],"Admins":["Admin"],"Orgs":{"Registrar":"Org1MSP","Admin":"Org1MSP"}}`)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwnersBatch",
+			`[{"Id":"Alice","Org":"Org1MSP"},{"Id":"Bob","Org":"Org1MSP"},{"Id":"Carol","Org":"Org1MSP"}]`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHousesBatch", twoHousesBatch))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RecordDeath", aliceid, `"`+reportHash+`"`))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "locked by the estate of Alice")
+		}
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("DistributeEstate", aliceid,
+			`{"ProbateRef":"2018-Neu-42","Allotments":[`+
+				`{"HouseId":"1","Shares":[{"OwnerId":"Bob","Numerator":1,"Denominator":2}]}]}`))
+		assert.Condition(t, responseFail(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("DistributeEstate", aliceid,
+			`{"ProbateRef":"2018-Neu-42","Allotments":[`+
+				`{"HouseId":"1","Shares":[{"OwnerId":"Bob","Numerator":2,"Denominator":3},{"OwnerId":"Carol","Numerator":1,"Denominator":3}]},`+
+				`{"HouseId":"2","Shares":[{"OwnerId":"Carol","Numerator":1,"Denominator":1}]}]}`))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", one))
+		if assert.Condition(t, responseOK(res)) {
+			details := new(cc.HouseDetails)
+			if assert.NoError(t, json.Unmarshal(res.Payload, details)) && assert.Len(t, details.Shares, 2) {
+				assert.Equal(t, "Bob", details.OwnerId)
+				assert.Equal(t, int64(2), details.Shares[0].Numerator)
+				assert.Equal(t, int64(3), details.Shares[0].Denominator)
+			}
+		}
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", two))
+		if assert.Condition(t, responseOK(res)) {
+			details := new(cc.HouseDetails)
+			if assert.NoError(t, json.Unmarshal(res.Payload, details)) {
+				assert.Equal(t, "Carol", details.OwnerId)
+				assert.Empty(t, details.Shares)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouseEdits", one))
+		if assert.Condition(t, responseOK(res)) {
+			goedits := []*cc.HouseEdit{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &goedits)) && assert.Len(t, goedits, 2) {
+				assert.Equal(t, "estate-lock", goedits[0].Kind)
+				assert.Equal(t, "inheritance", goedits[1].Kind)
+				assert.Equal(t, "2018-Neu-42", goedits[1].Reason)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetEstate", aliceid))
+		if assert.Condition(t, responseOK(res)) {
+			goestate := new(cc.Estate)
+			if assert.NoError(t, json.Unmarshal(res.Payload, goestate)) {
+				assert.Equal(t, "distributed", goestate.Status)
+				assert.Equal(t, []string{"1", "2"}, goestate.Houses)
+			}
+		}
+
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", two, aliceid))
+		assert.Condition(t, responseFail(res))
+
+		// Bob cannot sell the share Carol inherited without her
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, "Carol"))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "Admin")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GrantRole", `{"Id":"Notary","Role":"notary","Org":"Org1MSP"}`))
+		assert.Condition(t, responseOK(res))
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RequestTransferApproval", one, "Carol"))
+		if !assert.Condition(t, responseOK(res), res.Message) {
+			return
+		}
+		goapproval := new(cc.Approval)
+		if !assert.NoError(t, json.Unmarshal(res.Payload, goapproval)) || !assert.Len(t, goapproval.Signers, 3) {
+			return
+		}
+		for _, signer := range []string{"Bob", "Notary"} {
+			icc.creator = creator(t, signer)
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("Approve", goapproval.Id))
+			assert.Condition(t, responseOK(res), res.Message)
+		}
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, "Carol"))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "Carol")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Approve", goapproval.Id))
+		assert.Condition(t, responseOK(res), res.Message)
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, "Carol"))
+		assert.Condition(t, responseOK(res), res.Message)
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", one))
+		if assert.Condition(t, responseOK(res)) {
+			details := new(cc.HouseDetails)
+			if assert.NoError(t, json.Unmarshal(res.Payload, details)) {
+				assert.Equal(t, "Carol", details.OwnerId)
+				assert.Empty(t, details.Shares)
+			}
+		}
+	}
+}
+
+// NG1: only registrars record deaths
+func TestRecordDeath_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RecordDeath", aliceid, `"`+reportHash+`"`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK1: corporate Houses change hands only through signatories within their limits
+func TestSetSignatory_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", `{"Id":"Acme","Type":"corporate","Org":"Org1MSP"}`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse",
+			`{"Id":"1", "Address":"seoul", "OwnerId":"Acme","Price":"3000", "Timestamp":`+timestamp+`}`))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("SetSignatory", `{"OwnerId":"Acme","Id":"CEO"}`))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "CEO")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("SetSignatory",
+			`{"OwnerId":"Acme","Id":"Clerk","Limit":1000}`))
+		assert.Condition(t, responseOK(res))
+
+		for _, id := range []string{"Acme", "Clerk", "Bob"} {
+			icc.creator = creator(t, id)
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+			assert.Condition(t, responseFail(res), id)
+		}
+
+		icc.creator = creator(t, "CEO")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RemoveSignatory", `"Acme"`, `"Clerk"`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListSignatories", `"Acme"`))
+		if assert.Condition(t, responseOK(res)) {
+			gosignatories := []*cc.Signatory{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &gosignatories)) {
+				assert.Len(t, gosignatories, 1)
+			}
+		}
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListSignatoryChanges", `"Acme"`))
+		if assert.Condition(t, responseOK(res)) {
+			gochanges := []*cc.SignatoryChange{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &gochanges)) && assert.Len(t, gochanges, 3) {
+				assert.Equal(t, "Registrar", gochanges[0].ChangedBy)
+				assert.Equal(t, "removed", gochanges[2].Action)
+				assert.Equal(t, "CEO", gochanges[2].ChangedBy)
+			}
+		}
+	}
+}
+
+// NG1: signatories with a limit cannot manage signatories
+func TestSetSignatory_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", `{"Id":"Acme","Type":"corporate","Org":"Org1MSP"}`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("SetSignatory",
+			`{"OwnerId":"Acme","Id":"Clerk","Limit":1000}`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("SetSignatory", `{"OwnerId":"Alice","Id":"Bob"}`))
+		assert.Condition(t, responseFail(res))
+
+		icc.creator = creator(t, "Clerk")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("SetSignatory", `{"OwnerId":"Acme","Id":"Clerk"}`))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK1: an agent acts within the delegated scope and is recorded as the editor
+func TestGrantDelegation_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHousesBatch", twoHousesBatch))
+		assert.Condition(t, responseOK(res))
+
+		expiry, _ := json.Marshal(time.Now().Add(24 * time.Hour))
+		icc.creator = orgCreator(t, "Lawyer", "Org2MSP")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GrantDelegation", aliceid, `"Lawyer"`,
+			`{"Operations":["update","transfer"],"HouseIds":["1"]}`, string(expiry)))
+		assert.Condition(t, responseFail(res))
+
+		// an agent is not taken to belong to the organization of the Owner
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GrantDelegation", aliceid, `"Lawyer"`,
+			`{"Operations":["update","transfer"],"HouseIds":["1"]}`, string(expiry)))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "Lawyer is not enrolled")
+		}
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Enroll", `[{"Id":"Lawyer","Org":"Org2MSP"}]`))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GrantDelegation", aliceid, `"Lawyer"`,
+			`{"Operations":["update","transfer"],"HouseIds":["1"]}`, string(expiry)))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = orgCreator(t, "Lawyer", "Org2MSP")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse",
+			`{"Id":"2", "Address":"bucheon", "OwnerId":"Alice","Price":"2500", "Timestamp":`+timestamp+`}`))
+		assert.Condition(t, responseFail(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouseEdits", one))
+		if assert.Condition(t, responseOK(res)) {
+			goedits := []*cc.HouseEdit{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &goedits)) && assert.Len(t, goedits, 1) {
+				assert.Equal(t, "Lawyer", goedits[0].EditorId)
+				assert.Equal(t, "Alice", goedits[0].OnBehalfOf)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwnerTransferReceipts", bobid))
+		if assert.Condition(t, responseOK(res)) {
+			receipts := []*cc.TransferReceipt{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &receipts)) && assert.Len(t, receipts, 1) {
+				assert.Equal(t, "Lawyer", receipts[0].AgentId)
+			}
+		}
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RevokeDelegation", aliceid, `"Lawyer"`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListDelegations", aliceid))
+		if assert.Condition(t, responseOK(res)) {
+			godelegations := []*cc.Delegation{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &godelegations)) && assert.Len(t, godelegations, 1) {
+				assert.Equal(t, "Alice", godelegations[0].RevokedBy)
+			}
+		}
+	}
+}
+
+// NG1: a revoked delegation no longer authorizes the agent
+func TestGrantDelegation_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", registrarConfig)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Registrar")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("Enroll", `[{"Id":"Lawyer","Org":"Org2MSP"}]`))
+		assert.Condition(t, responseOK(res))
+
+		expiry, _ := json.Marshal(time.Now().Add(24 * time.Hour))
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GrantDelegation", aliceid, `"Lawyer"`,
+			`{"Operations":["sell"]}`, string(expiry)))
+		assert.Condition(t, responseFail(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GrantDelegation", aliceid, `"Lawyer"`,
+			`{"Operations":["update"]}`, string(expiry)))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RevokeDelegation", aliceid, `"Lawyer"`))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = orgCreator(t, "Lawyer", "Org2MSP")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse", house1c))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK1: brokers of a listed House earn their commissions at settlement
+func TestListHouseForSale_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init",
+			`{"Registrars":["Registrar"],"Orgs":{"Registrar":"Org1MSP"},"FeePercentages":{"seller-commission":0.5,"buyer-commission":0.4}}`)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		expiry, _ := json.Marshal(time.Now().Add(365 * 24 * time.Hour))
+		icc.creator = creator(t, "Registrar")
+		for _, id := range []string{"Kim", "Lee"} {
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("RegisterBroker",
+				`{"Id":"`+id+`","Name":"`+id+` Realty","LicenseNo":"11-2018-0001","LicenseExpiry":`+string(expiry)+`}`))
+			assert.Condition(t, responseOK(res))
+		}
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouseForSale", one, `3200`, `"Kim"`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouseForSale", one, `3100`, `"Kim"`))
+		assert.Condition(t, responseFail(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListBrokerListings", `"Kim"`))
+		if assert.Condition(t, responseOK(res)) {
+			golistings := []*cc.Listing{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &golistings)) {
+				assert.Len(t, golistings, 1)
+			}
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid, `{"BuyerBrokerId":"Lee"}`))
+		assert.Condition(t, responseOK(res))
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListBrokerListings", `"Kim"`))
+		if assert.Condition(t, responseOK(res)) {
+			golistings := []*cc.Listing{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &golistings)) {
+				assert.Len(t, golistings, 0)
+			}
+		}
+		for id, amount := range map[string]int64{"Kim": 15, "Lee": 12} {
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListBrokerDeals", `"`+id+`"`))
+			if assert.Condition(t, responseOK(res)) {
+				goreceivables := []*cc.Receivable{}
+				if assert.NoError(t, json.Unmarshal(res.Payload, &goreceivables)) && assert.Len(t, goreceivables, 1) {
+					assert.Equal(t, amount, goreceivables[0].Amount)
+				}
+			}
+		}
+	}
+}
+
+// NG1: frozen Houses cannot be listed, nor can unlicensed brokers list
+func TestListHouseForSale_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init", adminConfig)))) {
+		icc.creator = creator(t, "Admin")
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("GrantRole", `{"Id":"Court","Role":"court","Org":"Org1MSP"}`))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouseForSale", one, `3200`, `"Nobody"`))
+		assert.Condition(t, responseFail(res))
+
+		until, _ := json.Marshal(time.Now().Add(24 * time.Hour))
+		icc.creator = creator(t, "Court")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("FreezeHouse", one, `"order-1"`, string(until)))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListHouseForSale", one, `3200`))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "is frozen")
+		}
+	}
+}
+
+// OK1: a Lease without a late fee takes the one of the config
+func TestProposeLease_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), getBytes("init",
+			`{"FeePercentages":{"rent-late":2.5}}`)))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ProposeLease",
+			`{"Id":"L1","HouseId":"1","TenantId":"Bob","Rent":1000,"DueDay":1,"Start":"2029-01-01T00:00:00Z","End":"2030-01-01T00:00:00Z"}`))
+		assert.Condition(t, responseOK(res), res.Message)
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetLease", "L1"))
+		if assert.Condition(t, responseOK(res)) {
+			golease := new(cc.Lease)
+			if assert.NoError(t, json.Unmarshal(res.Payload, golease)) {
+				assert.Equal(t, "Alice", golease.LandlordId)
+				assert.Equal(t, "proposed", golease.Status)
+				assert.Equal(t, 2.5, golease.LateFeePercent)
+			}
+		}
+
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AcceptLease", "L1"))
+		assert.Condition(t, responseOK(res), res.Message)
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetRentSchedule", "L1"))
+		if assert.Condition(t, responseOK(res)) {
+			goinstallments := []*cc.Installment{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &goinstallments)) {
+				assert.Len(t, goinstallments, 12)
+			}
+		}
+	}
+}
+
+// NG1: invalid terms, unknown tenants and duplicate Ids
+func TestProposeLease_NG1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		icc.creator = creator(t, "Alice")
+		lease := func(tenantId string, terms string) string {
+			return `{"Id":"L1","HouseId":"1","TenantId":"` + tenantId + `",` + terms + `}`
+		}
+		for _, golease := range []string{
+			lease("Carol", `"Rent":1000,"DueDay":1,"Start":"2029-01-01T00:00:00Z","End":"2030-01-01T00:00:00Z"`),
+			lease("Alice", `"Rent":1000,"DueDay":1,"Start":"2029-01-01T00:00:00Z","End":"2030-01-01T00:00:00Z"`),
+			lease("Bob", `"Rent":0,"DueDay":1,"Start":"2029-01-01T00:00:00Z","End":"2030-01-01T00:00:00Z"`),
+			lease("Bob", `"Rent":1000,"DueDay":31,"Start":"2029-01-01T00:00:00Z","End":"2030-01-01T00:00:00Z"`),
+			lease("Bob", `"Rent":1000,"DueDay":1,"Start":"2030-01-01T00:00:00Z","End":"2029-01-01T00:00:00Z"`),
+			lease("Bob", `"Rent":1000,"DueDay":1,"Start":"2029-01-02T00:00:00Z","End":"2029-02-01T00:00:00Z"`),
+			lease("Bob", `"Rent":1000,"DueDay":1,"Start":"2029-01-01T00:00:00Z","End":"2030-01-01T00:00:00Z","LateFeePercent":150`),
+		} {
+			res = stub.MockInvoke(util.GenerateUUID(), getBytes("ProposeLease", golease))
+			assert.Condition(t, responseFail(res), golease)
+		}
+
+		valid := lease("Bob", `"Rent":1000,"DueDay":1,"Start":"2029-01-01T00:00:00Z","End":"2030-01-01T00:00:00Z"`)
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ProposeLease", valid))
+		assert.Condition(t, responseOK(res), res.Message)
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ProposeLease", valid))
+		assert.Condition(t, responseFail(res))
+
+		// the landlord cannot accept for the tenant
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AcceptLease", "L1"))
+		assert.Condition(t, responseFail(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RecordRentPayment", "L1", "1000"))
+		assert.Condition(t, responseFail(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetLease", "L9"))
+		assert.Condition(t, responseFail(res))
+	}
+}
+
+// OK1: rent the tenant records counts once the landlord confirms it
+func TestConfirmRentPayment_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		assert.Condition(t, responseOK(res))
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+		assert.Condition(t, responseOK(res))
+
+		now := time.Now().UTC()
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -3, 0)
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ProposeLease",
+			`{"Id":"L1","HouseId":"1","TenantId":"Bob","Rent":1000,"DueDay":1,`+
+				`"Start":"`+start.Format(time.RFC3339)+`","End":"`+start.AddDate(1, 0, 0).Format(time.RFC3339)+`"}`))
+		assert.Condition(t, responseOK(res), res.Message)
+		icc.creator = creator(t, "Bob")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("AcceptLease", "L1"))
+		assert.Condition(t, responseOK(res), res.Message)
+
+		owed := func() int64 {
+			res := stub.MockInvoke(util.GenerateUUID(), getBytes("ListTenantArrears", bobid))
+			goarrears := []*cc.Arrears{}
+			if assert.Condition(t, responseOK(res)) && assert.NoError(t, json.Unmarshal(res.Payload, &goarrears)) &&
+				assert.Len(t, goarrears, 1) {
+				return goarrears[0].Amount
+			}
+			return 0
+		}
+		before := owed()
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("RecordRentPayment", "L1", "1000"))
+		if !assert.Condition(t, responseOK(res), res.Message) {
+			return
+		}
+		gopayment := new(cc.RentPayment)
+		if !assert.NoError(t, json.Unmarshal(res.Payload, gopayment)) {
+			return
+		}
+		assert.Equal(t, "Bob", gopayment.RecordedBy)
+		assert.Empty(t, gopayment.ConfirmedBy)
+		assert.Equal(t, before, owed())
+
+		// the tenant cannot confirm their own payment
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ConfirmRentPayment", "L1", gopayment.TxId))
+		assert.Condition(t, responseFail(res))
+		assert.Equal(t, before, owed())
+
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ConfirmRentPayment", "L1", gopayment.TxId))
+		assert.Condition(t, responseOK(res), res.Message)
+		assert.Equal(t, before-1000, owed())
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ConfirmRentPayment", "L1", gopayment.TxId))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "is already confirmed")
+		}
+
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("ListRentPayments", "L1"))
+		if assert.Condition(t, responseOK(res)) {
+			gopayments := []*cc.RentPayment{}
+			if assert.NoError(t, json.Unmarshal(res.Payload, &gopayments)) && assert.Len(t, gopayments, 1) {
+				assert.Equal(t, "Alice", gopayments[0].ConfirmedBy)
+			}
+		}
+	}
+}
+
+// OK1: registry rules run on the in-memory store without a stub
+func TestRegisterHouse_OK1(t *testing.T) {
+	store := cc.NewMemoryStore()
+	assert.NoError(t, cc.RegisterOwner(store, &cc.Owner{Id: "Alice", Org: "Org1MSP"}))
+	assert.NoError(t, cc.RegisterOwner(store, &cc.Owner{Id: "Bob", Org: "Org1MSP"}))
+
+	gohouse := &cc.House{Id: "1", Address: "seoul", OwnerId: "Alice", Price: "3000"}
+	goowner, err := cc.RegisterHouse(store, store, gohouse)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Alice", goowner.Id)
+	}
+
+	gohouse, _ = store.GetHouse("1")
+	buyer, err := cc.Reassign(store, gohouse, "Bob", []string{"Alice"})
+	if assert.NoError(t, err) && assert.NoError(t, store.PutHouse(gohouse)) {
+		assert.Equal(t, "Bob", buyer.Id)
+		owned, _ := store.ListOwnerHouses("Alice")
+		assert.Len(t, owned, 0)
+		owned, _ = store.ListOwnerHouses("Bob")
+		assert.Len(t, owned, 1)
+	}
+}
+
+// OK2: the ledger store moves a House in its Owner index when it changes hands
+func TestRegisterHouse_OK2(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	stub.MockTransactionStart(util.GenerateUUID())
+	defer stub.MockTransactionEnd("")
+	store := cc.NewLedgerStore(stub)
+	assert.NoError(t, cc.RegisterOwner(store, &cc.Owner{Id: "Alice", Org: "Org1MSP"}))
+	assert.NoError(t, cc.RegisterOwner(store, &cc.Owner{Id: "Bob", Org: "Org1MSP"}))
+
+	for _, id := range []string{"1", "2"} {
+		_, err := cc.RegisterHouse(store, store, &cc.House{Id: id, OwnerId: "Alice", Price: "3000"})
+		assert.NoError(t, err)
+	}
+
+	gohouse, _ := store.GetHouse("1")
+	_, err := cc.Reassign(store, gohouse, "Bob", []string{"Alice"})
+	if assert.NoError(t, err) && assert.NoError(t, store.PutHouse(gohouse)) {
+		owned, err := store.ListOwnerHouses("Alice")
+		if assert.NoError(t, err) && assert.Len(t, owned, 1) {
+			assert.Equal(t, "2", owned[0].Id)
+		}
+		owned, err = store.ListOwnerHouses("Bob")
+		if assert.NoError(t, err) && assert.Len(t, owned, 1) {
+			assert.Equal(t, "1", owned[0].Id)
+		}
+	}
+}
+
+// NG1: duplicates and unknown Owners are refused by the rules
+func TestRegisterHouse_NG1(t *testing.T) {
+	store := cc.NewMemoryStore()
+	assert.NoError(t, cc.RegisterOwner(store, &cc.Owner{Id: "Alice", Org: "Org1MSP"}))
+	assert.Error(t, cc.RegisterOwner(store, &cc.Owner{Id: "Alice", Org: "Org1MSP"}))
+	assert.Error(t, cc.RegisterOwner(store, &cc.Owner{Id: "Bob", Type: "robot"}))
+
+	_, err := cc.RegisterHouse(store, store, &cc.House{Id: "1", OwnerId: "Bob", Price: "3000"})
+	assert.Error(t, err)
+	_, err = cc.RegisterHouse(store, store, &cc.House{Id: "1", OwnerId: "Alice", Price: "3000"})
+	assert.NoError(t, err)
+	_, err = cc.RegisterHouse(store, store, &cc.House{Id: "1", OwnerId: "Alice", Price: "3000"})
+	assert.Error(t, err)
+
+	gohouse, _ := store.GetHouse("1")
+	_, err = cc.Reassign(store, gohouse, "Bob", []string{"Alice"})
+	assert.Error(t, err)
+
+	// every holder of a co-owned House must agree
+	assert.NoError(t, cc.RegisterOwner(store, &cc.Owner{Id: "Bob", Org: "Org1MSP"}))
+	assert.NoError(t, cc.RegisterOwner(store, &cc.Owner{Id: "Carol", Org: "Org1MSP"}))
+	gohouse.Shares = []*cc.Share{
+		{OwnerId: "Alice", Numerator: 1, Denominator: 2},
+		{OwnerId: "Carol", Numerator: 1, Denominator: 2},
+	}
+	_, err = cc.Reassign(store, gohouse, "Bob", []string{"Alice"})
+	assert.Error(t, err)
+	_, err = cc.Reassign(store, gohouse, "Bob", []string{"Alice", "Carol"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Bob", gohouse.OwnerId)
+		assert.Empty(t, gohouse.Shares)
+	}
+}
+
+// OK1: string arguments may be passed plain or, during the deprecation
+// window, JSON-quoted
+func TestCallingConvention_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+
+		for _, id := range []string{"1", one} {
+			res := stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", id))
+			if assert.Condition(t, responseOK(res), res.Message) {
+				assert.JSONEq(t, house1, string(res.Payload))
+			}
+		}
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("ListOwnerIdHouses", "Alice"))
+		if assert.Condition(t, responseOK(res), res.Message) {
+			assert.JSONEq(t, "["+house1+"]", string(res.Payload))
+		}
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", "1", bobid))
+		assert.Condition(t, responseOK(res), res.Message)
+	}
+}
+
+// NG1: quoted arguments are rejected once the deprecation window ends
+func TestCallingConvention_NG1(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(),
+			getBytes("init", `{"RejectQuotedArgs":true}`)))) {
+		stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", one))
+		if assert.Condition(t, responseFail(res)) {
+			assert.Contains(t, res.Message, "no longer accepted")
+		}
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("GetHouse", "1"))
+		assert.Condition(t, responseOK(res), res.Message)
+	}
+}
+
+// OK1: responses are wrapped in an Envelope once configured
+func TestResponseEnvelope_OK1(t *testing.T) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(),
+			getBytes("init", `{"ResponseEnvelope":true}`)))) {
+		res := stub.MockInvoke("tx1", getBytes("AddOwner", alice))
+		if assert.Condition(t, responseOK(res), res.Message) {
+			assert.JSONEq(t, `{"Function":"AddOwner","TxId":"tx1","Data":null}`, string(res.Payload))
+		}
+
+		res = stub.MockInvoke("tx2", getBytes("GetOwner", aliceid))
+		if assert.Condition(t, responseOK(res), res.Message) {
+			goenvelope := new(cc.Envelope)
+			if assert.NoError(t, json.Unmarshal(res.Payload, goenvelope)) {
+				assert.JSONEq(t, alice, string(goenvelope.Data))
+				assert.Len(t, goenvelope.Warnings, 1)
+			}
+		}
+
+		res = stub.MockInvoke("tx3", getBytes("GetHouse", "9"))
+		if assert.Condition(t, responseFail(res)) {
+			goenvelope := new(cc.Envelope)
+			if assert.NoError(t, json.Unmarshal(res.Payload, goenvelope)) {
+				assert.Equal(t, res.Message, goenvelope.Error)
+				assert.Equal(t, "null", string(goenvelope.Data))
+			}
+		}
+	}
+}
+
+// OK1: the digest covers Owners and Houses only and follows their changes
+func TestGetRegistryDigest_OK1(t *testing.T) {
+	icc := new(identityCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	if assert.NotNil(t, stub) &&
+		assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", alice))
+		stub.MockInvoke(util.GenerateUUID(), getBytes("AddOwner", bob))
+		stub.MockInvoke(util.GenerateUUID(), getBytes("AddHouse", house1))
+
+		digestOf := func() *cc.RegistryDigest {
+			res := stub.MockInvoke(util.GenerateUUID(), getBytes("GetRegistryDigest"))
+			godigest := new(cc.RegistryDigest)
+			if assert.Condition(t, responseOK(res), res.Message) {
+				assert.NoError(t, json.Unmarshal(res.Payload, godigest))
+			}
+			return godigest
+		}
+
+		before := digestOf()
+		assert.Equal(t, 2, before.Owners)
+		assert.Equal(t, 1, before.Houses)
+		assert.Equal(t, before.Root, digestOf().Root)
+
+		res := stub.MockInvoke(util.GenerateUUID(), getBytes("ExportRegistry"))
+		if assert.Condition(t, responseOK(res), res.Message) {
+			goexport := new(cc.RegistryExport)
+			if assert.NoError(t, json.Unmarshal(res.Payload, goexport)) {
+				assert.Len(t, goexport.Records, 3)
+				assert.Equal(t, before.Root, goexport.Digest.Root)
+				assert.Equal(t, before.Root, digest.Root(goexport.Records))
+			}
+		}
+
+		// a transfer changes the root
+		icc.creator = creator(t, "Alice")
+		res = stub.MockInvoke(util.GenerateUUID(), getBytes("TransferHouse", one, bobid))
+		assert.Condition(t, responseOK(res), res.Message)
+		assert.NotEqual(t, before.Root, digestOf().Root)
+	}
+}
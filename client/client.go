@@ -0,0 +1,154 @@
+// Package client calls the house contract from Go services. It exports the
+// types, the function names and the argument and payload encodings of the
+// contract, and depends on nothing but the standard library.
+//
+// A Client runs calls over a Transport. Transports for a live network wrap
+// the SDK of choice; mocktransport runs the contract in process for tests.
+//
+//	c := client.New(transport)
+//	house, err := c.GetHouse("1")
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Transport runs one call of the contract and returns its payload. Failures
+// reported by the contract are returned as *Error.
+type Transport interface {
+	Invoke(function string, args ...string) ([]byte, error)
+}
+
+// Error is a failure reported by the contract
+type Error struct {
+	Function string
+	Status   int32
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Function, e.Message)
+}
+
+// Client calls the functions of the contract with typed arguments and results
+type Client struct {
+	transport Transport
+}
+
+func New(transport Transport) *Client {
+	return &Client{transport}
+}
+
+// Call encodes args, invokes function and decodes its payload into result,
+// which may be nil
+func (c *Client) Call(result interface{}, function string, args ...interface{}) error {
+	encoded, err := EncodeArgs(args...)
+	if err != nil {
+		return fmt.Errorf("%s: %s", function, err)
+	}
+	payload, err := c.transport.Invoke(function, encoded...)
+	if err != nil {
+		return err
+	}
+	return DecodePayload(payload, result)
+}
+
+func (c *Client) AddOwner(owner *Owner) error {
+	return c.Call(nil, FnAddOwner, owner)
+}
+
+func (c *Client) GetOwner(id string) (*Owner, error) {
+	owner := new(Owner)
+	if err := c.Call(owner, FnGetOwner, id); err != nil {
+		return nil, err
+	}
+	return owner, nil
+}
+
+func (c *Client) ListOwners() ([]*Owner, error) {
+	owners := []*Owner{}
+	if err := c.Call(&owners, FnListOwners); err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
+
+// batch runs a batch registration. A rejected batch returns its report
+// together with the error.
+func (c *Client) batch(function string, args ...interface{}) (*BatchReport, error) {
+	report := new(BatchReport)
+	err := c.Call(report, function, args...)
+	if cerr, ok := err.(*Error); ok {
+		if json.Unmarshal([]byte(cerr.Message), report) == nil {
+			return report, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// AddOwnersBatch registers Owners all or none; dryRun only validates them
+func (c *Client) AddOwnersBatch(owners []*Owner, dryRun bool) (*BatchReport, error) {
+	return c.batch(FnAddOwnersBatch, owners, dryRun)
+}
+
+func (c *Client) AddHouse(house *House) error {
+	return c.Call(nil, FnAddHouse, house)
+}
+
+// AddHousesBatch registers Houses all or none; dryRun only validates them
+func (c *Client) AddHousesBatch(houses []*House, dryRun bool) (*BatchReport, error) {
+	return c.batch(FnAddHousesBatch, houses, dryRun)
+}
+
+func (c *Client) GetHouse(id string) (*House, error) {
+	house := new(House)
+	if err := c.Call(house, FnGetHouse, id); err != nil {
+		return nil, err
+	}
+	return house, nil
+}
+
+func (c *Client) UpdateHouse(house *House) error {
+	return c.Call(nil, FnUpdateHouse, house)
+}
+
+// CorrectHouse corrects a House as a registrar, giving the reason
+func (c *Client) CorrectHouse(house *House, reason string) error {
+	return c.Call(nil, FnCorrectHouse, house, reason)
+}
+
+func (c *Client) ListHouses() ([]*House, error) {
+	houses := []*House{}
+	if err := c.Call(&houses, FnListHouses); err != nil {
+		return nil, err
+	}
+	return houses, nil
+}
+
+func (c *Client) ListOwnerHouses(ownerId string) ([]*House, error) {
+	houses := []*House{}
+	if err := c.Call(&houses, FnListOwnerIdHouses, ownerId); err != nil {
+		return nil, err
+	}
+	return houses, nil
+}
+
+func (c *Client) ListHouseEdits(houseId string) ([]*HouseEdit, error) {
+	edits := []*HouseEdit{}
+	if err := c.Call(&edits, FnListHouseEdits, houseId); err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// TransferHouse transfers a House to newOwnerId; brokers may be nil
+func (c *Client) TransferHouse(houseId string, newOwnerId string, brokers *SaleBrokers) error {
+	if brokers == nil {
+		return c.Call(nil, FnTransferHouse, houseId, newOwnerId)
+	}
+	return c.Call(nil, FnTransferHouse, houseId, newOwnerId, brokers)
+}
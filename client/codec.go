@@ -0,0 +1,79 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Envelope is the payload of every response of a contract configured with
+// ResponseEnvelope
+type Envelope struct {
+	Function string
+	TxId     string
+	Data     json.RawMessage
+	Error    string   `json:",omitempty"`
+	Warnings []string `json:",omitempty"`
+}
+
+// EncodeArg encodes one argument following the calling convention of the
+// contract: strings plain, timestamps as RFC 3339, everything else as JSON
+func EncodeArg(v interface{}) (string, error) {
+	switch arg := v.(type) {
+	case string:
+		return arg, nil
+	case time.Time:
+		return arg.Format(time.RFC3339Nano), nil
+	}
+	jsonarg, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonarg), nil
+}
+
+// EncodeArgs encodes the arguments of one call
+func EncodeArgs(args ...interface{}) ([]string, error) {
+	encoded := make([]string, 0, len(args))
+	for i, v := range args {
+		arg, err := EncodeArg(v)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %s", i+1, err)
+		}
+		encoded = append(encoded, arg)
+	}
+	return encoded, nil
+}
+
+// isEnvelope tells an Envelope from a plain payload
+func isEnvelope(payload []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return false
+	}
+	_, function := fields["Function"]
+	_, txid := fields["TxId"]
+	_, data := fields["Data"]
+	return function && txid && data
+}
+
+// DecodePayload decodes a response payload into v, unwrapping an Envelope.
+// v may be nil for functions that return nothing.
+func DecodePayload(payload []byte, v interface{}) error {
+	if isEnvelope(payload) {
+		envelope := new(Envelope)
+		if err := json.Unmarshal(payload, envelope); err != nil {
+			return err
+		}
+		if envelope.Error != "" {
+			return &Error{Function: envelope.Function, Message: envelope.Error}
+		}
+		payload = envelope.Data
+	}
+
+	if v == nil || len(bytes.TrimSpace(payload)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(payload, v)
+}
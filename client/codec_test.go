@@ -0,0 +1,45 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// OK1: strings pass plain, timestamps as RFC 3339 and the rest as JSON
+func TestEncodeArgs_OK1(t *testing.T) {
+	args, err := EncodeArgs("1", &Owner{Id: "Alice"}, true, 5,
+		time.Date(2018, 1, 1, 12, 34, 56, 0, time.UTC))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"1", `{"Id":"Alice"}`, "true", "5", "2018-01-01T12:34:56Z"}, args)
+	}
+
+	_, err = EncodeArgs(func() {})
+	assert.Error(t, err)
+}
+
+// OK1: plain and enveloped payloads decode alike
+func TestDecodePayload_OK1(t *testing.T) {
+	for _, payload := range []string{
+		`{"Id":"Alice","Type":"corporate"}`,
+		`{"Function":"GetOwner","TxId":"tx1","Data":{"Id":"Alice","Type":"corporate"}}`,
+	} {
+		owner := new(Owner)
+		if assert.NoError(t, DecodePayload([]byte(payload), owner), payload) {
+			assert.Equal(t, &Owner{Id: "Alice", Type: OwnerTypeCorporate}, owner)
+		}
+	}
+
+	assert.NoError(t, DecodePayload([]byte{}, new(Owner)))
+	assert.NoError(t, DecodePayload([]byte(`{"Function":"AddOwner","TxId":"tx1","Data":null}`), nil))
+}
+
+// NG1: enveloped errors and malformed payloads fail
+func TestDecodePayload_NG1(t *testing.T) {
+	err := DecodePayload([]byte(`{"Function":"GetHouse","TxId":"tx1","Data":null,"Error":"not found"}`), new(House))
+	if assert.IsType(t, &Error{}, err) {
+		assert.Equal(t, "GetHouse failed: not found", err.Error())
+	}
+	assert.Error(t, DecodePayload([]byte(`{"Id":`), new(House)))
+}
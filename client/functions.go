@@ -0,0 +1,105 @@
+package client
+
+// Names of the Invoke functions of the contract
+const (
+	FnAddOwner       = "AddOwner"
+	FnGetOwner       = "GetOwner"
+	FnListOwners     = "ListOwners"
+	FnAddOwnersBatch = "AddOwnersBatch"
+
+	FnAddHouse          = "AddHouse"
+	FnAddHousesBatch    = "AddHousesBatch"
+	FnListHouses        = "ListHouses"
+	FnListOwnerIdHouses = "ListOwnerIdHouses"
+	FnGetHouse          = "GetHouse"
+	FnUpdateHouse       = "UpdateHouse"
+	FnCorrectHouse      = "CorrectHouse"
+	FnListHouseEdits    = "ListHouseEdits"
+	FnTransferHouse     = "TransferHouse"
+
+	FnUpdateTaxSchedule         = "UpdateTaxSchedule"
+	FnGetTaxSchedule            = "GetTaxSchedule"
+	FnListOwnerTransferReceipts = "ListOwnerTransferReceipts"
+	FnListTransferReceipts      = "ListTransferReceipts"
+
+	FnSubmitAppraisal    = "SubmitAppraisal"
+	FnListAppraisals     = "ListAppraisals"
+	FnGetLatestValuation = "GetLatestValuation"
+
+	FnAttachDocument = "AttachDocument"
+	FnVerifyDocument = "VerifyDocument"
+
+	FnRequestTransferApproval = "RequestTransferApproval"
+	FnApprove                 = "Approve"
+	FnRevokeApproval          = "RevokeApproval"
+	FnGetApproval             = "GetApproval"
+
+	FnFreezeHouse   = "FreezeHouse"
+	FnUnfreezeHouse = "UnfreezeHouse"
+	FnListHolds     = "ListHolds"
+
+	FnRecordDeath      = "RecordDeath"
+	FnDistributeEstate = "DistributeEstate"
+	FnGetEstate        = "GetEstate"
+
+	FnSetSignatory         = "SetSignatory"
+	FnRemoveSignatory      = "RemoveSignatory"
+	FnListSignatories      = "ListSignatories"
+	FnListSignatoryChanges = "ListSignatoryChanges"
+
+	FnGrantDelegation  = "GrantDelegation"
+	FnRevokeDelegation = "RevokeDelegation"
+	FnListDelegations  = "ListDelegations"
+
+	FnRegisterBroker     = "RegisterBroker"
+	FnGetBroker          = "GetBroker"
+	FnListHouseForSale   = "ListHouseForSale"
+	FnWithdrawListing    = "WithdrawListing"
+	FnListBrokerListings = "ListBrokerListings"
+	FnListBrokerDeals    = "ListBrokerDeals"
+
+	FnProposeLease       = "ProposeLease"
+	FnAcceptLease        = "AcceptLease"
+	FnGetLease           = "GetLease"
+	FnRecordRentPayment  = "RecordRentPayment"
+	FnConfirmRentPayment = "ConfirmRentPayment"
+	FnListRentPayments   = "ListRentPayments"
+	FnGetRentSchedule    = "GetRentSchedule"
+	FnListTenantArrears  = "ListTenantArrears"
+	FnListHouseArrears   = "ListHouseArrears"
+
+	FnGetRegistryDigest = "GetRegistryDigest"
+	FnExportRegistry    = "ExportRegistry"
+
+	FnListStaleKeys      = "ListStaleKeys"
+	FnMigrateBatch       = "MigrateBatch"
+	FnGetMigrationStatus = "GetMigrationStatus"
+	FnUpdateConfig       = "UpdateConfig"
+	FnGetConfig          = "GetConfig"
+	FnGrantRole          = "GrantRole"
+	FnRevokeRole         = "RevokeRole"
+	FnEnroll             = "Enroll"
+)
+
+// Functions lists every Invoke function of the contract
+var Functions = []string{
+	FnAddOwner, FnGetOwner, FnListOwners, FnAddOwnersBatch,
+	FnAddHouse, FnAddHousesBatch, FnListHouses, FnListOwnerIdHouses, FnGetHouse,
+	FnUpdateHouse, FnCorrectHouse, FnListHouseEdits, FnTransferHouse,
+	FnUpdateTaxSchedule, FnGetTaxSchedule, FnListOwnerTransferReceipts, FnListTransferReceipts,
+	FnSubmitAppraisal, FnListAppraisals, FnGetLatestValuation,
+	FnAttachDocument, FnVerifyDocument,
+	FnRequestTransferApproval, FnApprove, FnRevokeApproval, FnGetApproval,
+	FnFreezeHouse, FnUnfreezeHouse, FnListHolds,
+	FnRecordDeath, FnDistributeEstate, FnGetEstate,
+	FnSetSignatory, FnRemoveSignatory, FnListSignatories, FnListSignatoryChanges,
+	FnGrantDelegation, FnRevokeDelegation, FnListDelegations,
+	FnRegisterBroker, FnGetBroker, FnListHouseForSale, FnWithdrawListing,
+	FnListBrokerListings, FnListBrokerDeals,
+	FnProposeLease, FnAcceptLease, FnGetLease, FnRecordRentPayment,
+	FnConfirmRentPayment, FnListRentPayments,
+	FnGetRentSchedule, FnListTenantArrears, FnListHouseArrears,
+	FnGetRegistryDigest, FnExportRegistry,
+	FnListStaleKeys, FnMigrateBatch, FnGetMigrationStatus, FnUpdateConfig, FnGetConfig,
+	FnGrantRole, FnRevokeRole, FnEnroll,
+}
@@ -0,0 +1,90 @@
+// Package mocktransport runs the house contract in process on a
+// shim.MockStub, as a client.Transport for tests
+package mocktransport
+
+import (
+	"fmt"
+	"housecontract/cc"
+	"housecontract/client"
+	"housecontract/scenario"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// mspid is the organization the invokers of a Transport belong to
+const mspid = "Org1MSP"
+
+// Transport invokes the chaincode on its MockStub
+type Transport struct {
+	Stub *shim.MockStub
+	icc  *invokerCC
+}
+
+// invokerCC runs HouseContractCC with the creator set by As, which
+// shim.MockStub does not let us set
+type invokerCC struct {
+	cc.HouseContractCC
+	creator []byte
+}
+
+type invokerStub struct {
+	shim.ChaincodeStubInterface
+	creator []byte
+}
+
+func (s *invokerStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (t *invokerCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.HouseContractCC.Init(&invokerStub{stub, t.creator})
+}
+
+func (t *invokerCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.HouseContractCC.Invoke(&invokerStub{stub, t.creator})
+}
+
+// New initialises HouseContractCC on a new MockStub; config is the Init
+// configuration, none if empty
+func New(config string) (*Transport, error) {
+	icc := new(invokerCC)
+	stub := shim.NewMockStub("housecontract", icc)
+	var args [][]byte
+	if config != "" {
+		args = [][]byte{[]byte("init"), []byte(config)}
+	}
+	res := stub.MockInit(util.GenerateUUID(), args)
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return nil, fmt.Errorf("Init failed: %s", res.Message)
+	}
+	return &Transport{stub, icc}, nil
+}
+
+// As makes the later invocations as the enrollment ID id of Org1MSP, or
+// without an identity if id is empty
+func (t *Transport) As(id string) error {
+	if id == "" {
+		t.icc.creator = nil
+		return nil
+	}
+	creator, err := scenario.Creator(id, mspid)
+	if err != nil {
+		return err
+	}
+	t.icc.creator = creator
+	return nil
+}
+
+func (t *Transport) Invoke(function string, args ...string) ([]byte, error) {
+	ccargs := [][]byte{[]byte(function)}
+	for _, arg := range args {
+		ccargs = append(ccargs, []byte(arg))
+	}
+	res := t.Stub.MockInvoke(util.GenerateUUID(), ccargs)
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return nil, &client.Error{Function: function, Status: res.Status, Message: res.Message}
+	}
+	return res.Payload, nil
+}
@@ -0,0 +1,113 @@
+package mocktransport
+
+import (
+	"housecontract/cc"
+	"housecontract/client"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var timestamp = time.Date(2018, 1, 1, 12, 34, 56, 0, time.UTC)
+
+// OK1: the client types encode like the types of cc
+func TestTypes_OK1(t *testing.T) {
+	for _, pair := range [][2]interface{}{
+		{cc.Owner{}, client.Owner{}},
+		{cc.House{}, client.House{}},
+		{cc.Share{}, client.Share{}},
+		{cc.FieldChange{}, client.FieldChange{}},
+		{cc.HouseEdit{}, client.HouseEdit{}},
+		{cc.SaleBrokers{}, client.SaleBrokers{}},
+		{cc.BatchItemError{}, client.BatchItemError{}},
+		{cc.BatchReport{}, client.BatchReport{}},
+		{cc.Envelope{}, client.Envelope{}},
+	} {
+		ccType, clientType := reflect.TypeOf(pair[0]), reflect.TypeOf(pair[1])
+		if !assert.Equal(t, ccType.NumField(), clientType.NumField(), ccType.Name()) {
+			continue
+		}
+		for i := 0; i < ccType.NumField(); i++ {
+			ccField, clientField := ccType.Field(i), clientType.Field(i)
+			assert.Equal(t, ccField.Name, clientField.Name, ccType.Name())
+			assert.Equal(t, ccField.Tag.Get("json"), clientField.Tag.Get("json"), ccField.Name)
+			// named types of cc and client differ in package only
+			assert.Equal(t, strings.Replace(ccField.Type.String(), "cc.", "client.", -1),
+				clientField.Type.String(), ccField.Name)
+		}
+	}
+}
+
+// OK1: every function name is dispatched by Invoke
+func TestFunctions_OK1(t *testing.T) {
+	transport, err := New("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, function := range client.Functions {
+		_, err := transport.Invoke(function)
+		if err != nil {
+			assert.NotContains(t, err.Error(), "Unknown method", function)
+		}
+	}
+}
+
+// OK1: a typed round trip through the registry
+func TestClient_OK1(t *testing.T) {
+	for _, config := range []string{"", `{"ResponseEnvelope":true}`} {
+		transport, err := New(config)
+		if !assert.NoError(t, err) {
+			return
+		}
+		c := client.New(transport)
+
+		report, err := c.AddOwnersBatch([]*client.Owner{{Id: "Alice", Org: "Org1MSP"}, {Id: "Bob", Org: "Org1MSP"}}, false)
+		if assert.NoError(t, err, config) {
+			assert.Equal(t, 2, report.Added)
+		}
+		house := &client.House{Id: "1", Address: "seoul", OwnerId: "Alice", Price: "3000", Timestamp: timestamp}
+		assert.NoError(t, c.AddHouse(house), config)
+
+		got, err := c.GetHouse("1")
+		if assert.NoError(t, err, config) {
+			assert.Equal(t, house, got)
+		}
+
+		assert.NoError(t, transport.As("Bob"))
+		assert.Error(t, c.TransferHouse("1", "Bob", nil), config)
+		assert.NoError(t, transport.As("Alice"))
+		assert.NoError(t, c.TransferHouse("1", "Bob", nil), config)
+		houses, err := c.ListOwnerHouses("Bob")
+		if assert.NoError(t, err, config) && assert.Len(t, houses, 1) {
+			assert.Equal(t, "1", houses[0].Id)
+		}
+		owners, err := c.ListOwners()
+		if assert.NoError(t, err, config) {
+			assert.Len(t, owners, 2)
+		}
+	}
+}
+
+// NG1: contract failures come back as *client.Error, rejected batches with
+// their report
+func TestClient_NG1(t *testing.T) {
+	transport, err := New("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	c := client.New(transport)
+
+	_, err = c.GetHouse("9")
+	if assert.IsType(t, &client.Error{}, err) {
+		assert.Equal(t, client.FnGetHouse, err.(*client.Error).Function)
+	}
+
+	report, err := c.AddHousesBatch([]*client.House{{Id: "1", OwnerId: "Nobody", Timestamp: timestamp}}, false)
+	if assert.Error(t, err) && assert.NotNil(t, report) {
+		assert.Len(t, report.Failed, 1)
+		assert.Equal(t, 0, report.Added)
+	}
+}
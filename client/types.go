@@ -0,0 +1,77 @@
+package client
+
+import "time"
+
+// The types below mirror the JSON documents of the contract. They are kept
+// free of any chaincode dependency; mocktransport checks that they stay in
+// sync with package cc.
+
+// Owner is a registered owner of Houses
+type Owner struct {
+	Id   string
+	Type string `json:",omitempty"` //person (default) or corporate
+	Org  string `json:",omitempty"` //MSP ID of the organization the Owner belongs to
+}
+
+// Owner types
+const (
+	OwnerTypePerson    = "person"
+	OwnerTypeCorporate = "corporate"
+)
+
+// House is a registered House
+type House struct {
+	Id        string
+	Address   string
+	OwnerId   string
+	Price     string
+	Timestamp time.Time
+	Shares    []*Share `json:",omitempty"` //co-owners; OwnerId is the largest of them
+}
+
+// Share is the part of a House held by one co-owner
+type Share struct {
+	OwnerId     string
+	Numerator   int64
+	Denominator int64
+}
+
+// FieldChange is one changed field of a HouseEdit
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// HouseEdit is the audit record of an edit of a House
+type HouseEdit struct {
+	HouseId    string
+	Kind       string //update (owner), correction (registrar), estate-lock or inheritance
+	EditorId   string
+	OnBehalfOf string `json:",omitempty"` //the Owner an agent or signatory edited for
+	Reason     string
+	Changes    []FieldChange
+	TxId       string
+	Timestamp  time.Time
+}
+
+// SaleBrokers names the brokers of a transfer
+type SaleBrokers struct {
+	SellerBrokerId string
+	BuyerBrokerId  string
+}
+
+// BatchItemError reports why a single batch item was rejected
+type BatchItemError struct {
+	Index int
+	Id    string
+	Error string
+}
+
+// BatchReport is the outcome of a batch registration
+type BatchReport struct {
+	DryRun bool
+	Total  int
+	Added  int
+	Failed []*BatchItemError
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"housecontract/cc"
+	"housecontract/digest"
+	"os"
+	"strings"
+)
+
+// runDump writes every Owner and House record with their digest to a file
+func runDump(args []string) error {
+	flags := newClientFlags("dump")
+	outPath := flags.String("out", "registry-export.json", "write the export to this file")
+	flags.Parse(args)
+	t, err := flags.target()
+	if err != nil {
+		return err
+	}
+
+	payload, err := t.invoke("ExportRegistry")
+	if err != nil {
+		return err
+	}
+	goexport := new(cc.RegistryExport)
+	if err := json.Unmarshal(payload, goexport); err != nil {
+		return err
+	}
+	if err := verifyExport(goexport); err != nil {
+		return fmt.Errorf("export does not match its own digest: %s", err)
+	}
+	if err := writeJSONFile(*outPath, goexport); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d Owners and %d Houses exported at %s, root %s\n",
+		goexport.Digest.Owners, goexport.Digest.Houses, goexport.Digest.TxId, goexport.Digest.Root)
+	return nil
+}
+
+// verifyExport recomputes the digest of the records of an export
+func verifyExport(goexport *cc.RegistryExport) error {
+	godigest := goexport.Digest
+	if godigest == nil {
+		return errors.New("no digest")
+	}
+	if godigest.Algorithm != digest.Algorithm {
+		return fmt.Errorf("unknown digest algorithm %q", godigest.Algorithm)
+	}
+
+	owners, houses := 0, 0
+	for _, record := range goexport.Records {
+		switch {
+		case strings.HasPrefix(record.Key, "\x00Owner\x00"):
+			owners++
+		case strings.HasPrefix(record.Key, "\x00House\x00"):
+			houses++
+		default:
+			return fmt.Errorf("unexpected record %q", record.Key)
+		}
+	}
+	if owners != godigest.Owners || houses != godigest.Houses {
+		return fmt.Errorf("%d Owners and %d Houses found, %d and %d expected",
+			owners, houses, godigest.Owners, godigest.Houses)
+	}
+
+	if root := digest.Root(goexport.Records); root != godigest.Root {
+		return fmt.Errorf("root %s computed, %s expected", root, godigest.Root)
+	}
+	return nil
+}
+
+// runVerify checks an export against its digest and, with -live, against
+// the current digest of the ledger
+func runVerify(args []string) error {
+	flags := newClientFlags("verify")
+	inPath := flags.String("in", "registry-export.json", "export written by dump")
+	live := flags.Bool("live", false, "also compare with the digest of the ledger now")
+	flags.Parse(args)
+
+	goexport := new(cc.RegistryExport)
+	if err := readJSONFile(*inPath, goexport); err != nil {
+		return fmt.Errorf("%s: %s", *inPath, err)
+	}
+	if err := verifyExport(goexport); err != nil {
+		return fmt.Errorf("%s: %s", *inPath, err)
+	}
+	fmt.Printf("%s: %d Owners and %d Houses match root %s\n",
+		*inPath, goexport.Digest.Owners, goexport.Digest.Houses, goexport.Digest.Root)
+
+	if !*live {
+		return nil
+	}
+	t, err := flags.target()
+	if err != nil {
+		return err
+	}
+	payload, err := t.invoke("GetRegistryDigest")
+	if err != nil {
+		return err
+	}
+	current := new(cc.RegistryDigest)
+	if err := json.Unmarshal(payload, current); err != nil {
+		return err
+	}
+	if current.Root != goexport.Digest.Root {
+		return fmt.Errorf("the ledger has changed: root %s at %s, %s in the export",
+			current.Root, current.TxId, goexport.Digest.Root)
+	}
+	fmt.Printf("the ledger still matches at %s\n", current.TxId)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"housecontract/cc"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// export returns an export of a local ledger holding Alice and her House
+func export(t *testing.T) *cc.RegistryExport {
+	target, err := newStateTarget(filepath.Join(t.TempDir(), "state.json"), "", "", "Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"AddOwner", `{"Id":"Alice","Org":"Org1MSP"}`},
+		{"AddHouse", `{"Id":"1", "Address":"seoul", "OwnerId":"Alice","Price":"3000", "Timestamp":"2018-01-01T12:34:56Z"}`},
+	} {
+		if _, err := target.invoke(args[0], args[1:]...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	payload, err := target.invoke("ExportRegistry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	goexport := new(cc.RegistryExport)
+	if err := json.Unmarshal(payload, goexport); err != nil {
+		t.Fatal(err)
+	}
+	return goexport
+}
+
+// OK1: an export matches its digest
+func TestVerifyExport_OK1(t *testing.T) {
+	goexport := export(t)
+	assert.NoError(t, verifyExport(goexport))
+
+	// the file round trip keeps the records byte for byte
+	path := filepath.Join(t.TempDir(), "export.json")
+	read := new(cc.RegistryExport)
+	if assert.NoError(t, writeJSONFile(path, goexport)) && assert.NoError(t, readJSONFile(path, read)) {
+		assert.NoError(t, verifyExport(read))
+	}
+}
+
+// NG1: tampered exports fail
+func TestVerifyExport_NG1(t *testing.T) {
+	goexport := export(t)
+	goexport.Records[0].Value = goexport.Records[0].Value + " "
+	assert.Error(t, verifyExport(goexport))
+
+	goexport = export(t)
+	goexport.Records = goexport.Records[1:]
+	assert.Error(t, verifyExport(goexport))
+
+	goexport = export(t)
+	goexport.Digest.Algorithm = "md5"
+	assert.Error(t, verifyExport(goexport))
+}
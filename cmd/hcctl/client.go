@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"housecontract/cc"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// clientFlags are the flags shared by the owner, house and history commands
+type clientFlags struct {
+	*flag.FlagSet
+	state   *string
+	config  *string
+	gateway *string
+	invoker *string
+	mspid   *string
+	output  *string
+}
+
+func newClientFlags(name string) *clientFlags {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	return &clientFlags{
+		FlagSet: flags,
+		state:   flags.String("state", "hcctl-state.json", "local ledger state file, created if missing"),
+		config:  flags.String("config", "", "Init configuration (JSON file) for a new state file"),
+		gateway: flags.String("gateway", os.Getenv("HCCTL_GATEWAY"), "hcgateway URL; overrides -state"),
+		invoker: flags.String("as", "", "enrollment ID to invoke as"),
+		mspid:   flags.String("mspid", "Org1MSP", "MSP ID of the invoker on the local state"),
+		output:  flags.String("o", "table", "output format: table, json or csv"),
+	}
+}
+
+func (f *clientFlags) target() (target, error) {
+	switch *f.output {
+	case "table", "json", "csv":
+	default:
+		return nil, fmt.Errorf("unknown output format %q", *f.output)
+	}
+
+	if *f.gateway != "" {
+		return newGatewayTarget(*f.gateway, *f.invoker), nil
+	}
+	var config []byte
+	if *f.config != "" {
+		var err error
+		config, err = ioutil.ReadFile(*f.config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newStateTarget(*f.state, string(config), *f.invoker, *f.mspid)
+}
+
+// required fails unless every named flag has a value
+func required(flags *clientFlags, names ...string) error {
+	for _, name := range names {
+		if flags.Lookup(name).Value.String() == "" {
+			return fmt.Errorf("-%s is required", name)
+		}
+	}
+	return nil
+}
+
+func runOwner(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("owner: add or list expected")
+	}
+	flags := newClientFlags("owner " + args[0])
+
+	switch args[0] {
+	case "add":
+		id := flags.String("id", "", "Owner Id")
+		ownerType := flags.String("type", "", "person (default) or corporate")
+		org := flags.String("org", "", "MSP ID of the organization of the Owner")
+		flags.Parse(args[1:])
+		if err := required(flags, "id"); err != nil {
+			return err
+		}
+		t, err := flags.target()
+		if err != nil {
+			return err
+		}
+
+		jsonowner, err := json.Marshal(&cc.Owner{Id: *id, Type: *ownerType, Org: *org})
+		if err != nil {
+			return err
+		}
+		if _, err := t.invoke("AddOwner", string(jsonowner)); err != nil {
+			return err
+		}
+		payload, err := t.invoke("GetOwner", *id)
+		if err != nil {
+			return err
+		}
+		goowner := new(cc.Owner)
+		if err := json.Unmarshal(payload, goowner); err != nil {
+			return err
+		}
+		return printOwners(os.Stdout, *flags.output, []*cc.Owner{goowner})
+
+	case "list":
+		flags.Parse(args[1:])
+		t, err := flags.target()
+		if err != nil {
+			return err
+		}
+		payload, err := t.invoke("ListOwners")
+		if err != nil {
+			return err
+		}
+		goowners := []*cc.Owner{}
+		if err := json.Unmarshal(payload, &goowners); err != nil {
+			return err
+		}
+		return printOwners(os.Stdout, *flags.output, goowners)
+	}
+	return fmt.Errorf("owner: unknown command %q", args[0])
+}
+
+// getHouse fetches a House and decodes it
+func getHouse(t target, id string) (*cc.House, error) {
+	payload, err := t.invoke("GetHouse", id)
+	if err != nil {
+		return nil, err
+	}
+	gohouse := new(cc.House)
+	return gohouse, json.Unmarshal(payload, gohouse)
+}
+
+// submitHouse runs a function on a House and prints the House afterwards
+func submitHouse(t target, output string, id string, function string, args ...string) error {
+	if _, err := t.invoke(function, args...); err != nil {
+		return err
+	}
+	gohouse, err := getHouse(t, id)
+	if err != nil {
+		return err
+	}
+	return printHouses(os.Stdout, output, []*cc.House{gohouse})
+}
+
+func runHouse(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("house: add, get, update, list or transfer expected")
+	}
+	flags := newClientFlags("house " + args[0])
+
+	switch args[0] {
+	case "add":
+		id := flags.String("id", "", "House Id")
+		address := flags.String("address", "", "address of the House")
+		ownerId := flags.String("owner", "", "Id of the Owner")
+		price := flags.String("price", "", "price of the House")
+		timestamp := flags.String("timestamp", "", "registration time (RFC 3339); now if empty")
+		flags.Parse(args[1:])
+		if err := required(flags, "id", "owner"); err != nil {
+			return err
+		}
+		gohouse := &cc.House{Id: *id, Address: *address, OwnerId: *ownerId, Price: *price,
+			Timestamp: time.Now().UTC().Truncate(time.Second)}
+		if *timestamp != "" {
+			var err error
+			gohouse.Timestamp, err = time.Parse(time.RFC3339, *timestamp)
+			if err != nil {
+				return err
+			}
+		}
+		t, err := flags.target()
+		if err != nil {
+			return err
+		}
+
+		jsonhouse, err := json.Marshal(gohouse)
+		if err != nil {
+			return err
+		}
+		return submitHouse(t, *flags.output, *id, "AddHouse", string(jsonhouse))
+
+	case "get":
+		id := flags.String("id", "", "House Id")
+		flags.Parse(args[1:])
+		if err := required(flags, "id"); err != nil {
+			return err
+		}
+		t, err := flags.target()
+		if err != nil {
+			return err
+		}
+		gohouse, err := getHouse(t, *id)
+		if err != nil {
+			return err
+		}
+		return printHouses(os.Stdout, *flags.output, []*cc.House{gohouse})
+
+	case "update":
+		id := flags.String("id", "", "House Id")
+		address := flags.String("address", "", "new address")
+		price := flags.String("price", "", "new price")
+		flags.Parse(args[1:])
+		if err := required(flags, "id"); err != nil {
+			return err
+		}
+		t, err := flags.target()
+		if err != nil {
+			return err
+		}
+
+		// only the given fields change
+		gohouse, err := getHouse(t, *id)
+		if err != nil {
+			return err
+		}
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "address":
+				gohouse.Address = *address
+			case "price":
+				gohouse.Price = *price
+			}
+		})
+		jsonhouse, err := json.Marshal(gohouse)
+		if err != nil {
+			return err
+		}
+		return submitHouse(t, *flags.output, *id, "UpdateHouse", string(jsonhouse))
+
+	case "list":
+		ownerId := flags.String("owner", "", "list only the Houses of this Owner")
+		flags.Parse(args[1:])
+		t, err := flags.target()
+		if err != nil {
+			return err
+		}
+
+		var payload []byte
+		if *ownerId != "" {
+			payload, err = t.invoke("ListOwnerIdHouses", *ownerId)
+		} else {
+			payload, err = t.invoke("ListHouses")
+		}
+		if err != nil {
+			return err
+		}
+		gohouses := []*cc.House{}
+		if err := json.Unmarshal(payload, &gohouses); err != nil {
+			return err
+		}
+		return printHouses(os.Stdout, *flags.output, gohouses)
+
+	case "transfer":
+		id := flags.String("id", "", "House Id")
+		to := flags.String("to", "", "Id of the new Owner")
+		sellerBroker := flags.String("seller-broker", "", "Id of the broker of the seller")
+		buyerBroker := flags.String("buyer-broker", "", "Id of the broker of the buyer")
+		flags.Parse(args[1:])
+		if err := required(flags, "id", "to"); err != nil {
+			return err
+		}
+		t, err := flags.target()
+		if err != nil {
+			return err
+		}
+
+		transferArgs := []string{*id, *to}
+		if *sellerBroker != "" || *buyerBroker != "" {
+			jsonbrokers, err := json.Marshal(&cc.SaleBrokers{
+				SellerBrokerId: *sellerBroker,
+				BuyerBrokerId:  *buyerBroker,
+			})
+			if err != nil {
+				return err
+			}
+			transferArgs = append(transferArgs, string(jsonbrokers))
+		}
+		return submitHouse(t, *flags.output, *id, "TransferHouse", transferArgs...)
+	}
+	return fmt.Errorf("house: unknown command %q", args[0])
+}
+
+func runHistory(args []string) error {
+	flags := newClientFlags("history")
+	id := flags.String("id", "", "House Id")
+	flags.Parse(args)
+	if err := required(flags, "id"); err != nil {
+		return err
+	}
+	t, err := flags.target()
+	if err != nil {
+		return err
+	}
+
+	payload, err := t.invoke("ListHouseEdits", *id)
+	if err != nil {
+		return err
+	}
+	goedits := []*cc.HouseEdit{}
+	if err := json.Unmarshal(payload, &goedits); err != nil {
+		return err
+	}
+	return printEdits(os.Stdout, *flags.output, goedits)
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable aligns the rows under the header
+func printTable(w io.Writer, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func printOwners(w io.Writer, output string, goowners []*cc.Owner) error {
+	switch output {
+	case "json":
+		return printJSON(w, goowners)
+	case "csv":
+		return writeOwnersCSV(w, goowners)
+	}
+	rows := [][]string{}
+	for _, goowner := range goowners {
+		rows = append(rows, []string{goowner.Id, goowner.Type, goowner.Org})
+	}
+	return printTable(w, []string{"ID", "TYPE", "ORG"}, rows)
+}
+
+func printHouses(w io.Writer, output string, gohouses []*cc.House) error {
+	switch output {
+	case "json":
+		return printJSON(w, gohouses)
+	case "csv":
+		return writeHousesCSV(w, gohouses)
+	}
+	rows := [][]string{}
+	for _, gohouse := range gohouses {
+		rows = append(rows, []string{gohouse.Id, gohouse.Address, gohouse.OwnerId, gohouse.Price,
+			gohouse.Timestamp.Format(time.RFC3339)})
+	}
+	return printTable(w, []string{"ID", "ADDRESS", "OWNER", "PRICE", "TIMESTAMP"}, rows)
+}
+
+// formatChanges renders changes as `Field: old -> new; ...`
+func formatChanges(changes []cc.FieldChange) string {
+	parts := []string{}
+	for _, change := range changes {
+		parts = append(parts, fmt.Sprintf("%s: %s -> %s", change.Field, change.Old, change.New))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func printEdits(w io.Writer, output string, goedits []*cc.HouseEdit) error {
+	header := []string{"Timestamp", "Kind", "EditorId", "OnBehalfOf", "Reason", "Changes", "TxId"}
+	rows := [][]string{}
+	for _, goedit := range goedits {
+		rows = append(rows, []string{goedit.Timestamp.Format(time.RFC3339), goedit.Kind,
+			goedit.EditorId, goedit.OnBehalfOf, goedit.Reason, formatChanges(goedit.Changes), goedit.TxId})
+	}
+
+	switch output {
+	case "json":
+		return printJSON(w, goedits)
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		writer.WriteAll(rows)
+		return writer.Error()
+	}
+	for i := range header {
+		header[i] = strings.ToUpper(header[i])
+	}
+	return printTable(w, header, rows)
+}
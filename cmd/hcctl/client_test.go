@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"housecontract/cc"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// OK1: the state file keeps the ledger between targets
+func TestStateTarget_OK1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	target, err := newStateTarget(path, "", "", "Org1MSP")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = target.invoke("AddOwner", `{"Id":"Alice","Org":"Org1MSP"}`)
+	assert.NoError(t, err)
+	_, err = target.invoke("AddHouse",
+		`{"Id":"1", "Address":"seoul", "OwnerId":"Alice","Price":"3000", "Timestamp":"2018-01-01T12:34:56Z"}`)
+	assert.NoError(t, err)
+
+	target, err = newStateTarget(path, "", "Alice", "Org1MSP")
+	if !assert.NoError(t, err) {
+		return
+	}
+	gohouse, err := getHouse(target, "1")
+	if assert.NoError(t, err) {
+		gohouse.Price = "3500"
+		jsonhouse, _ := json.Marshal(gohouse)
+		_, err = target.invoke("UpdateHouse", string(jsonhouse))
+		assert.NoError(t, err)
+	}
+
+	payload, err := target.invoke("ListHouseEdits", "1")
+	if assert.NoError(t, err) {
+		goedits := []*cc.HouseEdit{}
+		json.Unmarshal(payload, &goedits)
+		if assert.Len(t, goedits, 1) {
+			assert.Equal(t, "Alice", goedits[0].EditorId)
+		}
+	}
+}
+
+// NG1: failed transactions are reported and leave the state file alone
+func TestStateTarget_NG1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	target, err := newStateTarget(path, "", "", "Org1MSP")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = target.invoke("AddHouse", `{"Id":"1", "OwnerId":"Alice"}`)
+	assert.Error(t, err)
+	_, err = getHouse(target, "1")
+	assert.Error(t, err)
+
+	_, err = newStateTarget(filepath.Join(t.TempDir(), "new.json"), `{"Admins":`, "", "Org1MSP")
+	assert.Error(t, err)
+}
+
+// OK1: functions map to gateway resources
+func TestRoute_OK1(t *testing.T) {
+	for _, c := range []struct {
+		function string
+		args     []string
+		method   string
+		path     string
+		body     string
+	}{
+		{"ListOwners", nil, http.MethodGet, "/owners", ""},
+		{"GetOwner", []string{"Kim Lee"}, http.MethodGet, "/owners/Kim%20Lee", ""},
+		{"ListOwnerIdHouses", []string{"Alice"}, http.MethodGet, "/owners/Alice/houses", ""},
+		{"UpdateHouse", []string{`{"Id":"1"}`}, http.MethodPut, "/houses/1", `{"Id":"1"}`},
+		{"ListHouseEdits", []string{"1"}, http.MethodGet, "/houses/1/edits", ""},
+		{"TransferHouse", []string{"1", "Bob", `{"SellerBrokerId":"B1"}`},
+			http.MethodPost, "/houses/1/transfer", `{"BuyerBrokerId":"","NewOwnerId":"Bob","SellerBrokerId":"B1"}`},
+	} {
+		method, path, body, err := route(c.function, c.args)
+		if assert.NoError(t, err, c.function) {
+			assert.Equal(t, c.method, method, c.function)
+			assert.Equal(t, c.path, path, c.function)
+			assert.Equal(t, c.body, string(body), c.function)
+		}
+	}
+
+	_, _, _, err := route("FreezeHouse", []string{"1"})
+	assert.Error(t, err)
+	_, _, _, err = route("GetHouse", nil)
+	assert.Error(t, err)
+}
+
+// OK1: results print as a table, JSON or CSV
+func TestPrintHouses_OK1(t *testing.T) {
+	gohouses := []*cc.House{{Id: "1", Address: "seoul", OwnerId: "Alice", Price: "3000",
+		Timestamp: time.Date(2018, 1, 1, 12, 34, 56, 0, time.UTC)}}
+
+	var buf bytes.Buffer
+	if assert.NoError(t, printHouses(&buf, "table", gohouses)) {
+		assert.Equal(t, "ID  ADDRESS  OWNER  PRICE  TIMESTAMP\n"+
+			"1   seoul    Alice  3000   2018-01-01T12:34:56Z\n", buf.String())
+	}
+	buf.Reset()
+	if assert.NoError(t, printHouses(&buf, "csv", gohouses)) {
+		assert.Equal(t, "Id,Address,OwnerId,Price,Timestamp\n1,seoul,Alice,3000,2018-01-01T12:34:56Z\n", buf.String())
+	}
+	buf.Reset()
+	if assert.NoError(t, printHouses(&buf, "json", gohouses)) {
+		assert.JSONEq(t, `[{"Id":"1","Address":"seoul","OwnerId":"Alice","Price":"3000",
+			"Timestamp":"2018-01-01T12:34:56Z"}]`, buf.String())
+	}
+
+	buf.Reset()
+	goedits := []*cc.HouseEdit{{Kind: "update", EditorId: "Alice", TxId: "tx1",
+		Changes: []cc.FieldChange{{Field: "Price", Old: "3000", New: "3500"}}}}
+	if assert.NoError(t, printEdits(&buf, "csv", goedits)) {
+		assert.Contains(t, buf.String(), "update,Alice,,,Price: 3000 -> 3500,tx1")
+	}
+}
@@ -0,0 +1,223 @@
+// Command hcctl operates the house registry and imports and exports its data
+// off-chain.
+//
+// The owner, house and history commands build the chaincode arguments from
+// flags and print the results as a table, JSON or CSV. They run against a
+// local ledger state file on a memstub.Stub, or against an hcgateway given by
+// -gateway or $HCCTL_GATEWAY. dump exports every Owner and House record with
+// the Merkle root the ledger reports over them; verify checks such an export
+// against its root and, with -live, against the ledger.
+//
+// Owners and Houses are read from CSV (.csv) or JSON-lines files, rehearsed
+// against the chaincode on a shim.MockStub, and turned into batched invoke
+// payloads and/or a snapshot file. Snapshots and ListOwners/ListHouses
+// results can be exported to CSV.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"housecontract/cc"
+	"os"
+)
+
+const usage = `usage:
+  hcctl owner add -id id [-type person|corporate] [-org mspid]
+  hcctl owner list
+  hcctl house add -id id -owner id [-address a] [-price p] [-timestamp t]
+  hcctl house get -id id
+  hcctl house update -id id [-address a] [-price p]
+  hcctl house list [-owner id]
+  hcctl house transfer -id id -to id [-seller-broker id] [-buyer-broker id]
+  hcctl history -id id
+  hcctl dump [-out file]
+  hcctl verify [-in file] [-live]
+      common flags: [-state file | -gateway url] [-as invoker] [-o table|json|csv]
+  hcctl import [-owners file] [-houses file] [-base snapshot.json]
+               [-batch n] [-payloads file] [-snapshot file]
+  hcctl export [-snapshot file | -owners-json file -houses-json file]
+               [-owners file.csv] [-houses file.csv]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "owner":
+		err = runOwner(os.Args[2:])
+	case "house":
+		err = runHouse(os.Args[2:])
+	case "history":
+		err = runHistory(os.Args[2:])
+	case "dump":
+		err = runDump(os.Args[2:])
+	case "verify":
+		err = runVerify(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hcctl: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	ownersPath := flags.String("owners", "", "Owners to import (.csv or JSON lines)")
+	housesPath := flags.String("houses", "", "Houses to import (.csv or JSON lines)")
+	basePath := flags.String("base", "", "snapshot of the existing registry to validate against")
+	batchSize := flags.Int("batch", 500, "number of items per batch invocation")
+	payloadsPath := flags.String("payloads", "", "write the batch invocations to this file")
+	snapshotPath := flags.String("snapshot", "", "write the resulting registry to this file")
+	flags.Parse(args)
+
+	if *batchSize < 1 {
+		return fmt.Errorf("batch size must be positive: %d", *batchSize)
+	}
+
+	goowners := []*cc.Owner{}
+	gohouses := []*cc.House{}
+	var err error
+	if *ownersPath != "" {
+		goowners, err = readOwners(*ownersPath)
+		if err != nil {
+			return fmt.Errorf("%s: %s", *ownersPath, err)
+		}
+	}
+	if *housesPath != "" {
+		gohouses, err = readHouses(*housesPath)
+		if err != nil {
+			return fmt.Errorf("%s: %s", *housesPath, err)
+		}
+	}
+
+	r, err := newRehearsal()
+	if err != nil {
+		return err
+	}
+
+	if *basePath != "" {
+		base := new(Snapshot)
+		if err := readJSONFile(*basePath, base); err != nil {
+			return fmt.Errorf("%s: %s", *basePath, err)
+		}
+		invocations, err := batchInvocations(base.Owners, base.Houses, *batchSize)
+		if err != nil {
+			return err
+		}
+		if err := r.run(invocations); err != nil {
+			return fmt.Errorf("loading %s: %s", *basePath, err)
+		}
+	}
+
+	invocations, err := batchInvocations(goowners, gohouses, *batchSize)
+	if err != nil {
+		return err
+	}
+	if err := r.run(invocations); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d Owners and %d Houses validated in %d invocations\n",
+		len(goowners), len(gohouses), len(invocations))
+
+	if *payloadsPath != "" {
+		if err := writeInvocations(*payloadsPath, invocations); err != nil {
+			return err
+		}
+	}
+
+	if *snapshotPath != "" {
+		snapshot, err := r.snapshot()
+		if err != nil {
+			return err
+		}
+		if err := writeJSONFile(*snapshotPath, snapshot); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeInvocations writes one `peer chaincode invoke -c` argument per line
+func writeInvocations(path string, invocations []*Invocation) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, invocation := range invocations {
+		jsoninvocation, err := json.Marshal(invocation)
+		if err != nil {
+			return err
+		}
+		writer.Write(jsoninvocation)
+		writer.WriteString("\n")
+	}
+	return writer.Flush()
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	snapshotPath := flags.String("snapshot", "", "snapshot file written by import")
+	ownersJSONPath := flags.String("owners-json", "", "ListOwners result")
+	housesJSONPath := flags.String("houses-json", "", "ListHouses result")
+	ownersPath := flags.String("owners", "", "write the Owners to this CSV file")
+	housesPath := flags.String("houses", "", "write the Houses to this CSV file")
+	flags.Parse(args)
+
+	snapshot := &Snapshot{Owners: []*cc.Owner{}, Houses: []*cc.House{}}
+	if *snapshotPath != "" {
+		if err := readJSONFile(*snapshotPath, snapshot); err != nil {
+			return fmt.Errorf("%s: %s", *snapshotPath, err)
+		}
+	}
+	if *ownersJSONPath != "" {
+		if err := readJSONFile(*ownersJSONPath, &snapshot.Owners); err != nil {
+			return fmt.Errorf("%s: %s", *ownersJSONPath, err)
+		}
+	}
+	if *housesJSONPath != "" {
+		if err := readJSONFile(*housesJSONPath, &snapshot.Houses); err != nil {
+			return fmt.Errorf("%s: %s", *housesJSONPath, err)
+		}
+	}
+
+	if *ownersPath != "" {
+		file, err := os.Create(*ownersPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := writeOwnersCSV(file, snapshot.Owners); err != nil {
+			return err
+		}
+	}
+	if *housesPath != "" {
+		file, err := os.Create(*housesPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := writeHousesCSV(file, snapshot.Houses); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
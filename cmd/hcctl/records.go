@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"housecontract/cc"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ownerColumns = []string{"Id", "Org"}
+var houseColumns = []string{"Id", "Address", "OwnerId", "Price", "Timestamp"}
+
+// Snapshot is the whole registry as returned by ListOwners and ListHouses
+type Snapshot struct {
+	Owners []*cc.Owner
+	Houses []*cc.House
+}
+
+// isCSV tells CSV files from JSON-lines files by their extension
+func isCSV(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// readCSV returns the rows of a CSV file as maps keyed by the header columns
+func readCSV(r io.Reader, columns []string) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range columns {
+		found := false
+		for _, h := range header {
+			if h == column {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("missing column %s", column)
+		}
+	}
+
+	rows := []map[string]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := map[string]string{}
+		for i, h := range header {
+			row[h] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readJSONLines decodes one JSON document per non-empty line
+func readJSONLines(r io.Reader, newItem func() interface{}) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(text), newItem()); err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func readOwners(path string) ([]*cc.Owner, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	goowners := []*cc.Owner{}
+	if !isCSV(path) {
+		err = readJSONLines(file, func() interface{} {
+			goowner := new(cc.Owner)
+			goowners = append(goowners, goowner)
+			return goowner
+		})
+		return goowners, err
+	}
+
+	rows, err := readCSV(file, ownerColumns)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		goowners = append(goowners, &cc.Owner{Id: row["Id"], Type: row["Type"], Org: row["Org"]})
+	}
+	return goowners, nil
+}
+
+func readHouses(path string) ([]*cc.House, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gohouses := []*cc.House{}
+	if !isCSV(path) {
+		err = readJSONLines(file, func() interface{} {
+			gohouse := new(cc.House)
+			gohouses = append(gohouses, gohouse)
+			return gohouse
+		})
+		return gohouses, err
+	}
+
+	rows, err := readCSV(file, houseColumns)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		gohouse := &cc.House{
+			Id:      row["Id"],
+			Address: row["Address"],
+			OwnerId: row["OwnerId"],
+			Price:   row["Price"],
+		}
+		if row["Timestamp"] != "" {
+			gohouse.Timestamp, err = time.Parse(time.RFC3339, row["Timestamp"])
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %s", i+2, err)
+			}
+		}
+		gohouses = append(gohouses, gohouse)
+	}
+	return gohouses, nil
+}
+
+func writeOwnersCSV(w io.Writer, goowners []*cc.Owner) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Id", "Type", "Org"})
+	for _, goowner := range goowners {
+		writer.Write([]string{goowner.Id, goowner.Type, goowner.Org})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeHousesCSV(w io.Writer, gohouses []*cc.House) error {
+	writer := csv.NewWriter(w)
+	writer.Write(houseColumns)
+	for _, gohouse := range gohouses {
+		writer.Write([]string{
+			gohouse.Id,
+			gohouse.Address,
+			gohouse.OwnerId,
+			gohouse.Price,
+			gohouse.Timestamp.Format(time.RFC3339),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func readJSONFile(path string, v interface{}) error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, v)
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	bytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(bytes, '\n'), 0644)
+}
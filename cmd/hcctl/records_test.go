@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"housecontract/cc"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const housesCSV = `Id,Address,OwnerId,Price,Timestamp
+1,seoul,Alice,3000,2018-01-01T12:34:56Z
+2,bucheon,Alice,2000,2018-01-01T12:34:56Z
+`
+
+// OK1: CSV round trip
+func TestHousesCSV_OK1(t *testing.T) {
+	rows, err := readCSV(strings.NewReader(housesCSV), houseColumns)
+	if assert.NoError(t, err) && assert.Len(t, rows, 2) {
+		assert.Equal(t, "bucheon", rows[1]["Address"])
+	}
+
+	timestamp := time.Date(2018, 1, 1, 12, 34, 56, 0, time.UTC)
+	gohouses := []*cc.House{
+		{Id: "1", Address: "seoul", OwnerId: "Alice", Price: "3000", Timestamp: timestamp},
+		{Id: "2", Address: "bucheon", OwnerId: "Alice", Price: "2000", Timestamp: timestamp},
+	}
+	var buf bytes.Buffer
+	if assert.NoError(t, writeHousesCSV(&buf, gohouses)) {
+		assert.Equal(t, housesCSV, buf.String())
+	}
+}
+
+// NG1: missing column
+func TestHousesCSV_NG1(t *testing.T) {
+	_, err := readCSV(strings.NewReader("Id,Address\n1,seoul\n"), houseColumns)
+	assert.Error(t, err)
+}
+
+// OK1: rehearsal registers Owners before their Houses
+func TestRehearsal_OK1(t *testing.T) {
+	goowners := []*cc.Owner{{Id: "Alice", Org: "Org1MSP"}, {Id: "Bob", Org: "Org1MSP"}}
+	gohouses := []*cc.House{
+		{Id: "1", OwnerId: "Alice", Price: "3000"},
+		{Id: "2", OwnerId: "Bob", Price: "2000"},
+		{Id: "3", OwnerId: "Bob", Price: "1000"},
+	}
+	invocations, err := batchInvocations(goowners, gohouses, 2)
+	if assert.NoError(t, err) && assert.Len(t, invocations, 3) {
+		r, err := newRehearsal()
+		if assert.NoError(t, err) && assert.NoError(t, r.run(invocations)) {
+			snapshot, err := r.snapshot()
+			if assert.NoError(t, err) {
+				assert.Len(t, snapshot.Owners, 2)
+				assert.Len(t, snapshot.Houses, 3)
+			}
+		}
+	}
+}
+
+// NG1: rehearsal fails for a House of an unknown Owner
+func TestRehearsal_NG1(t *testing.T) {
+	invocations, err := batchInvocations(nil, []*cc.House{{Id: "1", OwnerId: "Carol"}}, 10)
+	if assert.NoError(t, err) {
+		r, err := newRehearsal()
+		if assert.NoError(t, err) {
+			assert.Error(t, r.run(invocations))
+		}
+	}
+}
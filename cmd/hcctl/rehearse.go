@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"housecontract/cc"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Invocation is one chaincode call in the form accepted by
+// `peer chaincode invoke -c`
+type Invocation struct {
+	Args []string
+}
+
+// batchInvocations splits the Owners and Houses into batch calls. All Owners
+// are registered before any House, so every House finds its Owner committed.
+func batchInvocations(goowners []*cc.Owner, gohouses []*cc.House,
+	size int) ([]*Invocation, error) {
+	invocations := []*Invocation{}
+
+	for start := 0; start < len(goowners); start += size {
+		end := start + size
+		if end > len(goowners) {
+			end = len(goowners)
+		}
+		jsonowners, err := json.Marshal(goowners[start:end])
+		if err != nil {
+			return nil, err
+		}
+		invocations = append(invocations,
+			&Invocation{[]string{"AddOwnersBatch", string(jsonowners)}})
+	}
+
+	for start := 0; start < len(gohouses); start += size {
+		end := start + size
+		if end > len(gohouses) {
+			end = len(gohouses)
+		}
+		jsonhouses, err := json.Marshal(gohouses[start:end])
+		if err != nil {
+			return nil, err
+		}
+		invocations = append(invocations,
+			&Invocation{[]string{"AddHousesBatch", string(jsonhouses)}})
+	}
+
+	return invocations, nil
+}
+
+// rehearsal runs invocations against the chaincode on a shim.MockStub
+type rehearsal struct {
+	stub *shim.MockStub
+}
+
+func newRehearsal() (*rehearsal, error) {
+	stub := shim.NewMockStub("housecontract", new(cc.HouseContractCC))
+	res := stub.MockInit(util.GenerateUUID(), nil)
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return nil, fmt.Errorf("Init failed: %s", res.Message)
+	}
+	return &rehearsal{stub}, nil
+}
+
+func (r *rehearsal) invoke(invocation *Invocation) ([]byte, error) {
+	args := make([][]byte, 0, len(invocation.Args))
+	for _, arg := range invocation.Args {
+		args = append(args, []byte(arg))
+	}
+
+	res := r.stub.MockInvoke(util.GenerateUUID(), args)
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return nil, fmt.Errorf("%s failed: %s", invocation.Args[0], res.Message)
+	}
+	return res.Payload, nil
+}
+
+// run invokes all invocations in order and stops at the first failure
+func (r *rehearsal) run(invocations []*Invocation) error {
+	for i, invocation := range invocations {
+		if _, err := r.invoke(invocation); err != nil {
+			return fmt.Errorf("invocation %d: %s", i+1, err)
+		}
+	}
+	return nil
+}
+
+// snapshot lists all Owners and Houses of the rehearsal ledger
+func (r *rehearsal) snapshot() (*Snapshot, error) {
+	snapshot := new(Snapshot)
+
+	payload, err := r.invoke(&Invocation{[]string{"ListOwners"}})
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(payload, &snapshot.Owners); err != nil {
+		return nil, err
+	}
+
+	payload, err = r.invoke(&Invocation{[]string{"ListHouses"}})
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(payload, &snapshot.Houses); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"housecontract/cc"
+	"housecontract/memstub"
+	"housecontract/scenario"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// target runs chaincode functions for the client commands. Arguments follow
+// the calling convention of Invoke: plain strings and JSON records.
+type target interface {
+	invoke(function string, args ...string) ([]byte, error)
+}
+
+// stateFile is the world state a stateTarget keeps between runs
+type stateFile struct {
+	State       map[string]string
+	Endorsement map[string][]byte `json:",omitempty"`
+}
+
+// stateTarget runs the chaincode on a memstub.Stub loaded from a state file
+// and writes the state back after every successful transaction
+type stateTarget struct {
+	path string
+	stub *memstub.Stub
+}
+
+// newStateTarget loads the state file at path. A missing file starts an
+// empty ledger initialised with config.
+func newStateTarget(path string, config string, invoker string, mspid string) (*stateTarget, error) {
+	t := &stateTarget{path, memstub.New("housecontract", new(cc.HouseContractCC))}
+	if invoker != "" {
+		creator, err := scenario.Creator(invoker, mspid)
+		if err != nil {
+			return nil, err
+		}
+		t.stub.Creator = creator
+	}
+
+	state := new(stateFile)
+	err := readJSONFile(path, state)
+	if os.IsNotExist(err) {
+		var args [][]byte
+		if config != "" {
+			args = [][]byte{[]byte("init"), []byte(config)}
+		}
+		res := t.stub.MockInit(util.GenerateUUID(), args)
+		if res.Status >= shim.ERRORTHRESHOLD {
+			return nil, fmt.Errorf("Init failed: %s", res.Message)
+		}
+		return t, t.save()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	for key, value := range state.State {
+		t.stub.State[key] = []byte(value)
+	}
+	for key, ep := range state.Endorsement {
+		t.stub.Endorsement[key] = ep
+	}
+	return t, nil
+}
+
+func (t *stateTarget) save() error {
+	state := &stateFile{State: map[string]string{}, Endorsement: t.stub.Endorsement}
+	for key, value := range t.stub.State {
+		state.State[key] = string(value)
+	}
+	return writeJSONFile(t.path, state)
+}
+
+func (t *stateTarget) invoke(function string, args ...string) ([]byte, error) {
+	ccargs := [][]byte{[]byte(function)}
+	for _, arg := range args {
+		ccargs = append(ccargs, []byte(arg))
+	}
+	res := t.stub.MockInvoke(util.GenerateUUID(), ccargs)
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return nil, fmt.Errorf("%s failed: %s", function, res.Message)
+	}
+	return res.Payload, t.save()
+}
+
+// gatewayTarget calls the resources of an hcgateway
+type gatewayTarget struct {
+	base    string
+	invoker string
+	client  *http.Client
+}
+
+func newGatewayTarget(base string, invoker string) *gatewayTarget {
+	return &gatewayTarget{strings.TrimRight(base, "/"), invoker, http.DefaultClient}
+}
+
+// route maps a function and its arguments to a gateway request
+func route(function string, args []string) (method string, path string, body []byte, err error) {
+	id := ""
+	switch function {
+	case "GetOwner", "ListOwnerIdHouses", "GetHouse", "ListHouseEdits", "TransferHouse":
+		if len(args) < 1 {
+			return "", "", nil, fmt.Errorf("%s needs an Id", function)
+		}
+		id = url.PathEscape(args[0])
+	}
+
+	switch function {
+	case "AddOwner":
+		return http.MethodPost, "/owners", []byte(args[0]), nil
+	case "ListOwners":
+		return http.MethodGet, "/owners", nil, nil
+	case "GetOwner":
+		return http.MethodGet, "/owners/" + id, nil, nil
+	case "ListOwnerIdHouses":
+		return http.MethodGet, "/owners/" + id + "/houses", nil, nil
+	case "AddHouse":
+		return http.MethodPost, "/houses", []byte(args[0]), nil
+	case "ListHouses":
+		return http.MethodGet, "/houses", nil, nil
+	case "GetHouse":
+		return http.MethodGet, "/houses/" + id, nil, nil
+	case "ListHouseEdits":
+		return http.MethodGet, "/houses/" + id + "/edits", nil, nil
+	case "GetRegistryDigest":
+		return http.MethodGet, "/registry/digest", nil, nil
+	case "ExportRegistry":
+		return http.MethodGet, "/registry/export", nil, nil
+	case "UpdateHouse":
+		gohouse := new(cc.House)
+		if err := json.Unmarshal([]byte(args[0]), gohouse); err != nil {
+			return "", "", nil, err
+		}
+		return http.MethodPut, "/houses/" + url.PathEscape(gohouse.Id), []byte(args[0]), nil
+	case "TransferHouse":
+		if len(args) < 2 {
+			return "", "", nil, fmt.Errorf("%s needs a new Owner", function)
+		}
+		req := map[string]string{"NewOwnerId": args[1]}
+		if len(args) > 2 {
+			brokers := new(cc.SaleBrokers)
+			if err := json.Unmarshal([]byte(args[2]), brokers); err != nil {
+				return "", "", nil, err
+			}
+			req["SellerBrokerId"] = brokers.SellerBrokerId
+			req["BuyerBrokerId"] = brokers.BuyerBrokerId
+		}
+		body, err = json.Marshal(req)
+		return http.MethodPost, "/houses/" + id + "/transfer", body, err
+	}
+	return "", "", nil, fmt.Errorf("%s is not served by the gateway", function)
+}
+
+func (t *gatewayTarget) invoke(function string, args ...string) ([]byte, error) {
+	method, path, body, err := route(function, args)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, t.base+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.invoker != "" {
+		req.Header.Set("X-Invoker", t.invoker)
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	payload, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		var failure struct{ Error string }
+		if json.Unmarshal(payload, &failure) == nil && failure.Error != "" {
+			return nil, fmt.Errorf("%s failed: %s", function, failure.Error)
+		}
+		return nil, fmt.Errorf("%s failed: %s", function, res.Status)
+	}
+	return payload, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"housecontract/cc"
+	"housecontract/scenario"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Backend runs the chaincode functions behind the gateway. Evaluate only
+// reads; Submit commits its writes.
+type Backend interface {
+	Evaluate(invoker string, function string, args ...string) ([]byte, error)
+	Submit(invoker string, function string, args ...string) ([]byte, error)
+}
+
+// ChaincodeError is a failure reported by the chaincode itself
+type ChaincodeError struct {
+	Status  int32
+	Message string
+}
+
+func (e *ChaincodeError) Error() string {
+	return e.Message
+}
+
+// invokerCC runs HouseContractCC with the creator of the current request,
+// which shim.MockStub does not let us set
+type invokerCC struct {
+	cc.HouseContractCC
+	creator []byte
+}
+
+type invokerStub struct {
+	shim.ChaincodeStubInterface
+	creator []byte
+}
+
+func (s *invokerStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (t *invokerCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.HouseContractCC.Init(&invokerStub{stub, t.creator})
+}
+
+func (t *invokerCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.HouseContractCC.Invoke(&invokerStub{stub, t.creator})
+}
+
+// mockBackend runs the chaincode in process on a shim.MockStub. Invokers
+// get a certificate of mspid made up on first use.
+type mockBackend struct {
+	mu       sync.Mutex
+	stub     *shim.MockStub
+	icc      *invokerCC
+	mspid    string
+	creators map[string][]byte
+}
+
+func newMockBackend(config string, mspid string) (*mockBackend, error) {
+	icc := new(invokerCC)
+	b := &mockBackend{
+		stub:     shim.NewMockStub("housecontract", icc),
+		icc:      icc,
+		mspid:    mspid,
+		creators: map[string][]byte{},
+	}
+
+	var args [][]byte
+	if config != "" {
+		args = [][]byte{[]byte("init"), []byte(config)}
+	}
+	res := b.stub.MockInit(util.GenerateUUID(), args)
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return nil, fmt.Errorf("Init failed: %s", res.Message)
+	}
+	return b, nil
+}
+
+func (b *mockBackend) creator(invoker string) ([]byte, error) {
+	if invoker == "" {
+		return nil, nil
+	}
+	if creator, ok := b.creators[invoker]; ok {
+		return creator, nil
+	}
+	creator, err := scenario.Creator(invoker, b.mspid)
+	if err != nil {
+		return nil, err
+	}
+	b.creators[invoker] = creator
+	return creator, nil
+}
+
+func (b *mockBackend) Submit(invoker string, function string, args ...string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	creator, err := b.creator(invoker)
+	if err != nil {
+		return nil, err
+	}
+	b.icc.creator = creator
+
+	ccargs := [][]byte{[]byte(function)}
+	for _, arg := range args {
+		ccargs = append(ccargs, []byte(arg))
+	}
+	res := b.stub.MockInvoke(util.GenerateUUID(), ccargs)
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return nil, &ChaincodeError{res.Status, res.Message}
+	}
+	return res.Payload, nil
+}
+
+func (b *mockBackend) Evaluate(invoker string, function string, args ...string) ([]byte, error) {
+	return b.Submit(invoker, function, args...)
+}
+
+// peerBackend calls a live network through the `peer` CLI, as the identity
+// configured in its environment (CORE_PEER_MSPCONFIGPATH etc.). That single
+// identity is the only invoker it serves.
+type peerBackend struct {
+	bin       string
+	channel   string
+	chaincode string
+	extra     []string //further flags of `peer chaincode`, e.g. the orderer and TLS
+	identity  string   //enrollment ID of the peer CLI identity
+}
+
+// checkInvoker fails unless invoker is the identity the peer CLI acts as, so
+// that no request runs as an identity it did not name
+func (b *peerBackend) checkInvoker(invoker string) error {
+	if invoker != b.identity {
+		return &ChaincodeError{shim.ERROR, fmt.Sprintf(
+			"%q may not invoke through this gateway, which acts as %s only", invoker, b.identity)}
+	}
+	return nil
+}
+
+func (b *peerBackend) run(command string, function string, args []string, extra []string) ([]byte, error) {
+	ctor, err := json.Marshal(struct{ Args []string }{append([]string{function}, args...)})
+	if err != nil {
+		return nil, err
+	}
+	cmdArgs := append([]string{"chaincode", command, "-C", b.channel, "-n", b.chaincode,
+		"-c", string(ctor)}, extra...)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(b.bin, cmdArgs...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, &ChaincodeError{shim.ERROR, peerErrorMessage(stderr.String())}
+		}
+		return nil, err
+	}
+	return bytes.TrimSpace(stdout.Bytes()), nil
+}
+
+// peerErrorMessage extracts the chaincode message from the output of a
+// failed `peer` command, such as
+// `Error: endorsement failure during query. response: status:500 message:"..."`
+func peerErrorMessage(output string) string {
+	output = strings.TrimSpace(output)
+	if i := strings.LastIndex(output, "message:"); i >= 0 {
+		message := strings.TrimSpace(output[i+len("message:"):])
+		if unquoted, err := strconv.Unquote(message); err == nil {
+			return unquoted
+		}
+		return message
+	}
+	if i := strings.LastIndex(output, "Error: "); i >= 0 {
+		return strings.TrimSpace(output[i+len("Error: "):])
+	}
+	return output
+}
+
+func (b *peerBackend) Evaluate(invoker string, function string, args ...string) ([]byte, error) {
+	if err := b.checkInvoker(invoker); err != nil {
+		return nil, err
+	}
+	return b.run("query", function, args, b.extra)
+}
+
+// Submit waits for the transaction to commit. The payload of an invoke is
+// not returned by the peer CLI in a usable form, so it is always empty.
+func (b *peerBackend) Submit(invoker string, function string, args ...string) ([]byte, error) {
+	if err := b.checkInvoker(invoker); err != nil {
+		return nil, err
+	}
+	_, err := b.run("invoke", function, args, append([]string{"--waitForEvent"}, b.extra...))
+	return nil, err
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"housecontract/cc"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// invokerHeader names the enrollment ID a request is made as. Only the mock
+// backend honors it; the peer backend always acts as its own identity.
+const invokerHeader = "X-Invoker"
+
+// maxBodySize bounds request bodies
+const maxBodySize = 1 << 20
+
+// TransferRequest is the body of POST /houses/{id}/transfer
+type TransferRequest struct {
+	NewOwnerId     string
+	SellerBrokerId string `json:",omitempty"`
+	BuyerBrokerId  string `json:",omitempty"`
+}
+
+// ErrorResponse is the body of every failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// gateway maps REST resources to the Invoke functions of HouseContractCC
+type gateway struct {
+	backend Backend
+}
+
+func newGateway(backend Backend) http.Handler {
+	return &gateway{backend}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writePayload passes a chaincode payload through; payloads are JSON already
+func writePayload(w http.ResponseWriter, status int, payload []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(payload)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, &ErrorResponse{message})
+}
+
+// errorStatus maps an error to an HTTP status. The chaincode reports all its
+// failures alike, so its message is all there is to go by.
+func errorStatus(err error) int {
+	ccerr, ok := err.(*ChaincodeError)
+	if !ok {
+		return http.StatusBadGateway
+	}
+	message := ccerr.Message
+	switch {
+	case strings.Contains(message, "not found"), strings.Contains(message, "does not exist"):
+		return http.StatusNotFound
+	case strings.Contains(message, "already exists"), strings.Contains(message, "alerady exists"),
+		strings.Contains(message, "is frozen"):
+		return http.StatusConflict
+	case strings.Contains(message, "may not"), strings.Contains(message, "is not a"),
+		strings.Contains(message, "is neither"), strings.Contains(message, "is disabled"):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func (g *gateway) fail(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+	if status == http.StatusBadGateway {
+		log.Printf("backend: %s", err)
+	}
+	writeError(w, status, err.Error())
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	invoker := r.Header.Get(invokerHeader)
+
+	// the methods allowed on the resource and what each one does
+	var routes map[string]func()
+	switch {
+	case len(parts) == 1 && parts[0] == "openapi.json":
+		routes = map[string]func(){
+			http.MethodGet: func() { writePayload(w, http.StatusOK, []byte(openapiSpec)) },
+		}
+	case len(parts) == 1 && parts[0] == "houses":
+		routes = map[string]func(){
+			http.MethodGet:  func() { g.evaluate(w, invoker, "ListHouses") },
+			http.MethodPost: func() { g.addHouse(w, r, invoker) },
+		}
+	case len(parts) == 2 && parts[0] == "houses":
+		routes = map[string]func(){
+			http.MethodGet: func() { g.evaluate(w, invoker, "GetHouse", parts[1]) },
+			http.MethodPut: func() { g.updateHouse(w, r, invoker, parts[1]) },
+		}
+	case len(parts) == 3 && parts[0] == "houses" && parts[2] == "edits":
+		routes = map[string]func(){
+			http.MethodGet: func() { g.evaluate(w, invoker, "ListHouseEdits", parts[1]) },
+		}
+	case len(parts) == 3 && parts[0] == "houses" && parts[2] == "transfer":
+		routes = map[string]func(){
+			http.MethodPost: func() { g.transferHouse(w, r, invoker, parts[1]) },
+		}
+	case len(parts) == 1 && parts[0] == "owners":
+		routes = map[string]func(){
+			http.MethodGet:  func() { g.evaluate(w, invoker, "ListOwners") },
+			http.MethodPost: func() { g.addOwner(w, r, invoker) },
+		}
+	case len(parts) == 2 && parts[0] == "owners":
+		routes = map[string]func(){
+			http.MethodGet: func() { g.evaluate(w, invoker, "GetOwner", parts[1]) },
+		}
+	case len(parts) == 3 && parts[0] == "owners" && parts[2] == "houses":
+		routes = map[string]func(){
+			http.MethodGet: func() { g.evaluate(w, invoker, "ListOwnerIdHouses", parts[1]) },
+		}
+	case len(parts) == 2 && parts[0] == "registry" && parts[1] == "digest":
+		routes = map[string]func(){
+			http.MethodGet: func() { g.evaluate(w, invoker, "GetRegistryDigest") },
+		}
+	case len(parts) == 2 && parts[0] == "registry" && parts[1] == "export":
+		routes = map[string]func(){
+			http.MethodGet: func() { g.evaluate(w, invoker, "ExportRegistry") },
+		}
+	default:
+		writeError(w, http.StatusNotFound, "no such resource: "+r.URL.Path)
+		return
+	}
+
+	serve, ok := routes[r.Method]
+	if !ok {
+		allowed := []string{}
+		for method := range routes {
+			allowed = append(allowed, method)
+		}
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(w, http.StatusMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+		return
+	}
+	serve()
+}
+
+func (g *gateway) evaluate(w http.ResponseWriter, invoker string, function string, args ...string) {
+	payload, err := g.backend.Evaluate(invoker, function, args...)
+	if err != nil {
+		g.fail(w, err)
+		return
+	}
+	writePayload(w, http.StatusOK, payload)
+}
+
+// readBody decodes the JSON request body into v
+func readBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err == nil {
+		err = json.Unmarshal(body, v)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// POST /owners registers an Owner and answers with it
+func (g *gateway) addOwner(w http.ResponseWriter, r *http.Request, invoker string) {
+	goowner := new(cc.Owner)
+	if !readBody(w, r, goowner) {
+		return
+	}
+	jsonowner, err := json.Marshal(goowner)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := g.backend.Submit(invoker, "AddOwner", string(jsonowner)); err != nil {
+		g.fail(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/owners/"+goowner.Id)
+	payload, err := g.backend.Evaluate(invoker, "GetOwner", goowner.Id)
+	if err != nil {
+		g.fail(w, err)
+		return
+	}
+	writePayload(w, http.StatusCreated, payload)
+}
+
+// POST /houses registers a House and answers with it
+func (g *gateway) addHouse(w http.ResponseWriter, r *http.Request, invoker string) {
+	gohouse := new(cc.House)
+	if !readBody(w, r, gohouse) {
+		return
+	}
+	jsonhouse, err := json.Marshal(gohouse)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := g.backend.Submit(invoker, "AddHouse", string(jsonhouse)); err != nil {
+		g.fail(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/houses/"+gohouse.Id)
+	payload, err := g.backend.Evaluate(invoker, "GetHouse", gohouse.Id)
+	if err != nil {
+		g.fail(w, err)
+		return
+	}
+	writePayload(w, http.StatusCreated, payload)
+}
+
+// POST /houses/{id}/transfer transfers a House and answers with it
+func (g *gateway) transferHouse(w http.ResponseWriter, r *http.Request, invoker string, houseId string) {
+	req := new(TransferRequest)
+	if !readBody(w, r, req) {
+		return
+	}
+	if req.NewOwnerId == "" {
+		writeError(w, http.StatusBadRequest, "NewOwnerId is required")
+		return
+	}
+
+	args := []string{houseId, req.NewOwnerId}
+	if req.SellerBrokerId != "" || req.BuyerBrokerId != "" {
+		jsonbrokers, err := json.Marshal(&cc.SaleBrokers{
+			SellerBrokerId: req.SellerBrokerId,
+			BuyerBrokerId:  req.BuyerBrokerId,
+		})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		args = append(args, string(jsonbrokers))
+	}
+
+	if _, err := g.backend.Submit(invoker, "TransferHouse", args...); err != nil {
+		g.fail(w, err)
+		return
+	}
+	g.evaluate(w, invoker, "GetHouse", houseId)
+}
+
+// PUT /houses/{id} updates a House and answers with it
+func (g *gateway) updateHouse(w http.ResponseWriter, r *http.Request, invoker string, houseId string) {
+	gohouse := new(cc.House)
+	if !readBody(w, r, gohouse) {
+		return
+	}
+	if gohouse.Id == "" {
+		gohouse.Id = houseId
+	}
+	if gohouse.Id != houseId {
+		writeError(w, http.StatusBadRequest, "the Id of the House does not match the path")
+		return
+	}
+	jsonhouse, err := json.Marshal(gohouse)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := g.backend.Submit(invoker, "UpdateHouse", string(jsonhouse)); err != nil {
+		g.fail(w, err)
+		return
+	}
+	g.evaluate(w, invoker, "GetHouse", houseId)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	registry = `{"Owners":[{"Id":"Alice","Org":"Org1MSP"},{"Id":"Bob","Org":"Org1MSP"}],
+		"Houses":[{"Id":"1", "Address":"seoul", "OwnerId":"Alice","Price":"3000", "Timestamp":"2018-01-01T12:34:56Z"}]}`
+	house2 = `{"Id":"2", "Address":"busan", "OwnerId":"Alice","Price":"2000", "Timestamp":"2018-01-01T12:34:56Z"}`
+)
+
+// newTestGateway returns a gateway on a mock backend holding registry
+func newTestGateway(t *testing.T) http.Handler {
+	backend, err := newMockBackend("", "Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := ioutil.WriteFile(path, []byte(registry), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadSnapshot(backend, path); err != nil {
+		t.Fatal(err)
+	}
+	return newGateway(backend)
+}
+
+func request(g http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	return requestAs(g, "", method, path, body)
+}
+
+// requestAs makes a request as the enrollment ID invoker
+func requestAs(g http.Handler, invoker string, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if invoker != "" {
+		req.Header.Set(invokerHeader, invoker)
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	return w
+}
+
+// ids lists the Ids of the Owners or Houses in a response
+func ids(t *testing.T, w *httptest.ResponseRecorder) []string {
+	items := []struct{ Id string }{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	list := []string{}
+	for _, item := range items {
+		list = append(list, item.Id)
+	}
+	return list
+}
+
+// OK1: add, list, get and transfer Houses
+func TestGateway_OK1(t *testing.T) {
+	g := newTestGateway(t)
+
+	w := request(g, http.MethodPost, "/houses", house2)
+	if assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+		assert.Equal(t, "/houses/2", w.Header().Get("Location"))
+		assert.JSONEq(t, house2, w.Body.String())
+	}
+
+	w = request(g, http.MethodGet, "/houses", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		assert.Equal(t, []string{"1", "2"}, ids(t, w))
+	}
+
+	w = requestAs(g, "Bob", http.MethodPost, "/houses/2/transfer", `{"NewOwnerId":"Bob"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	w = requestAs(g, "Alice", http.MethodPost, "/houses/2/transfer", `{"NewOwnerId":"Bob"}`)
+	if assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) {
+		assert.JSONEq(t, strings.Replace(house2, `"Alice"`, `"Bob"`, 1), w.Body.String())
+	}
+
+	w = request(g, http.MethodGet, "/owners/Bob/houses", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		assert.Equal(t, []string{"2"}, ids(t, w))
+	}
+	w = request(g, http.MethodGet, "/houses/1", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		assert.Contains(t, w.Body.String(), `"OwnerId":"Alice"`)
+	}
+
+	w = request(g, http.MethodGet, "/openapi.json", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		var spec map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	}
+}
+
+// NG1: failures map to HTTP statuses
+func TestGateway_NG1(t *testing.T) {
+	g := newTestGateway(t)
+
+	for _, c := range []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/houses/9", "", http.StatusNotFound},
+		{http.MethodGet, "/apartments", "", http.StatusNotFound},
+		{http.MethodPost, "/houses", `{"Id":"1", "OwnerId":"Alice", "Price":"3000"}`, http.StatusConflict},
+		{http.MethodPost, "/houses", `{"Id":"3", "OwnerId":"Alice", "Price":"3,000"}`, http.StatusBadRequest},
+		{http.MethodPost, "/houses", `{"Id":`, http.StatusBadRequest},
+		{http.MethodPost, "/houses/1/transfer", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "/houses/1/transfer", `{"NewOwnerId":"Carol"}`, http.StatusNotFound},
+		{http.MethodDelete, "/houses/1", "", http.StatusMethodNotAllowed},
+	} {
+		w := request(g, c.method, c.path, c.body)
+		assert.Equal(t, c.status, w.Code, c.method+" "+c.path)
+		var res ErrorResponse
+		if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res)) {
+			assert.NotEmpty(t, res.Error)
+		}
+	}
+
+	w := request(g, http.MethodPut, "/houses", "")
+	assert.Equal(t, "GET, POST", w.Header().Get("Allow"))
+}
+
+// OK1: chaincode messages map to HTTP statuses
+func TestErrorStatus_OK1(t *testing.T) {
+	for message, status := range map[string]int{
+		"House with Id = 1 was not found":         http.StatusNotFound,
+		"an Owner with Id = Alice alerady exists": http.StatusConflict,
+		"House 1 is frozen":                       http.StatusConflict,
+		"Bob may not update House 1":              http.StatusForbidden,
+		"invalid character":                       http.StatusBadRequest,
+	} {
+		assert.Equal(t, status, errorStatus(&ChaincodeError{500, message}), message)
+	}
+	assert.Equal(t, http.StatusBadGateway, errorStatus(errors.New("connection refused")))
+}
+
+// OK1: messages are extracted from the output of the peer CLI
+func TestPeerErrorMessage_OK1(t *testing.T) {
+	assert.Equal(t, `House with Id = "1" was not found`, peerErrorMessage(
+		`Error: endorsement failure during query. response: status:500 message:"House with Id = \"1\" was not found"`+"\n"))
+	assert.Equal(t, "could not connect", peerErrorMessage("Error: could not connect\n"))
+}
+
+// NG2: the peer backend serves no invoker but its own identity, and does
+// not run the peer CLI for any other
+func TestGateway_NG2(t *testing.T) {
+	g := newGateway(&peerBackend{bin: filepath.Join(t.TempDir(), "peer"), identity: "Alice"})
+
+	for _, invoker := range []string{"", "Bob"} {
+		w := requestAs(g, invoker, http.MethodPost, "/houses/1/transfer", `{"NewOwnerId":"Bob"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code, invoker)
+		w = requestAs(g, invoker, http.MethodGet, "/houses", "")
+		assert.Equal(t, http.StatusForbidden, w.Code, invoker)
+	}
+
+	// the peer CLI does not exist, so a request it would serve fails with it
+	w := requestAs(g, "Alice", http.MethodGet, "/houses", "")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+// OK2: register Owners, update a House as its Owner and list its edits
+func TestGateway_OK2(t *testing.T) {
+	g := newTestGateway(t)
+
+	w := request(g, http.MethodPost, "/owners", `{"Id":"Carol","Type":"corporate","Org":"Org1MSP"}`)
+	if assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+		assert.Equal(t, "/owners/Carol", w.Header().Get("Location"))
+		assert.Contains(t, w.Body.String(), `"Type":"corporate"`)
+	}
+	w = request(g, http.MethodGet, "/owners", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		assert.Equal(t, []string{"Alice", "Bob", "Carol"}, ids(t, w))
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/houses/1",
+		strings.NewReader(`{"Address":"seoul", "OwnerId":"Alice","Price":"3500", "Timestamp":"2018-01-01T12:34:56Z"}`))
+	req.Header.Set(invokerHeader, "Alice")
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	if assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) {
+		assert.Contains(t, w.Body.String(), `"Price":"3500"`)
+	}
+
+	w = request(g, http.MethodGet, "/houses/1/edits", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		assert.Contains(t, w.Body.String(), `"EditorId":"Alice"`)
+	}
+
+	w = request(g, http.MethodPut, "/houses/1", `{"Id":"2"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,121 @@
+// Command hcgateway serves the house contract as a REST/JSON API.
+//
+// Resources map to the Invoke functions of HouseContractCC:
+//
+//	GET  /houses                 ListHouses
+//	POST /houses                 AddHouse
+//	GET  /houses/{id}            GetHouse
+//	PUT  /houses/{id}            UpdateHouse
+//	POST /houses/{id}/transfer   TransferHouse
+//	GET  /houses/{id}/edits      ListHouseEdits
+//	GET  /owners                 ListOwners
+//	POST /owners                 AddOwner
+//	GET  /owners/{id}            GetOwner
+//	GET  /owners/{id}/houses     ListOwnerIdHouses
+//	GET  /registry/digest        GetRegistryDigest
+//	GET  /registry/export        ExportRegistry
+//	GET  /openapi.json           the OpenAPI spec
+//
+// With -backend mock the chaincode runs in process on a shim.MockStub, for
+// local development; -backend peer calls a live network through the `peer`
+// CLI. The X-Invoker header names the enrollment ID to invoke as. It is not
+// authenticated: the peer backend acts as the single identity of its peer
+// CLI, given with -identity, and refuses requests naming any other.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"housecontract/cc"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// snapshot is the registry a mock backend starts from, as written by
+// `hcctl import -snapshot`
+type snapshot struct {
+	Owners []*cc.Owner
+	Houses []*cc.House
+}
+
+// loadSnapshot registers the Owners and Houses of the snapshot file
+func loadSnapshot(backend Backend, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	s := new(snapshot)
+	if err := json.Unmarshal(data, s); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	// Owners first, so every House finds its Owner
+	jsonowners, err := json.Marshal(s.Owners)
+	if err != nil {
+		return err
+	}
+	if _, err := backend.Submit("", "AddOwnersBatch", string(jsonowners)); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	jsonhouses, err := json.Marshal(s.Houses)
+	if err != nil {
+		return err
+	}
+	if _, err := backend.Submit("", "AddHousesBatch", string(jsonhouses)); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	backendName := flag.String("backend", "mock", "mock or peer")
+	configPath := flag.String("config", "", "mock: Init configuration (JSON file)")
+	mspid := flag.String("mspid", "Org1MSP", "mock: MSP ID of the invokers")
+	snapshotPath := flag.String("snapshot", "", "mock: registry snapshot to start from")
+	peerBin := flag.String("peer", "peer", "peer: path of the peer CLI")
+	channel := flag.String("channel", "mychannel", "peer: channel name")
+	chaincode := flag.String("chaincode", "housecontract", "peer: chaincode name")
+	peerFlags := flag.String("peer-flags", "", "peer: further flags of `peer chaincode`, space separated")
+	identity := flag.String("identity", "", "peer: enrollment ID of the peer CLI identity, the only invoker served")
+	flag.Parse()
+
+	var backend Backend
+	switch *backendName {
+	case "mock":
+		var config []byte
+		if *configPath != "" {
+			var err error
+			config, err = ioutil.ReadFile(*configPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		mock, err := newMockBackend(string(config), *mspid)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *snapshotPath != "" {
+			if err := loadSnapshot(mock, *snapshotPath); err != nil {
+				log.Fatal(err)
+			}
+		}
+		backend = mock
+	case "peer":
+		if *identity == "" {
+			fmt.Fprintln(os.Stderr, "hcgateway: -identity is required with the peer backend")
+			os.Exit(2)
+		}
+		backend = &peerBackend{*peerBin, *channel, *chaincode, strings.Fields(*peerFlags), *identity}
+	default:
+		fmt.Fprintf(os.Stderr, "hcgateway: unknown backend %q\n", *backendName)
+		os.Exit(2)
+	}
+
+	log.Printf("hcgateway: %s backend listening on %s", *backendName, *addr)
+	log.Fatal(http.ListenAndServe(*addr, newGateway(backend)))
+}
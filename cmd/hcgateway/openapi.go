@@ -0,0 +1,303 @@
+package main
+
+// openapiSpec describes the gateway; it is served at GET /openapi.json
+const openapiSpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "House contract gateway",
+    "description": "REST/JSON access to the Invoke functions of HouseContractCC. X-Invoker is not authenticated: the live (peer) backend acts as a single fixed identity and refuses requests naming any other.",
+    "version": "1.0.0"
+  },
+  "paths": {
+    "/houses": {
+      "get": {
+        "summary": "List all Houses (ListHouses)",
+        "parameters": [{"$ref": "#/components/parameters/Invoker"}],
+        "responses": {
+          "200": {
+            "description": "the Houses",
+            "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/House"}}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Register a House of an existing Owner (AddHouse)",
+        "parameters": [{"$ref": "#/components/parameters/Invoker"}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/House"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "the registered House",
+            "headers": {"Location": {"schema": {"type": "string"}}},
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/House"}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/houses/{id}": {
+      "get": {
+        "summary": "Get a House (GetHouse)",
+        "parameters": [
+          {"$ref": "#/components/parameters/Invoker"},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "the House",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/House"}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "summary": "Update a House (UpdateHouse)",
+        "parameters": [
+          {"$ref": "#/components/parameters/Invoker"},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/House"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "the updated House",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/House"}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/houses/{id}/edits": {
+      "get": {
+        "summary": "List the edits of a House (ListHouseEdits)",
+        "parameters": [
+          {"$ref": "#/components/parameters/Invoker"},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "the edits, oldest first",
+            "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/HouseEdit"}}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/houses/{id}/transfer": {
+      "post": {
+        "summary": "Transfer a House to another Owner (TransferHouse)",
+        "parameters": [
+          {"$ref": "#/components/parameters/Invoker"},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TransferRequest"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "the transferred House",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/House"}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/owners": {
+      "get": {
+        "summary": "List all Owners (ListOwners)",
+        "parameters": [{"$ref": "#/components/parameters/Invoker"}],
+        "responses": {
+          "200": {
+            "description": "the Owners",
+            "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Owner"}}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Register an Owner (AddOwner)",
+        "parameters": [{"$ref": "#/components/parameters/Invoker"}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Owner"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "the registered Owner",
+            "headers": {"Location": {"schema": {"type": "string"}}},
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Owner"}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/owners/{id}": {
+      "get": {
+        "summary": "Get an Owner (GetOwner)",
+        "parameters": [
+          {"$ref": "#/components/parameters/Invoker"},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "the Owner",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Owner"}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/owners/{id}/houses": {
+      "get": {
+        "summary": "List the Houses of an Owner (ListOwnerIdHouses)",
+        "parameters": [
+          {"$ref": "#/components/parameters/Invoker"},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "the Houses of the Owner",
+            "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/House"}}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/registry/digest": {
+      "get": {
+        "summary": "Merkle root over all Owner and House records (GetRegistryDigest)",
+        "parameters": [{"$ref": "#/components/parameters/Invoker"}],
+        "responses": {
+          "200": {
+            "description": "the digest",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RegistryDigest"}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/registry/export": {
+      "get": {
+        "summary": "All Owner and House records with their digest (ExportRegistry)",
+        "parameters": [{"$ref": "#/components/parameters/Invoker"}],
+        "responses": {
+          "200": {
+            "description": "the records and their digest",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RegistryExport"}}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "Invoker": {
+        "name": "X-Invoker",
+        "in": "header",
+        "description": "enrollment ID to invoke as. The mock backend invokes as any Id; the live (peer) backend acts as its one configured identity and refuses any other Id with 403",
+        "schema": {"type": "string"}
+      }
+    },
+    "responses": {
+      "Error": {
+        "description": "404 unknown Owner or House, 409 conflict, 403 not permitted, 400 rejected otherwise, 502 backend failure",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      }
+    },
+    "schemas": {
+      "Owner": {
+        "type": "object",
+        "required": ["Id", "Org"],
+        "properties": {
+          "Id": {"type": "string"},
+          "Type": {"type": "string", "enum": ["person", "corporate"]},
+          "Org": {"type": "string"}
+        }
+      },
+      "House": {
+        "type": "object",
+        "required": ["Id", "OwnerId", "Price"],
+        "properties": {
+          "Id": {"type": "string"},
+          "Address": {"type": "string"},
+          "OwnerId": {"type": "string"},
+          "Price": {"type": "string", "pattern": "^[0-9]+$"},
+          "Timestamp": {"type": "string", "format": "date-time"},
+          "Shares": {"type": "array", "items": {"$ref": "#/components/schemas/Share"}}
+        }
+      },
+      "Share": {
+        "type": "object",
+        "properties": {
+          "OwnerId": {"type": "string"},
+          "Numerator": {"type": "integer", "format": "int64"},
+          "Denominator": {"type": "integer", "format": "int64"}
+        }
+      },
+      "HouseEdit": {
+        "type": "object",
+        "properties": {
+          "HouseId": {"type": "string"},
+          "Kind": {"type": "string"},
+          "EditorId": {"type": "string"},
+          "OnBehalfOf": {"type": "string"},
+          "Reason": {"type": "string"},
+          "Changes": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {"Field": {"type": "string"}, "Old": {"type": "string"}, "New": {"type": "string"}}
+            }
+          },
+          "TxId": {"type": "string"},
+          "Timestamp": {"type": "string", "format": "date-time"}
+        }
+      },
+      "RegistryDigest": {
+        "type": "object",
+        "properties": {
+          "Algorithm": {"type": "string"},
+          "Root": {"type": "string"},
+          "Owners": {"type": "integer"},
+          "Houses": {"type": "integer"},
+          "TxId": {"type": "string"},
+          "Timestamp": {"type": "string", "format": "date-time"}
+        }
+      },
+      "RegistryExport": {
+        "type": "object",
+        "properties": {
+          "Digest": {"$ref": "#/components/schemas/RegistryDigest"},
+          "Records": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {"Key": {"type": "string"}, "Value": {"type": "string"}}
+            }
+          }
+        }
+      },
+      "TransferRequest": {
+        "type": "object",
+        "required": ["NewOwnerId"],
+        "properties": {
+          "NewOwnerId": {"type": "string"},
+          "SellerBrokerId": {"type": "string"},
+          "BuyerBrokerId": {"type": "string"}
+        }
+      },
+      "Error": {
+        "type": "object",
+        "properties": {"error": {"type": "string"}}
+      }
+    }
+  }
+}
+`
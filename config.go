@@ -0,0 +1,252 @@
+package cc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const prefixConfig = "Config"
+
+// Config is the chaincode configuration set by Init and UpdateConfig
+type Config struct {
+	Admins                     []string          //bootstrap admin Ids
+	Registrars                 []string          //bootstrap registrar Ids
+	Orgs                       map[string]string //MSP ID of every bootstrap admin and registrar
+	Currencies                 []string          //allowed ISO 4217 currency codes
+	TransferExpiryHours        int               //how long a pending transfer stays valid
+	FeePercentages             map[string]float64
+	Features                   map[string]bool //Invoke functions switched on or off
+	RequiredTransferDocuments  []string        //document types TransferHouse requires
+	HighValueTransferThreshold int64           //transfers of Houses priced above need an Approval; 0 disables
+	RejectQuotedArgs           bool            //ends the deprecation window of JSON-quoted string arguments
+	ResponseEnvelope           bool            //wraps every Invoke response in an Envelope
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Admins:                    []string{},
+		Registrars:                []string{},
+		Orgs:                      map[string]string{},
+		Currencies:                []string{"KRW"},
+		TransferExpiryHours:       72,
+		FeePercentages:            map[string]float64{},
+		Features:                  map[string]bool{},
+		RequiredTransferDocuments: []string{},
+	}
+}
+
+// Enabled tells whether an Invoke function is switched on. Functions are on
+// unless switched off; the configuration functions cannot be switched off.
+func (c *Config) Enabled(function string) bool {
+	if function == "UpdateConfig" || function == "GetConfig" {
+		return true
+	}
+	enabled, found := c.Features[function]
+	return !found || enabled
+}
+
+// AllowsCurrency tells whether the currency code is allowed
+func (c *Config) AllowsCurrency(currency string) bool {
+	for _, allowed := range c.Currencies {
+		if allowed == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConfig decodes a JSON configuration; fields left out get their defaults
+func parseConfig(jsonconfig []byte) (*Config, error) {
+	goconfig := defaultConfig()
+	decoder := json.NewDecoder(bytes.NewReader(jsonconfig))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(goconfig); err != nil {
+		return nil, err
+	}
+	if err := validateConfig(goconfig); err != nil {
+		return nil, err
+	}
+	return goconfig, nil
+}
+
+func validateConfig(goconfig *Config) error {
+	for _, currency := range goconfig.Currencies {
+		if len(currency) != 3 {
+			return fmt.Errorf("invalid currency code: %q", currency)
+		}
+	}
+	if goconfig.HighValueTransferThreshold < 0 {
+		return fmt.Errorf("negative high value transfer threshold: %d", goconfig.HighValueTransferThreshold)
+	}
+	if goconfig.TransferExpiryHours <= 0 {
+		return fmt.Errorf("transfer expiry must be positive: %d hours", goconfig.TransferExpiryHours)
+	}
+	for _, id := range append(append([]string{}, goconfig.Admins...), goconfig.Registrars...) {
+		if goconfig.Orgs[id] == "" {
+			return fmt.Errorf("no organization given for %s", id)
+		}
+	}
+	for name, percentage := range goconfig.FeePercentages {
+		if percentage < 0 || percentage > 100 {
+			return fmt.Errorf("fee %s out of range: %g%%", name, percentage)
+		}
+	}
+	return nil
+}
+
+// getConfig loads the configuration, or the defaults if none is stored
+func getConfig(stub shim.ChaincodeStubInterface) (*Config, error) {
+	key, err := stub.CreateCompositeKey(prefixConfig, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return defaultConfig(), nil
+	}
+
+	goconfig := defaultConfig()
+	err = unmarshalDoc(prefixConfig, jsonBytes, goconfig)
+	if err != nil {
+		return nil, err
+	}
+	return goconfig, nil
+}
+
+func hasConfig(stub shim.ChaincodeStubInterface) (bool, error) {
+	key, err := stub.CreateCompositeKey(prefixConfig, []string{})
+	if err != nil {
+		return false, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return false, err
+	}
+
+	return jsonBytes != nil, nil
+}
+
+// putConfig stores the configuration and grants the bootstrap roles it lists
+func putConfig(stub shim.ChaincodeStubInterface, goconfig *Config) error {
+	err := putDoc(stub, prefixConfig, []string{}, goconfig)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range goconfig.Admins {
+		if err := putRole(stub, &Role{Id: id, Role: roleAdmin, Org: goconfig.Orgs[id]}); err != nil {
+			return err
+		}
+	}
+	for _, id := range goconfig.Registrars {
+		if err := putRole(stub, &Role{Id: id, Role: roleRegistrar, Org: goconfig.Orgs[id]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replaces the configuration. Admin only.
+func (t *HouseContractCC) UpdateConfig(stub shim.ChaincodeStubInterface,
+	goconfig *Config) error {
+	logger := shim.NewLogger("UpdateConfig")
+	logger.Infof("UpdateConfig: config = %+v", goconfig)
+
+	if _, err := requireRole(stub, logger, roleAdmin); err != nil {
+		return err
+	}
+
+	if goconfig == nil {
+		mes := "config is null"
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	if err := validateConfig(goconfig); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	err := putConfig(stub, goconfig)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns the current configuration
+func (t *HouseContractCC) GetConfig(stub shim.ChaincodeStubInterface) (*Config, error) {
+	logger := shim.NewLogger("GetConfig")
+	logger.Info("GetConfig")
+
+	goconfig, err := getConfig(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return goconfig, nil
+}
+
+// Grants a role to an Id. Admin only.
+func (t *HouseContractCC) GrantRole(stub shim.ChaincodeStubInterface, gorole *Role) error {
+	logger := shim.NewLogger("GrantRole")
+	logger.Infof("GrantRole: Id = %s, Role = %s, Org = %s", gorole.Id, gorole.Role, gorole.Org)
+
+	if _, err := requireRole(stub, logger, roleAdmin); err != nil {
+		return err
+	}
+	if gorole.Id == "" || gorole.Role == "" || gorole.Org == "" {
+		mes := "Id, Role and Org are all required"
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	err := putRole(stub, gorole)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Revokes a role from an Id. Admin only.
+func (t *HouseContractCC) RevokeRole(stub shim.ChaincodeStubInterface, gorole *Role) error {
+	logger := shim.NewLogger("RevokeRole")
+	logger.Infof("RevokeRole: Id = %s, Role = %s", gorole.Id, gorole.Role)
+
+	invokerId, err := requireRole(stub, logger, roleAdmin)
+	if err != nil {
+		return err
+	}
+	if gorole.Role == roleAdmin && gorole.Id == invokerId {
+		mes := "admins cannot revoke their own admin role"
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	key, err := stub.CreateCompositeKey(prefixRole, []string{gorole.Role, gorole.Id})
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	err = stub.DelState(key)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
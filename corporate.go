@@ -0,0 +1,371 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	prefixSignatory       = "Signatory"
+	prefixSignatoryChange = "SignatoryChange"
+)
+
+const (
+	signatoryAdded   = "added"
+	signatoryChanged = "changed"
+	signatoryRemoved = "removed"
+)
+
+// Signatory is an officer authorized to act for a corporate Owner
+type Signatory struct {
+	OwnerId string
+	Id      string //enrollment ID of the officer
+	Limit   int64  //highest House price the officer may sign for; 0 means no limit
+	Org     string `json:",omitempty"` //MSP ID of the officer; the Owner's by default
+}
+
+// SignatoryChange is the audit record of a change to the signatory list
+type SignatoryChange struct {
+	OwnerId     string
+	SignatoryId string
+	Action      string //added, changed or removed
+	OldLimit    int64
+	NewLimit    int64
+	ChangedBy   string
+	TxId        string
+	Timestamp   time.Time
+}
+
+func getSignatory(stub shim.ChaincodeStubInterface, ownerId string, id string) (*Signatory, error) {
+	key, err := stub.CreateCompositeKey(prefixSignatory, []string{ownerId, id})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return nil, nil
+	}
+
+	gosignatory := new(Signatory)
+	err = unmarshalDoc(prefixSignatory, jsonBytes, gosignatory)
+	if err != nil {
+		return nil, err
+	}
+	return gosignatory, nil
+}
+
+func addSignatoryChange(stub shim.ChaincodeStubInterface, gochange *SignatoryChange) error {
+	now, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	gochange.TxId = stub.GetTxID()
+	gochange.Timestamp = now
+
+	return putDoc(stub, prefixSignatoryChange, []string{gochange.OwnerId, gochange.TxId}, gochange)
+}
+
+// actsFor tells whether invokerId may perform operation for the Owner ownerId
+// on gohouse. A person acts for themself; a corporate Owner acts through its
+// signatories, within their limits; agents act within their Delegation.
+// gohouse may be nil for acts not tied to a House.
+func (t *HouseContractCC) actsFor(stub shim.ChaincodeStubInterface, invokerId string,
+	ownerId string, gohouse *House, operation string) (bool, error) {
+	goowner, err := t.GetOwner(stub, ownerId)
+	if err != nil {
+		return false, err
+	}
+	if !goowner.IsCorporate() {
+		if invokerId == ownerId {
+			return true, nil
+		}
+		return delegated(stub, invokerId, ownerId, operation, gohouse)
+	}
+
+	gosignatory, err := getSignatory(stub, ownerId, invokerId)
+	if err != nil {
+		return false, err
+	}
+	if gosignatory == nil {
+		return delegated(stub, invokerId, ownerId, operation, gohouse)
+	}
+	if gosignatory.Limit > 0 && gohouse != nil {
+		price, err := parsePrice(gohouse)
+		if err != nil {
+			return false, err
+		}
+		if price > gosignatory.Limit {
+			return false, fmt.Errorf("signatory %s of %s may only sign for up to %d",
+				invokerId, ownerId, gosignatory.Limit)
+		}
+	}
+	return true, nil
+}
+
+// requireActsFor returns the invoker's Id if the invoker may perform
+// operation for ownerId
+func (t *HouseContractCC) requireActsFor(stub shim.ChaincodeStubInterface,
+	logger *shim.ChaincodeLogger, ownerId string, gohouse *House, operation string) (string, error) {
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+
+	ok, err := t.actsFor(stub, invokerId, ownerId, gohouse, operation)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+	if !ok {
+		mes := fmt.Sprintf("%s may not %s for Owner with Id = %s", invokerId, operation, ownerId)
+		logger.Warning(mes)
+		return "", errors.New(mes)
+	}
+
+	return invokerId, nil
+}
+
+// requireSignatoryManager returns the invoker's Id if the invoker may change
+// the signatories of a corporate Owner: a registrar or a signatory without limit
+func (t *HouseContractCC) requireSignatoryManager(stub shim.ChaincodeStubInterface,
+	logger *shim.ChaincodeLogger, ownerId string) (string, error) {
+	goowner, err := t.GetOwner(stub, ownerId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+	if !goowner.IsCorporate() {
+		mes := fmt.Sprintf("Owner with Id = %s is not a corporation", ownerId)
+		logger.Warning(mes)
+		return "", errors.New(mes)
+	}
+
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+
+	registrar, err := hasRole(stub, invokerId, roleRegistrar)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+	if registrar {
+		return invokerId, nil
+	}
+
+	gosignatory, err := getSignatory(stub, ownerId, invokerId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+	if gosignatory == nil || gosignatory.Limit > 0 {
+		mes := fmt.Sprintf("%s may not change the signatories of %s", invokerId, ownerId)
+		logger.Warning(mes)
+		return "", errors.New(mes)
+	}
+
+	return invokerId, nil
+}
+
+// Adds a signatory to a corporate Owner or changes their limit. Registrar or
+// unlimited signatory only.
+func (t *HouseContractCC) SetSignatory(stub shim.ChaincodeStubInterface,
+	gosignatory *Signatory) error {
+	logger := shim.NewLogger("SetSignatory")
+	logger.Infof("SetSignatory: signatory = %+v", gosignatory)
+
+	changedBy, err := t.requireSignatoryManager(stub, logger, gosignatory.OwnerId)
+	if err != nil {
+		return err
+	}
+
+	if gosignatory.Id == "" || gosignatory.Limit < 0 {
+		mes := fmt.Sprintf("invalid signatory: Id = %q, Limit = %d", gosignatory.Id, gosignatory.Limit)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	current, err := getSignatory(stub, gosignatory.OwnerId, gosignatory.Id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	gochange := &SignatoryChange{
+		OwnerId:     gosignatory.OwnerId,
+		SignatoryId: gosignatory.Id,
+		Action:      signatoryAdded,
+		NewLimit:    gosignatory.Limit,
+		ChangedBy:   changedBy,
+	}
+	if current != nil {
+		if current.Limit == gosignatory.Limit {
+			mes := fmt.Sprintf("%s already signs for %s with limit %d",
+				gosignatory.Id, gosignatory.OwnerId, gosignatory.Limit)
+			logger.Warning(mes)
+			return errors.New(mes)
+		}
+		gochange.Action = signatoryChanged
+		gochange.OldLimit = current.Limit
+	}
+
+	if gosignatory.Org == "" {
+		goowner, err := t.GetOwner(stub, gosignatory.OwnerId)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+		gosignatory.Org = goowner.Org
+	}
+	err = enroll(stub, gosignatory.Id, gosignatory.Org)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	err = putDoc(stub, prefixSignatory, []string{gosignatory.OwnerId, gosignatory.Id}, gosignatory)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	err = addSignatoryChange(stub, gochange)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Removes a signatory from a corporate Owner. Registrar or unlimited
+// signatory only.
+func (t *HouseContractCC) RemoveSignatory(stub shim.ChaincodeStubInterface,
+	ownerId string, id string) error {
+	logger := shim.NewLogger("RemoveSignatory")
+	logger.Infof("RemoveSignatory: Owner Id = %s, signatory = %s", ownerId, id)
+
+	changedBy, err := t.requireSignatoryManager(stub, logger, ownerId)
+	if err != nil {
+		return err
+	}
+
+	current, err := getSignatory(stub, ownerId, id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if current == nil {
+		mes := fmt.Sprintf("%s is not a signatory of %s", id, ownerId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	key, err := stub.CreateCompositeKey(prefixSignatory, []string{ownerId, id})
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	err = stub.DelState(key)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	err = addSignatoryChange(stub, &SignatoryChange{
+		OwnerId:     ownerId,
+		SignatoryId: id,
+		Action:      signatoryRemoved,
+		OldLimit:    current.Limit,
+		ChangedBy:   changedBy,
+	})
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Lists the signatories of a corporate Owner
+func (t *HouseContractCC) ListSignatories(stub shim.ChaincodeStubInterface,
+	ownerId string) ([]*Signatory, error) {
+	logger := shim.NewLogger("ListSignatories")
+	logger.Infof("ListSignatories: Owner Id = %s", ownerId)
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixSignatory, []string{ownerId})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	gosignatories := []*Signatory{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		gosignatory := new(Signatory)
+		err = unmarshalDoc(prefixSignatory, kv.Value, gosignatory)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		gosignatories = append(gosignatories, gosignatory)
+	}
+
+	logger.Infof("%d %s found", len(gosignatories), "Signatory")
+	return gosignatories, nil
+}
+
+// Lists the changes to the signatories of a corporate Owner, oldest first
+func (t *HouseContractCC) ListSignatoryChanges(stub shim.ChaincodeStubInterface,
+	ownerId string) ([]*SignatoryChange, error) {
+	logger := shim.NewLogger("ListSignatoryChanges")
+	logger.Infof("ListSignatoryChanges: Owner Id = %s", ownerId)
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixSignatoryChange, []string{ownerId})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	gochanges := []*SignatoryChange{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		gochange := new(SignatoryChange)
+		err = unmarshalDoc(prefixSignatoryChange, kv.Value, gochange)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		gochanges = append(gochanges, gochange)
+	}
+
+	sort.Slice(gochanges, func(i, j int) bool {
+		return gochanges[i].Timestamp.Before(gochanges[j].Timestamp)
+	})
+
+	logger.Infof("%d %s found", len(gochanges), "SignatoryChange")
+	return gochanges, nil
+}
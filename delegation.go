@@ -0,0 +1,297 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const prefixDelegation = "Delegation"
+
+// Operations a Delegation may cover
+const (
+	operationUpdate      = "update" //UpdateHouse and AttachDocument
+	operationListForSale = "list"
+	operationTransfer    = "transfer" //TransferHouse and its approvals
+	operationLease       = "lease"    //Leases and their rent payments
+)
+
+// DelegationScope limits what an agent may do. Without HouseIds the agent may
+// act on every House of the Owner.
+type DelegationScope struct {
+	Operations []string
+	HouseIds   []string
+}
+
+// Delegation is a power of attorney letting an agent act for an Owner
+type Delegation struct {
+	OwnerId   string
+	AgentId   string //enrollment ID of the agent
+	Scope     DelegationScope
+	Expiry    time.Time
+	GrantedBy string
+	TxId      string
+	Timestamp time.Time
+	RevokedBy string
+	RevokedAt time.Time
+}
+
+// Covers tells whether the Delegation allows operation on gohouse at now.
+// gohouse may be nil for acts not tied to a House.
+func (d *Delegation) Covers(operation string, gohouse *House, now time.Time) bool {
+	if d.RevokedBy != "" || !now.Before(d.Expiry) {
+		return false
+	}
+
+	allowed := false
+	for _, op := range d.Scope.Operations {
+		if op == operation {
+			allowed = true
+		}
+	}
+	if !allowed || len(d.Scope.HouseIds) == 0 {
+		return allowed
+	}
+	if gohouse == nil {
+		return false
+	}
+	for _, houseId := range d.Scope.HouseIds {
+		if houseId == gohouse.Id {
+			return true
+		}
+	}
+	return false
+}
+
+func validateDelegationScope(goscope *DelegationScope) error {
+	if len(goscope.Operations) == 0 {
+		return errors.New("a delegation must allow at least one operation")
+	}
+	for _, op := range goscope.Operations {
+		switch op {
+		case operationUpdate, operationListForSale, operationTransfer, operationLease:
+		default:
+			return fmt.Errorf("unknown delegated operation: %q", op)
+		}
+	}
+	return nil
+}
+
+func getDelegation(stub shim.ChaincodeStubInterface, ownerId string, agentId string) (*Delegation, error) {
+	key, err := stub.CreateCompositeKey(prefixDelegation, []string{ownerId, agentId})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return nil, nil
+	}
+
+	godelegation := new(Delegation)
+	err = unmarshalDoc(prefixDelegation, jsonBytes, godelegation)
+	if err != nil {
+		return nil, err
+	}
+	return godelegation, nil
+}
+
+// delegated tells whether agentId holds a Delegation of ownerId covering
+// operation on gohouse
+func delegated(stub shim.ChaincodeStubInterface, agentId string, ownerId string,
+	operation string, gohouse *House) (bool, error) {
+	godelegation, err := getDelegation(stub, ownerId, agentId)
+	if err != nil {
+		return false, err
+	}
+	if godelegation == nil {
+		return false, nil
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		return false, err
+	}
+	return godelegation.Covers(operation, gohouse, now), nil
+}
+
+// requireGrantor returns the invoker's Id if the invoker may grant and revoke
+// Delegations of ownerId: the Owner in person or, for a corporation, a
+// signatory without limit. Agents cannot delegate further.
+func (t *HouseContractCC) requireGrantor(stub shim.ChaincodeStubInterface,
+	logger *shim.ChaincodeLogger, ownerId string) (string, error) {
+	goowner, err := t.GetOwner(stub, ownerId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+
+	granted := invokerId == ownerId && !goowner.IsCorporate()
+	if goowner.IsCorporate() {
+		gosignatory, err := getSignatory(stub, ownerId, invokerId)
+		if err != nil {
+			logger.Warning(err.Error())
+			return "", err
+		}
+		granted = gosignatory != nil && gosignatory.Limit == 0
+	}
+	if !granted {
+		mes := fmt.Sprintf("%s may not delegate for Owner with Id = %s", invokerId, ownerId)
+		logger.Warning(mes)
+		return "", errors.New(mes)
+	}
+
+	return invokerId, nil
+}
+
+// Lets an agent act for an Owner within scope until expiry. A new grant to
+// the same agent replaces the previous one.
+func (t *HouseContractCC) GrantDelegation(stub shim.ChaincodeStubInterface, ownerId string,
+	agentId string, goscope *DelegationScope, expiry time.Time) error {
+	logger := shim.NewLogger("GrantDelegation")
+	logger.Infof("GrantDelegation: Owner Id = %s, agent = %s, scope = %+v, expiry = %s",
+		ownerId, agentId, goscope, expiry)
+
+	grantorId, err := t.requireGrantor(stub, logger, ownerId)
+	if err != nil {
+		return err
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	var mes string
+	switch {
+	case agentId == "" || agentId == ownerId:
+		mes = fmt.Sprintf("invalid agent: %q", agentId)
+	case goscope == nil:
+		mes = "a delegation scope is required"
+	case !expiry.After(now):
+		mes = fmt.Sprintf("the delegation must expire in the future: %s", expiry)
+	}
+	if mes != "" {
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	if err := validateDelegationScope(goscope); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	godelegation := &Delegation{
+		OwnerId:   ownerId,
+		AgentId:   agentId,
+		Scope:     *goscope,
+		Expiry:    expiry,
+		GrantedBy: grantorId,
+		TxId:      stub.GetTxID(),
+		Timestamp: now,
+	}
+	// the organization of an agent is never taken from the Owner: a registrar
+	// enrolls agents that are not enrolled yet
+	goenrollment, err := getEnrollment(stub, agentId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if goenrollment == nil {
+		mes := fmt.Sprintf("%s is not enrolled", agentId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	err = putDoc(stub, prefixDelegation, []string{ownerId, agentId}, godelegation)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Ends a Delegation before it expires
+func (t *HouseContractCC) RevokeDelegation(stub shim.ChaincodeStubInterface,
+	ownerId string, agentId string) error {
+	logger := shim.NewLogger("RevokeDelegation")
+	logger.Infof("RevokeDelegation: Owner Id = %s, agent = %s", ownerId, agentId)
+
+	grantorId, err := t.requireGrantor(stub, logger, ownerId)
+	if err != nil {
+		return err
+	}
+
+	godelegation, err := getDelegation(stub, ownerId, agentId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if godelegation == nil || godelegation.RevokedBy != "" || !now.Before(godelegation.Expiry) {
+		mes := fmt.Sprintf("%s holds no active delegation of %s", agentId, ownerId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	godelegation.RevokedBy = grantorId
+	godelegation.RevokedAt = now
+
+	err = putDoc(stub, prefixDelegation, []string{ownerId, agentId}, godelegation)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Lists the Delegations an Owner granted, including expired and revoked ones
+func (t *HouseContractCC) ListDelegations(stub shim.ChaincodeStubInterface,
+	ownerId string) ([]*Delegation, error) {
+	logger := shim.NewLogger("ListDelegations")
+	logger.Infof("ListDelegations: Owner Id = %s", ownerId)
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixDelegation, []string{ownerId})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	godelegations := []*Delegation{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		godelegation := new(Delegation)
+		err = unmarshalDoc(prefixDelegation, kv.Value, godelegation)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		godelegations = append(godelegations, godelegation)
+	}
+
+	logger.Infof("%d %s found", len(godelegations), "Delegation")
+	return godelegations, nil
+}
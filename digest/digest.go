@@ -0,0 +1,73 @@
+// Package digest computes the Merkle root the house contract reports over
+// its Owner and House records. It depends on the standard library only, so
+// off-chain tools verify exports with the very code the chaincode runs.
+//
+// Records are sorted by key. Each leaf is SHA-256 over 0x00, the key length
+// (8 bytes, big endian), the key and the value; each inner node is SHA-256
+// over 0x01 and its two children. An odd node at the end of a level moves up
+// unchanged. The root of no records is SHA-256 of the empty string.
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+)
+
+// Algorithm names the construction above
+const Algorithm = "sha256-merkle-v1"
+
+// Record is one world state entry, its value byte for byte
+type Record struct {
+	Key   string
+	Value string
+}
+
+func leaf(record *Record) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(record.Key)))
+	h.Write(n[:])
+	h.Write([]byte(record.Key))
+	h.Write([]byte(record.Value))
+	return h.Sum(nil)
+}
+
+func node(left []byte, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// Root returns the hex encoded Merkle root of the records, in any order
+func Root(records []*Record) string {
+	sorted := make([]*Record, len(records))
+	copy(sorted, records)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+
+	if len(sorted) == 0 {
+		empty := sha256.Sum256(nil)
+		return hex.EncodeToString(empty[:])
+	}
+
+	level := make([][]byte, 0, len(sorted))
+	for _, record := range sorted {
+		level = append(level, leaf(record))
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, node(level[i], level[i+1]))
+			}
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
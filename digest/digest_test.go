@@ -0,0 +1,40 @@
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// OK1: the root does not depend on the order of the records
+func TestRoot_OK1(t *testing.T) {
+	a := &Record{"\x00House\x001\x00", `{"Id":"1"}`}
+	b := &Record{"\x00House\x002\x00", `{"Id":"2"}`}
+	c := &Record{"\x00Owner\x00Alice\x00", `{"Id":"Alice"}`}
+
+	root := Root([]*Record{a, b, c})
+	assert.Len(t, root, 64)
+	assert.Equal(t, root, Root([]*Record{c, a, b}))
+
+	// three leaves: the third moves up unchanged
+	want := node(node(leaf(a), leaf(b)), leaf(c))
+	assert.Equal(t, hex.EncodeToString(want), root)
+
+	empty := sha256.Sum256(nil)
+	assert.Equal(t, hex.EncodeToString(empty[:]), Root(nil))
+	assert.Equal(t, hex.EncodeToString(leaf(a)), Root([]*Record{a}))
+}
+
+// NG1: any change of a key or a value changes the root
+func TestRoot_NG1(t *testing.T) {
+	records := []*Record{{"k1", "v1"}, {"k2", "v2"}}
+	root := Root(records)
+
+	assert.NotEqual(t, root, Root([]*Record{{"k1", "v1"}, {"k2", "v3"}}))
+	assert.NotEqual(t, root, Root([]*Record{{"k1", "v1"}, {"k3", "v2"}}))
+	assert.NotEqual(t, root, Root(records[:1]))
+	// the key length keeps key and value apart
+	assert.NotEqual(t, Root([]*Record{{"ab", "c"}}), Root([]*Record{{"a", "bc"}}))
+}
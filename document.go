@@ -0,0 +1,258 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const prefixDocument = "Document"
+
+// Document anchors an off-chain document to a House by its SHA-256 digest
+type Document struct {
+	HouseId    string
+	DocType    string //e.g. deed, floor-plan, inspection, sales-contract
+	SHA256     string
+	URI        string
+	MimeType   string
+	AttachedBy string
+	TransferId string //the transfer the document was used for, if any
+	TxId       string
+	Timestamp  time.Time
+}
+
+// HouseDetails is the GetHouse output: the House, what is attached to it and
+// the holds in force on it
+type HouseDetails struct {
+	*House
+	Documents []*Document `json:",omitempty"`
+	Holds     []*Hold     `json:",omitempty"`
+}
+
+func putDocument(stub shim.ChaincodeStubInterface, godocument *Document) error {
+	return putDoc(stub, prefixDocument, []string{godocument.HouseId, godocument.SHA256}, godocument)
+}
+
+// listDocuments lists the Documents of a House, oldest first
+func listDocuments(stub shim.ChaincodeStubInterface, houseId string) ([]*Document, error) {
+	iter, err := stub.GetStateByPartialCompositeKey(prefixDocument, []string{houseId})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	godocuments := []*Document{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		godocument := new(Document)
+		err = unmarshalDoc(prefixDocument, kv.Value, godocument)
+		if err != nil {
+			return nil, err
+		}
+		godocuments = append(godocuments, godocument)
+	}
+
+	sort.Slice(godocuments, func(i, j int) bool {
+		return godocuments[i].Timestamp.Before(godocuments[j].Timestamp)
+	})
+	return godocuments, nil
+}
+
+// transferDocuments picks, for each required document type, the newest
+// Document of the House not used for an earlier transfer
+func transferDocuments(stub shim.ChaincodeStubInterface, houseId string,
+	required []string) ([]*Document, error) {
+	if len(required) == 0 {
+		return []*Document{}, nil
+	}
+
+	godocuments, err := listDocuments(stub, houseId)
+	if err != nil {
+		return nil, err
+	}
+
+	picked := []*Document{}
+	missing := []string{}
+	for _, docType := range required {
+		var newest *Document
+		for _, godocument := range godocuments {
+			if godocument.DocType == docType && godocument.TransferId == "" {
+				newest = godocument
+			}
+		}
+		if newest == nil {
+			missing = append(missing, docType)
+			continue
+		}
+		picked = append(picked, newest)
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("House with Id = %s lacks documents required for a transfer: %s",
+			houseId, strings.Join(missing, ", "))
+	}
+	return picked, nil
+}
+
+// Attaches a document to a House. Owner or registrar only.
+func (t *HouseContractCC) AttachDocument(stub shim.ChaincodeStubInterface,
+	houseId string, docType string, sha256 string, uri string, mimeType string) error {
+	logger := shim.NewLogger("AttachDocument")
+	logger.Infof("AttachDocument: House Id = %s, type = %s, SHA-256 = %s", houseId, docType, sha256)
+
+	godocument := &Document{
+		HouseId:  houseId,
+		DocType:  docType,
+		SHA256:   sha256,
+		URI:      uri,
+		MimeType: mimeType,
+	}
+
+	gohouse, err := t.GetHouse(stub, godocument.HouseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	owner, err := t.actsFor(stub, invokerId, gohouse.OwnerId, gohouse, operationUpdate)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if !owner {
+		registrar, err := hasRole(stub, invokerId, roleRegistrar)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+		if !registrar {
+			mes := fmt.Sprintf("%s may not attach documents to House with Id = %s",
+				invokerId, gohouse.Id)
+			logger.Warning(mes)
+			return errors.New(mes)
+		}
+	}
+
+	var mes string
+	switch {
+	case strings.TrimSpace(godocument.DocType) == "":
+		mes = "a document type is required"
+	case !isSHA256(godocument.SHA256):
+		mes = fmt.Sprintf("not a hex SHA-256 digest: %q", godocument.SHA256)
+	case strings.TrimSpace(godocument.URI) == "":
+		mes = "a document URI is required"
+	case !strings.Contains(godocument.MimeType, "/"):
+		mes = fmt.Sprintf("invalid MIME type: %q", godocument.MimeType)
+	}
+	if mes != "" {
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	godocument.SHA256 = strings.ToLower(godocument.SHA256)
+
+	key, err := stub.CreateCompositeKey(prefixDocument, []string{godocument.HouseId, godocument.SHA256})
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if jsonBytes != nil {
+		mes := fmt.Sprintf("a document with SHA-256 = %s is already attached to House with Id = %s",
+			godocument.SHA256, godocument.HouseId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	godocument.AttachedBy = invokerId
+	godocument.TxId = stub.GetTxID()
+	godocument.Timestamp = now
+
+	err = putDocument(stub, godocument)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns the Document of a House with the given digest, if it is attached
+func (t *HouseContractCC) VerifyDocument(stub shim.ChaincodeStubInterface,
+	houseId string, sha256 string) (*Document, error) {
+	logger := shim.NewLogger("VerifyDocument")
+	logger.Infof("VerifyDocument: House Id = %s, SHA-256 = %s", houseId, sha256)
+
+	key, err := stub.CreateCompositeKey(prefixDocument, []string{houseId, strings.ToLower(sha256)})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if jsonBytes == nil {
+		mes := fmt.Sprintf("no document with SHA-256 = %s is attached to House with Id = %s",
+			sha256, houseId)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	godocument := new(Document)
+	err = unmarshalDoc(prefixDocument, jsonBytes, godocument)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return godocument, nil
+}
+
+// Returns a House together with its Documents and active Holds
+func (t *HouseContractCC) GetHouseDetails(stub shim.ChaincodeStubInterface,
+	id string) (*HouseDetails, error) {
+	logger := shim.NewLogger("GetHouseDetails")
+
+	gohouse, err := t.GetHouse(stub, id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	godocuments, err := listDocuments(stub, id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	goholds, err := activeHolds(stub, id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return &HouseDetails{House: gohouse, Documents: godocuments, Holds: goholds}, nil
+}
@@ -0,0 +1,44 @@
+package cc
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/statebased"
+)
+
+// invokerOrg returns the MSP ID of the transaction submitter
+func invokerOrg(stub shim.ChaincodeStubInterface) (string, error) {
+	mspid, err := cid.GetMSPID(stub)
+	if err != nil {
+		return "", fmt.Errorf("the organization of the invoker cannot be read: %s", err)
+	}
+	return mspid, nil
+}
+
+// setHouseEndorsement requires a peer of org to endorse every later change of
+// the House key
+func setHouseEndorsement(stub shim.ChaincodeStubInterface, houseId string, org string) error {
+	if org == "" {
+		return fmt.Errorf("no organization to endorse House with Id = %s", houseId)
+	}
+	key, err := houseKey(stub, houseId)
+	if err != nil {
+		return err
+	}
+
+	ep, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return err
+	}
+	err = ep.AddOrgs(statebased.RoleTypePeer, org)
+	if err != nil {
+		return err
+	}
+	policy, err := ep.Policy()
+	if err != nil {
+		return err
+	}
+	return stub.SetStateValidationParameter(key, policy)
+}
@@ -0,0 +1,463 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const prefixEstate = "Estate"
+
+const (
+	editKindEstateLock  = "estate-lock"
+	editKindInheritance = "inheritance"
+)
+
+const (
+	estateOpen        = "open"
+	estateDistributed = "distributed"
+)
+
+// Share is a fractional co-ownership of a House
+type Share struct {
+	OwnerId     string
+	Numerator   int64
+	Denominator int64
+}
+
+func (s *Share) rat() *big.Rat {
+	return big.NewRat(s.Numerator, s.Denominator)
+}
+
+// Allotment assigns the deceased's part of a House to heirs. The Shares are
+// fractions of that part and must add up to 1.
+type Allotment struct {
+	HouseId string
+	Shares  []*Share
+}
+
+// DistributionPlan carries out a probate decision
+type DistributionPlan struct {
+	ProbateRef string
+	Allotments []*Allotment
+}
+
+// Estate records the death of an Owner and the distribution of their Houses
+type Estate struct {
+	OwnerId              string
+	DeathCertificateHash string //hex SHA-256 of the death certificate
+	Status               string
+	Houses               []string //Houses locked when the death was recorded
+	RecordedBy           string
+	Timestamp            time.Time
+	ProbateRef           string
+	Allotments           []*Allotment
+	DistributedBy        string
+	DistributedAt        time.Time
+}
+
+// formatShares renders shares for the audit trail, e.g. "Alice 1/2, Bob 1/2"
+func formatShares(shares []*Share) string {
+	parts := []string{}
+	for _, share := range shares {
+		parts = append(parts, fmt.Sprintf("%s %d/%d", share.OwnerId, share.Numerator, share.Denominator))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// holders returns the Owners holding a House, with the part each one holds
+func holders(gohouse *House) []*Share {
+	if len(gohouse.Shares) == 0 {
+		return []*Share{{OwnerId: gohouse.OwnerId, Numerator: 1, Denominator: 1}}
+	}
+	return gohouse.Shares
+}
+
+// holdsHouse tells whether ownerId owns the House or a share of it
+func holdsHouse(gohouse *House, ownerId string) bool {
+	for _, share := range holders(gohouse) {
+		if share.OwnerId == ownerId {
+			return true
+		}
+	}
+	return false
+}
+
+// inherit hands the part of gohouse held by deceasedId to the heirs of
+// goallotment. The largest holder becomes the House's OwnerId; a House left
+// with a single holder drops its Shares. Shares too fine to store fail.
+func inherit(gohouse *House, deceasedId string, goallotment *Allotment) error {
+	parts := map[string]*big.Rat{}
+	order := []string{}
+	add := func(ownerId string, part *big.Rat) {
+		if parts[ownerId] == nil {
+			parts[ownerId] = new(big.Rat)
+			order = append(order, ownerId)
+		}
+		parts[ownerId].Add(parts[ownerId], part)
+	}
+
+	deceasedPart := new(big.Rat)
+	for _, share := range holders(gohouse) {
+		if share.OwnerId == deceasedId {
+			deceasedPart.Add(deceasedPart, share.rat())
+			continue
+		}
+		add(share.OwnerId, share.rat())
+	}
+	for _, share := range goallotment.Shares {
+		add(share.OwnerId, new(big.Rat).Mul(deceasedPart, share.rat()))
+	}
+
+	if len(order) == 1 {
+		gohouse.OwnerId = order[0]
+		gohouse.Shares = nil
+		return nil
+	}
+
+	shares := []*Share{}
+	largest := order[0]
+	for _, ownerId := range order {
+		part := parts[ownerId]
+		if !part.Num().IsInt64() || !part.Denom().IsInt64() {
+			return fmt.Errorf("the share of %s in House with Id = %s is too fine to record: %s",
+				ownerId, gohouse.Id, part.RatString())
+		}
+		shares = append(shares, &Share{
+			OwnerId:     ownerId,
+			Numerator:   part.Num().Int64(),
+			Denominator: part.Denom().Int64(),
+		})
+		if part.Cmp(parts[largest]) > 0 {
+			largest = ownerId
+		}
+	}
+	gohouse.Shares = shares
+	gohouse.OwnerId = largest
+	return nil
+}
+
+func putEstate(stub shim.ChaincodeStubInterface, goestate *Estate) error {
+	return putDoc(stub, prefixEstate, []string{goestate.OwnerId}, goestate)
+}
+
+// getEstate returns the Estate of an Owner, or nil if none was recorded
+func getEstate(stub shim.ChaincodeStubInterface, ownerId string) (*Estate, error) {
+	key, err := stub.CreateCompositeKey(prefixEstate, []string{ownerId})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return nil, nil
+	}
+
+	goestate := new(Estate)
+	err = unmarshalDoc(prefixEstate, jsonBytes, goestate)
+	if err != nil {
+		return nil, err
+	}
+	return goestate, nil
+}
+
+// checkNotDeceased fails if the death of the Owner was recorded
+func checkNotDeceased(stub shim.ChaincodeStubInterface, ownerId string) error {
+	goestate, err := getEstate(stub, ownerId)
+	if err != nil {
+		return err
+	}
+	if goestate != nil {
+		return fmt.Errorf("Owner with Id = %s is deceased", ownerId)
+	}
+	return nil
+}
+
+// checkEstateLock fails while a holder of the House has an open Estate
+func checkEstateLock(stub shim.ChaincodeStubInterface, gohouse *House) error {
+	for _, share := range holders(gohouse) {
+		goestate, err := getEstate(stub, share.OwnerId)
+		if err != nil {
+			return err
+		}
+		if goestate != nil && goestate.Status == estateOpen {
+			return fmt.Errorf("House with Id = %s is locked by the estate of %s",
+				gohouse.Id, share.OwnerId)
+		}
+	}
+	return nil
+}
+
+// validateAllotment checks the heirs and fractions of an Allotment
+func (t *HouseContractCC) validateAllotment(stub shim.ChaincodeStubInterface,
+	deceasedId string, goallotment *Allotment) error {
+	if len(goallotment.Shares) == 0 {
+		return fmt.Errorf("House with Id = %s is allotted to nobody", goallotment.HouseId)
+	}
+
+	total := new(big.Rat)
+	seen := map[string]bool{}
+	for _, share := range goallotment.Shares {
+		if share == nil || share.Numerator <= 0 || share.Denominator <= 0 {
+			return fmt.Errorf("House with Id = %s: shares must be positive fractions", goallotment.HouseId)
+		}
+		if share.OwnerId == deceasedId || seen[share.OwnerId] {
+			return fmt.Errorf("House with Id = %s: invalid heir %s", goallotment.HouseId, share.OwnerId)
+		}
+		seen[share.OwnerId] = true
+
+		found, err := t.CheckOwner(stub, share.OwnerId)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("heir with Id = %s is not a registered Owner", share.OwnerId)
+		}
+		if err := checkNotDeceased(stub, share.OwnerId); err != nil {
+			return err
+		}
+		total.Add(total, share.rat())
+	}
+	if total.Cmp(big.NewRat(1, 1)) != 0 {
+		return fmt.Errorf("House with Id = %s: shares add up to %s, not 1",
+			goallotment.HouseId, total.RatString())
+	}
+	return nil
+}
+
+// Records the death of an Owner and locks their Houses. Registrar only.
+func (t *HouseContractCC) RecordDeath(stub shim.ChaincodeStubInterface,
+	ownerId string, certificateHash string) error {
+	logger := shim.NewLogger("RecordDeath")
+	logger.Infof("RecordDeath: Owner Id = %s, certificate = %s", ownerId, certificateHash)
+
+	registrarId, err := requireRole(stub, logger, roleRegistrar)
+	if err != nil {
+		return err
+	}
+
+	found, err := t.CheckOwner(stub, ownerId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if !found {
+		mes := fmt.Sprintf("Owner with Id = %s was not found", ownerId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	if !isSHA256(certificateHash) {
+		mes := fmt.Sprintf("certificate hash is not a hex SHA-256 digest: %q", certificateHash)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	if err := checkNotDeceased(stub, ownerId); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	gohouses, err := t.ListHouses(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	goestate := &Estate{
+		OwnerId:              ownerId,
+		DeathCertificateHash: strings.ToLower(certificateHash),
+		Status:               estateOpen,
+		Houses:               []string{},
+		RecordedBy:           registrarId,
+		Timestamp:            now,
+		Allotments:           []*Allotment{},
+	}
+	for _, gohouse := range gohouses {
+		if !holdsHouse(gohouse, ownerId) {
+			continue
+		}
+		goestate.Houses = append(goestate.Houses, gohouse.Id)
+
+		err = addHouseEdit(stub, &HouseEdit{
+			HouseId:  gohouse.Id,
+			Kind:     editKindEstateLock,
+			EditorId: registrarId,
+			Reason:   fmt.Sprintf("death of %s recorded", ownerId),
+			Changes:  []FieldChange{},
+		})
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+	}
+
+	err = putEstate(stub, goestate)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	logger.Infof("%d %s locked", len(goestate.Houses), "House")
+	return nil
+}
+
+// Hands the Houses of a deceased Owner to the heirs of a probate decision, all
+// in one transaction. Every locked House must be allotted. Registrar only.
+func (t *HouseContractCC) DistributeEstate(stub shim.ChaincodeStubInterface,
+	ownerId string, goplan *DistributionPlan) error {
+	logger := shim.NewLogger("DistributeEstate")
+	logger.Infof("DistributeEstate: Owner Id = %s", ownerId)
+
+	registrarId, err := requireRole(stub, logger, roleRegistrar)
+	if err != nil {
+		return err
+	}
+
+	goestate, err := getEstate(stub, ownerId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if goestate == nil || goestate.Status != estateOpen {
+		mes := fmt.Sprintf("Owner with Id = %s has no open estate", ownerId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	if goplan == nil || strings.TrimSpace(goplan.ProbateRef) == "" {
+		mes := "a probate decision reference is required"
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	locked := map[string]bool{}
+	for _, houseId := range goestate.Houses {
+		locked[houseId] = true
+	}
+	allotted := map[string]bool{}
+	for _, goallotment := range goplan.Allotments {
+		if goallotment == nil || !locked[goallotment.HouseId] || allotted[goallotment.HouseId] {
+			mes := "every House of the estate must be allotted exactly once"
+			logger.Warning(mes)
+			return errors.New(mes)
+		}
+		allotted[goallotment.HouseId] = true
+
+		if err := t.validateAllotment(stub, ownerId, goallotment); err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+	}
+	if len(allotted) != len(locked) {
+		mes := fmt.Sprintf("%d of %d Houses of the estate are not allotted",
+			len(locked)-len(allotted), len(locked))
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	for _, goallotment := range goplan.Allotments {
+		current, err := t.GetHouse(stub, goallotment.HouseId)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+		if err := checkNotFrozen(stub, current.Id); err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+
+		gohouse := *current
+		if err := inherit(&gohouse, ownerId, goallotment); err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+
+		err = putHouse(stub, &gohouse)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+
+		if gohouse.OwnerId != current.OwnerId {
+			before, err := t.GetOwner(stub, current.OwnerId)
+			if err != nil {
+				logger.Warning(err.Error())
+				return err
+			}
+			after, err := t.GetOwner(stub, gohouse.OwnerId)
+			if err != nil {
+				logger.Warning(err.Error())
+				return err
+			}
+			if before.Org != after.Org {
+				err = setHouseEndorsement(stub, gohouse.Id, after.Org)
+				if err != nil {
+					logger.Warning(err.Error())
+					return err
+				}
+			}
+		}
+
+		err = addHouseEdit(stub, &HouseEdit{
+			HouseId:  gohouse.Id,
+			Kind:     editKindInheritance,
+			EditorId: registrarId,
+			Reason:   goplan.ProbateRef,
+			Changes:  diffHouse(current, &gohouse),
+		})
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	goestate.Status = estateDistributed
+	goestate.ProbateRef = goplan.ProbateRef
+	goestate.Allotments = goplan.Allotments
+	goestate.DistributedBy = registrarId
+	goestate.DistributedAt = now
+
+	err = putEstate(stub, goestate)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns the Estate of a deceased Owner
+func (t *HouseContractCC) GetEstate(stub shim.ChaincodeStubInterface,
+	ownerId string) (*Estate, error) {
+	logger := shim.NewLogger("GetEstate")
+	logger.Infof("GetEstate: Owner Id = %s", ownerId)
+
+	goestate, err := getEstate(stub, ownerId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if goestate == nil {
+		mes := fmt.Sprintf("no death was recorded for Owner with Id = %s", ownerId)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	return goestate, nil
+}
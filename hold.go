@@ -0,0 +1,298 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const prefixHold = "Hold"
+
+const (
+	roleCourt     = "court"
+	roleRegulator = "regulator"
+)
+
+// Hold is a court order or regulatory hold blocking the disposition of a House
+type Hold struct {
+	HouseId    string
+	OrderRef   string //reference of the court order or decision
+	Until      time.Time
+	IssuedBy   string
+	TxId       string
+	Timestamp  time.Time
+	ReleasedBy string
+	ReleasedAt time.Time
+}
+
+// Active tells whether the hold is in force at the given time
+func (h *Hold) Active(now time.Time) bool {
+	return h.ReleasedBy == "" && now.Before(h.Until)
+}
+
+// FrozenError is returned by the operations a Hold blocks
+type FrozenError struct {
+	HouseId  string
+	OrderRef string
+	Until    time.Time
+}
+
+func (e *FrozenError) Error() string {
+	return fmt.Sprintf("House with Id = %s is frozen by order %s until %s",
+		e.HouseId, e.OrderRef, e.Until.Format(time.RFC3339))
+}
+
+func putHold(stub shim.ChaincodeStubInterface, gohold *Hold) error {
+	return putDoc(stub, prefixHold, []string{gohold.HouseId, gohold.OrderRef}, gohold)
+}
+
+// listHolds lists the Holds of a House, or of all Houses if houseId is empty
+func listHolds(stub shim.ChaincodeStubInterface, houseId string) ([]*Hold, error) {
+	attributes := []string{}
+	if houseId != "" {
+		attributes = append(attributes, houseId)
+	}
+	iter, err := stub.GetStateByPartialCompositeKey(prefixHold, attributes)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	goholds := []*Hold{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		gohold := new(Hold)
+		err = unmarshalDoc(prefixHold, kv.Value, gohold)
+		if err != nil {
+			return nil, err
+		}
+		goholds = append(goholds, gohold)
+	}
+	return goholds, nil
+}
+
+// activeHolds lists the Holds in force, soonest to expire first
+func activeHolds(stub shim.ChaincodeStubInterface, houseId string) ([]*Hold, error) {
+	goholds, err := listHolds(stub, houseId)
+	if err != nil {
+		return nil, err
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	active := []*Hold{}
+	for _, gohold := range goholds {
+		if gohold.Active(now) {
+			active = append(active, gohold)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Until.Before(active[j].Until)
+	})
+	return active, nil
+}
+
+// checkNotFrozen fails with a FrozenError while a Hold on the House is active
+func checkNotFrozen(stub shim.ChaincodeStubInterface, houseId string) error {
+	active, err := activeHolds(stub, houseId)
+	if err != nil {
+		return err
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	// report the hold lasting longest
+	last := active[len(active)-1]
+	return &FrozenError{HouseId: houseId, OrderRef: last.OrderRef, Until: last.Until}
+}
+
+// requireCourtOrRegulator returns the invoker's Id if the invoker is a court
+// or a regulator
+func requireCourtOrRegulator(stub shim.ChaincodeStubInterface,
+	logger *shim.ChaincodeLogger) (string, error) {
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+
+	for _, role := range []string{roleCourt, roleRegulator} {
+		found, err := hasRole(stub, invokerId, role)
+		if err != nil {
+			logger.Warning(err.Error())
+			return "", err
+		}
+		if found {
+			return invokerId, nil
+		}
+	}
+
+	mes := fmt.Sprintf("%s is neither a court nor a regulator", invokerId)
+	logger.Warning(mes)
+	return "", errors.New(mes)
+}
+
+// Puts a House on hold until the given time. Court or regulator only.
+func (t *HouseContractCC) FreezeHouse(stub shim.ChaincodeStubInterface,
+	houseId string, orderRef string, until time.Time) error {
+	logger := shim.NewLogger("FreezeHouse")
+	logger.Infof("FreezeHouse: House Id = %s, order = %s, until = %s", houseId, orderRef, until)
+
+	invokerId, err := requireCourtOrRegulator(stub, logger)
+	if err != nil {
+		return err
+	}
+
+	found, err := t.CheckHouse(stub, houseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if !found {
+		mes := fmt.Sprintf("House with Id = %s does not exist", houseId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	var mes string
+	switch {
+	case strings.TrimSpace(orderRef) == "":
+		mes = "an order reference is required"
+	case !until.After(now):
+		mes = fmt.Sprintf("the hold must end in the future: %s", until)
+	}
+	if mes != "" {
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	key, err := stub.CreateCompositeKey(prefixHold, []string{houseId, orderRef})
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if jsonBytes != nil {
+		// an order released or run out may be imposed again; the ledger
+		// history of the key keeps the earlier hold
+		previous := new(Hold)
+		err = unmarshalDoc(prefixHold, jsonBytes, previous)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+		if previous.Active(now) {
+			mes := fmt.Sprintf("order %s is already in force on House with Id = %s", orderRef, houseId)
+			logger.Warning(mes)
+			return errors.New(mes)
+		}
+	}
+
+	gohold := &Hold{
+		HouseId:   houseId,
+		OrderRef:  orderRef,
+		Until:     until,
+		IssuedBy:  invokerId,
+		TxId:      stub.GetTxID(),
+		Timestamp: now,
+	}
+	err = putHold(stub, gohold)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Releases a hold before it ends. Court or regulator only.
+func (t *HouseContractCC) UnfreezeHouse(stub shim.ChaincodeStubInterface,
+	houseId string, orderRef string) error {
+	logger := shim.NewLogger("UnfreezeHouse")
+	logger.Infof("UnfreezeHouse: House Id = %s, order = %s", houseId, orderRef)
+
+	invokerId, err := requireCourtOrRegulator(stub, logger)
+	if err != nil {
+		return err
+	}
+
+	key, err := stub.CreateCompositeKey(prefixHold, []string{houseId, orderRef})
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if jsonBytes == nil {
+		mes := fmt.Sprintf("House with Id = %s has no hold by order %s", houseId, orderRef)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	gohold := new(Hold)
+	err = unmarshalDoc(prefixHold, jsonBytes, gohold)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	if !gohold.Active(now) {
+		mes := fmt.Sprintf("the hold by order %s on House with Id = %s is no longer active",
+			orderRef, houseId)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	gohold.ReleasedBy = invokerId
+	gohold.ReleasedAt = now
+	err = putHold(stub, gohold)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Lists the active holds on a House, or on all Houses if houseId is empty
+func (t *HouseContractCC) ListHolds(stub shim.ChaincodeStubInterface,
+	houseId string) ([]*Hold, error) {
+	logger := shim.NewLogger("ListHolds")
+	logger.Infof("ListHolds: House Id = %s", houseId)
+
+	goholds, err := activeHolds(stub, houseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	logger.Infof("%d %s found", len(goholds), "Hold")
+	return goholds, nil
+}
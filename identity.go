@@ -0,0 +1,263 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	prefixRole       = "Role"
+	prefixEnrollment = "Enrollment"
+)
+
+const (
+	roleAdmin     = "admin"
+	roleRegistrar = "registrar"
+)
+
+// Role grants a named role to an invoker identity
+type Role struct {
+	Id   string //식별자 (enrollment ID)
+	Role string
+	Org  string `json:",omitempty"` //MSP ID that issues the enrollment ID
+}
+
+// Enrollment binds an enrollment ID to the MSP that issues it, so that the
+// same common name from another organization is not taken for it
+type Enrollment struct {
+	Id  string
+	Org string
+}
+
+func getEnrollment(stub shim.ChaincodeStubInterface, id string) (*Enrollment, error) {
+	key, err := stub.CreateCompositeKey(prefixEnrollment, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return nil, nil
+	}
+
+	goenrollment := new(Enrollment)
+	err = unmarshalDoc(prefixEnrollment, jsonBytes, goenrollment)
+	if err != nil {
+		return nil, err
+	}
+	return goenrollment, nil
+}
+
+// enroll binds id to org. An id stays with the organization it was first
+// bound to.
+func enroll(stub shim.ChaincodeStubInterface, id string, org string) error {
+	if org == "" {
+		return fmt.Errorf("no organization to enroll %s with", id)
+	}
+	current, err := getEnrollment(stub, id)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		if current.Org != org {
+			return fmt.Errorf("%s is enrolled with %s, not %s", id, current.Org, org)
+		}
+		return nil
+	}
+	return putDoc(stub, prefixEnrollment, []string{id}, &Enrollment{Id: id, Org: org})
+}
+
+// getInvokerId returns the enrollment ID (certificate CN) of the transaction
+// submitter, provided it is enrolled with the submitter's MSP. Owner.Id is
+// expected to match the enrollment ID of its owner.
+func getInvokerId(stub shim.ChaincodeStubInterface) (string, error) {
+	cert, err := cid.GetX509Certificate(stub)
+	if err != nil {
+		return "", err
+	}
+	if cert.Subject.CommonName == "" {
+		return "", errors.New("the invoker certificate has no common name")
+	}
+	id := cert.Subject.CommonName
+
+	org, err := invokerOrg(stub)
+	if err != nil {
+		return "", err
+	}
+	goenrollment, err := getEnrollment(stub, id)
+	if err != nil {
+		return "", err
+	}
+	if goenrollment == nil {
+		return "", fmt.Errorf("%s is not enrolled", id)
+	}
+	if goenrollment.Org != org {
+		return "", fmt.Errorf("%s is enrolled with %s, not %s", id, goenrollment.Org, org)
+	}
+	return id, nil
+}
+
+// putRole stores a Role and enrolls its holder with the Role's organization
+func putRole(stub shim.ChaincodeStubInterface, gorole *Role) error {
+	if err := enroll(stub, gorole.Id, gorole.Org); err != nil {
+		return err
+	}
+	return putDoc(stub, prefixRole, []string{gorole.Role, gorole.Id}, gorole)
+}
+
+func hasRole(stub shim.ChaincodeStubInterface, id string, role string) (bool, error) {
+	key, err := stub.CreateCompositeKey(prefixRole, []string{role, id})
+	if err != nil {
+		return false, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return false, err
+	}
+
+	return jsonBytes != nil, nil
+}
+
+// assignRoleOrg gives the roles of id stored without an organization the
+// organization org
+func assignRoleOrg(stub shim.ChaincodeStubInterface, id string, org string) error {
+	iter, err := stub.GetStateByPartialCompositeKey(prefixRole, []string{})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	goroles := []*Role{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		gorole := new(Role)
+		if err := unmarshalDoc(prefixRole, kv.Value, gorole); err != nil {
+			return err
+		}
+		if gorole.Id == id && gorole.Org == "" {
+			goroles = append(goroles, gorole)
+		}
+	}
+
+	for _, gorole := range goroles {
+		gorole.Org = org
+		if err := putDoc(stub, prefixRole, []string{gorole.Role, gorole.Id}, gorole); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignConfigOrg records org as the organization of id in the Config if id
+// is a bootstrap admin or registrar without one
+func assignConfigOrg(stub shim.ChaincodeStubInterface, id string, org string) error {
+	goconfig, err := getConfig(stub)
+	if err != nil {
+		return err
+	}
+	if goconfig.Orgs[id] != "" {
+		return nil
+	}
+	for _, bootstrap := range append(append([]string{}, goconfig.Admins...), goconfig.Registrars...) {
+		if bootstrap == id {
+			if goconfig.Orgs == nil {
+				goconfig.Orgs = map[string]string{}
+			}
+			goconfig.Orgs[id] = org
+			return putDoc(stub, prefixConfig, []string{}, goconfig)
+		}
+	}
+	return nil
+}
+
+// Enrolls Ids with the organizations that issue them. An Owner or role
+// stored before organizations were recorded takes the organization of its
+// Id, so that its holder can act again. Registrar only.
+func (t *HouseContractCC) Enroll(stub shim.ChaincodeStubInterface,
+	goenrollments []*Enrollment) error {
+	logger := shim.NewLogger("Enroll")
+	logger.Infof("Enroll: %d Ids", len(goenrollments))
+
+	if _, err := requireRole(stub, logger, roleRegistrar); err != nil {
+		return err
+	}
+	if err := checkBatchSize(logger, len(goenrollments)); err != nil {
+		return err
+	}
+
+	store := NewLedgerStore(stub)
+	for _, goenrollment := range goenrollments {
+		if goenrollment == nil || goenrollment.Id == "" || goenrollment.Org == "" {
+			mes := "Id and Org are both required"
+			logger.Warning(mes)
+			return errors.New(mes)
+		}
+
+		err := enroll(stub, goenrollment.Id, goenrollment.Org)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+
+		goowner, err := store.GetOwner(goenrollment.Id)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+		if goowner != nil && goowner.Org == "" {
+			goowner.Org = goenrollment.Org
+			err = store.PutOwner(goowner)
+			if err != nil {
+				logger.Warning(err.Error())
+				return err
+			}
+		}
+
+		err = assignRoleOrg(stub, goenrollment.Id, goenrollment.Org)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+		err = assignConfigOrg(stub, goenrollment.Id, goenrollment.Org)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+	}
+
+	logger.Infof("%d %s enrolled", len(goenrollments), "Id")
+	return nil
+}
+
+// requireRole returns the invoker's Id if the invoker holds the given role
+func requireRole(stub shim.ChaincodeStubInterface, logger *shim.ChaincodeLogger,
+	role string) (string, error) {
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+
+	found, err := hasRole(stub, invokerId, role)
+	if err != nil {
+		logger.Warning(err.Error())
+		return "", err
+	}
+	if !found {
+		mes := fmt.Sprintf("%s does not have the %s role", invokerId, role)
+		logger.Warning(mes)
+		return "", errors.New(mes)
+	}
+
+	return invokerId, nil
+}
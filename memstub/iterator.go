@@ -0,0 +1,111 @@
+package memstub
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// iterator walks key/value pairs captured when the query ran
+type iterator struct {
+	kvs    []*queryresult.KV
+	closed bool
+}
+
+// kvsOf returns the pairs of state under keys, in the order of keys
+func kvsOf(state map[string][]byte, keys []string) []*queryresult.KV {
+	kvs := make([]*queryresult.KV, 0, len(keys))
+	for _, key := range keys {
+		kvs = append(kvs, &queryresult.KV{Key: key, Value: state[key]})
+	}
+	return kvs
+}
+
+func (it *iterator) HasNext() bool {
+	return !it.closed && len(it.kvs) > 0
+}
+
+func (it *iterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+func (it *iterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// historyIterator walks the committed changes of a key
+type historyIterator struct {
+	mods   []*queryresult.KeyModification
+	closed bool
+}
+
+func (it *historyIterator) HasNext() bool {
+	return !it.closed && len(it.mods) > 0
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	mod := it.mods[0]
+	it.mods = it.mods[1:]
+	return mod, nil
+}
+
+func (it *historyIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// paginate returns the page of kvs after bookmark. The bookmark is the key of
+// the last result returned, so that pages stay in place when earlier results
+// are added or removed; an empty bookmark starts at the first result and an
+// empty returned bookmark means there is no next page.
+func paginate(kvs []*queryresult.KV, pageSize int32,
+	bookmark string) (*iterator, *pb.QueryResponseMetadata, error) {
+	if pageSize <= 0 {
+		return nil, nil, fmt.Errorf("invalid page size: %d", pageSize)
+	}
+	start := 0
+	if bookmark != "" {
+		start = -1
+		for i, kv := range kvs {
+			if kv.Key == bookmark {
+				start = i + 1
+				break
+			}
+		}
+		// a bookmark removed since lies between the keys around it, as long
+		// as the results are in key order
+		if start < 0 && sort.SliceIsSorted(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key }) {
+			start = sort.Search(len(kvs), func(i int) bool { return kvs[i].Key > bookmark })
+		}
+		if start < 0 {
+			return nil, nil, fmt.Errorf("invalid bookmark: %q", bookmark)
+		}
+	}
+
+	end := start + int(pageSize)
+	if end > len(kvs) {
+		end = len(kvs)
+	}
+	page := kvs[start:end]
+	next := ""
+	if end < len(kvs) {
+		next = page[len(page)-1].Key
+	}
+
+	return &iterator{kvs: page}, &pb.QueryResponseMetadata{
+		FetchedRecordsCount: int32(len(page)),
+		Bookmark:            next,
+	}, nil
+}
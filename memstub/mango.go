@@ -0,0 +1,515 @@
+package memstub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+)
+
+// query is a CouchDB Mango query as passed to GetQueryResult. Indexes
+// (use_index) are accepted and ignored.
+type query struct {
+	Selector map[string]interface{} `json:"selector"`
+	Sort     []interface{}          `json:"sort"`
+	Limit    *int                   `json:"limit"`
+	Skip     int                    `json:"skip"`
+	Fields   []string               `json:"fields"`
+	UseIndex interface{}            `json:"use_index"`
+}
+
+type sortField struct {
+	path []string
+	desc bool
+}
+
+func parseSort(specs []interface{}) ([]sortField, error) {
+	fields := []sortField{}
+	for _, spec := range specs {
+		switch spec := spec.(type) {
+		case string:
+			fields = append(fields, sortField{path: strings.Split(spec, ".")})
+		case map[string]interface{}:
+			if len(spec) != 1 {
+				return nil, fmt.Errorf("invalid sort field: %v", spec)
+			}
+			for field, dir := range spec {
+				switch dir {
+				case "asc", "desc":
+				default:
+					return nil, fmt.Errorf("invalid sort direction of %s: %v", field, dir)
+				}
+				fields = append(fields, sortField{path: strings.Split(field, "."), desc: dir == "desc"})
+			}
+		default:
+			return nil, fmt.Errorf("invalid sort field: %v", spec)
+		}
+	}
+	return fields, nil
+}
+
+type match struct {
+	key string
+	doc map[string]interface{}
+}
+
+// runQuery evaluates a Mango query over the JSON documents of state. Results
+// come in key order unless the query sorts them.
+func runQuery(state map[string][]byte, queryString string) ([]*queryresult.KV, error) {
+	q := new(query)
+	if err := json.Unmarshal([]byte(queryString), q); err != nil {
+		return nil, fmt.Errorf("invalid query: %s", err)
+	}
+	if q.Selector == nil {
+		return nil, errors.New("invalid query: a selector is required")
+	}
+	sortFields, err := parseSort(q.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	matches := []*match{}
+	for _, key := range keys {
+		var doc map[string]interface{}
+		if json.Unmarshal(state[key], &doc) != nil {
+			continue //only JSON documents are queryable
+		}
+		ok, err := matchSelector(q.Selector, doc)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, &match{key, doc})
+		}
+	}
+
+	if len(sortFields) > 0 {
+		sort.SliceStable(matches, func(i, j int) bool {
+			for _, f := range sortFields {
+				a, _ := lookup(matches[i].doc, f.path)
+				b, _ := lookup(matches[j].doc, f.path)
+				c := compare(a, b)
+				if c != 0 {
+					return (c < 0) != f.desc
+				}
+			}
+			return false
+		})
+	}
+
+	if q.Skip >= len(matches) {
+		matches = nil
+	} else if q.Skip > 0 {
+		matches = matches[q.Skip:]
+	}
+	if q.Limit != nil && *q.Limit < len(matches) {
+		matches = matches[:*q.Limit]
+	}
+
+	kvs := make([]*queryresult.KV, 0, len(matches))
+	for _, m := range matches {
+		value := state[m.key]
+		if len(q.Fields) > 0 {
+			value, err = json.Marshal(project(m.doc, q.Fields))
+			if err != nil {
+				return nil, err
+			}
+		}
+		kvs = append(kvs, &queryresult.KV{Key: m.key, Value: value})
+	}
+	return kvs, nil
+}
+
+// project keeps only the given fields of doc
+func project(doc map[string]interface{}, fields []string) map[string]interface{} {
+	res := map[string]interface{}{}
+	for _, field := range fields {
+		path := strings.Split(field, ".")
+		value, ok := lookup(doc, path)
+		if !ok {
+			continue
+		}
+		m := res
+		for _, p := range path[:len(path)-1] {
+			sub, ok := m[p].(map[string]interface{})
+			if !ok {
+				sub = map[string]interface{}{}
+				m[p] = sub
+			}
+			m = sub
+		}
+		m[path[len(path)-1]] = value
+	}
+	return res
+}
+
+// lookup returns the value at path in doc
+func lookup(doc interface{}, path []string) (interface{}, bool) {
+	value := doc
+	for _, p := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = m[p]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// matchSelector tells whether doc satisfies every condition of selector
+func matchSelector(selector map[string]interface{}, doc interface{}) (bool, error) {
+	for field, cond := range selector {
+		var ok bool
+		var err error
+		if strings.HasPrefix(field, "$") {
+			ok, err = matchCombination(field, cond, doc)
+		} else {
+			value, found := lookup(doc, strings.Split(field, "."))
+			ok, err = matchCondition(cond, value, found)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchCombination evaluates $and, $or, $nor and $not over whole documents
+func matchCombination(op string, arg interface{}, doc interface{}) (bool, error) {
+	if op == "$not" {
+		sub, ok := arg.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s needs a selector", op)
+		}
+		ok, err := matchSelector(sub, doc)
+		return !ok, err
+	}
+
+	subs, ok := arg.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("%s needs an array of selectors", op)
+	}
+	matched := 0
+	for _, sub := range subs {
+		selector, ok := sub.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s needs an array of selectors", op)
+		}
+		ok, err := matchSelector(selector, doc)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched++
+		}
+	}
+
+	switch op {
+	case "$and":
+		return matched == len(subs), nil
+	case "$or":
+		return matched > 0, nil
+	case "$nor":
+		return matched == 0, nil
+	}
+	return false, fmt.Errorf("unknown operator: %s", op)
+}
+
+// isOperatorObject tells whether cond is an object of operators, as opposed
+// to a selector of sub-fields
+func isOperatorObject(cond interface{}) (map[string]interface{}, bool) {
+	m, ok := cond.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return nil, false
+	}
+	for k := range m {
+		if !strings.HasPrefix(k, "$") {
+			return nil, false
+		}
+	}
+	return m, true
+}
+
+// matchCondition evaluates the condition of a field whose value is value;
+// found is false if the document lacks the field
+func matchCondition(cond interface{}, value interface{}, found bool) (bool, error) {
+	ops, ok := isOperatorObject(cond)
+	if !ok {
+		if sub, ok := cond.(map[string]interface{}); ok && len(sub) > 0 {
+			if !found {
+				return false, nil
+			}
+			return matchSelector(sub, value)
+		}
+		return found && compare(value, cond) == 0, nil
+	}
+
+	for op, arg := range ops {
+		ok, err := matchOperator(op, arg, value, found)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(op string, arg interface{}, value interface{}, found bool) (bool, error) {
+	if op == "$exists" {
+		want, ok := arg.(bool)
+		if !ok {
+			return false, errors.New("$exists needs a boolean")
+		}
+		return found == want, nil
+	}
+	if !found {
+		return false, nil
+	}
+
+	switch op {
+	case "$eq":
+		return compare(value, arg) == 0, nil
+	case "$ne":
+		return compare(value, arg) != 0, nil
+	case "$gt":
+		return compare(value, arg) > 0, nil
+	case "$gte":
+		return compare(value, arg) >= 0, nil
+	case "$lt":
+		return compare(value, arg) < 0, nil
+	case "$lte":
+		return compare(value, arg) <= 0, nil
+
+	case "$in", "$nin":
+		list, ok := arg.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s needs an array", op)
+		}
+		in := false
+		for _, item := range list {
+			if compare(value, item) == 0 {
+				in = true
+			}
+		}
+		return in == (op == "$in"), nil
+
+	case "$not":
+		ok, err := matchCondition(arg, value, found)
+		return !ok, err
+
+	case "$and", "$or", "$nor":
+		subs, ok := arg.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s needs an array", op)
+		}
+		matched := 0
+		for _, sub := range subs {
+			ok, err := matchCondition(sub, value, found)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				matched++
+			}
+		}
+		switch op {
+		case "$and":
+			return matched == len(subs), nil
+		case "$or":
+			return matched > 0, nil
+		}
+		return matched == 0, nil
+
+	case "$type":
+		want, ok := arg.(string)
+		if !ok {
+			return false, errors.New("$type needs a string")
+		}
+		return typeName(value) == want, nil
+
+	case "$size":
+		list, ok := value.([]interface{})
+		n, isNumber := arg.(float64)
+		if !isNumber {
+			return false, errors.New("$size needs a number")
+		}
+		return ok && float64(len(list)) == n, nil
+
+	case "$mod":
+		args, ok := arg.([]interface{})
+		if !ok || len(args) != 2 {
+			return false, errors.New("$mod needs [divisor, remainder]")
+		}
+		divisor, ok1 := args[0].(float64)
+		remainder, ok2 := args[1].(float64)
+		if !ok1 || !ok2 || divisor == 0 {
+			return false, errors.New("$mod needs [divisor, remainder]")
+		}
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return false, nil
+		}
+		return math.Mod(n, divisor) == remainder, nil
+
+	case "$regex":
+		pattern, ok := arg.(string)
+		if !ok {
+			return false, errors.New("$regex needs a string")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+		s, ok := value.(string)
+		return ok && re.MatchString(s), nil
+
+	case "$all":
+		want, ok := arg.([]interface{})
+		if !ok {
+			return false, errors.New("$all needs an array")
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			return false, nil
+		}
+		for _, w := range want {
+			in := false
+			for _, item := range list {
+				if compare(item, w) == 0 {
+					in = true
+				}
+			}
+			if !in {
+				return false, nil
+			}
+		}
+		return true, nil
+
+	case "$elemMatch", "$allMatch":
+		list, ok := value.([]interface{})
+		if !ok {
+			return false, nil
+		}
+		matched := 0
+		for _, item := range list {
+			ok, err := matchCondition(arg, item, true)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				matched++
+			}
+		}
+		if op == "$elemMatch" {
+			return matched > 0, nil
+		}
+		return len(list) > 0 && matched == len(list), nil
+	}
+
+	return false, fmt.Errorf("unknown operator: %s", op)
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// typeRank orders JSON types as CouchDB collates them
+func typeRank(value interface{}) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	}
+	return 6
+}
+
+// compare orders two JSON values: null < false < true < numbers < strings <
+// arrays < objects
+func compare(a, b interface{}) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	case []interface{}:
+		b := b.([]interface{})
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if c := compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+		return len(a) - len(b)
+	case map[string]interface{}:
+		b := b.(map[string]interface{})
+		ka, kb := sortedKeys(a), sortedKeys(b)
+		for i := 0; i < len(ka) && i < len(kb); i++ {
+			if c := strings.Compare(ka[i], kb[i]); c != 0 {
+				return c
+			}
+			if c := compare(a[ka[i]], b[kb[i]]); c != 0 {
+				return c
+			}
+		}
+		return len(ka) - len(kb)
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,255 @@
+package memstub
+
+import (
+	"encoding/json"
+	"housecontract/cc"
+	"housecontract/scenario"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	alice  = `{"Id":"Alice","Org":"Org1MSP"}`
+	house1 = `{"Id":"1", "Address":"seoul", "OwnerId":"Alice","Price":"3000", "Timestamp":"2018-01-01T12:34:56Z"}`
+	house2 = `{"Id":"2", "Address":"busan", "OwnerId":"Alice","Price":"2000", "Timestamp":"2018-01-01T12:34:56Z"}`
+)
+
+func getBytes(function string, args ...string) [][]byte {
+	bytes := make([][]byte, 0, len(args)+1)
+	bytes = append(bytes, []byte(function))
+	for _, s := range args {
+		bytes = append(bytes, []byte(s))
+	}
+	return bytes
+}
+
+func responseOK(res pb.Response) func() bool {
+	return func() bool { return res.Status < shim.ERRORTHRESHOLD }
+}
+
+func responseFail(res pb.Response) func() bool {
+	return func() bool { return res.Status >= shim.ERRORTHRESHOLD }
+}
+
+// newHouseStub returns a Stub of HouseContractCC holding Alice and her Houses
+func newHouseStub(t *testing.T) *Stub {
+	stub := New("housecontract", new(cc.HouseContractCC))
+	stub.SetTime(time.Date(2018, 1, 1, 12, 34, 56, 0, time.UTC))
+	if !assert.Condition(t, responseOK(stub.MockInit(util.GenerateUUID(), nil))) {
+		t.FailNow()
+	}
+	for _, args := range [][][]byte{getBytes("AddOwner", alice),
+		getBytes("AddHouse", house1), getBytes("AddHouse", house2)} {
+		res := stub.MockInvoke(util.GenerateUUID(), args)
+		if !assert.Condition(t, responseOK(res), res.Message) {
+			t.FailNow()
+		}
+	}
+	return stub
+}
+
+func queryKeys(t *testing.T, iter shim.StateQueryIteratorInterface) []string {
+	defer iter.Close()
+	keys := []string{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if !assert.NoError(t, err) {
+			break
+		}
+		keys = append(keys, kv.Key)
+	}
+	return keys
+}
+
+// OK1: HouseContractCC runs on the Stub; failed transactions are rolled back
+func TestStub_OK1(t *testing.T) {
+	stub := newHouseStub(t)
+	key, _ := stub.CreateCompositeKey("House", []string{"1"})
+	before := stub.State[key]
+
+	// Alice is not the invoker, so the update fails
+	res := stub.MockInvoke(util.GenerateUUID(), getBytes("UpdateHouse",
+		`{"Id":"1", "Address":"seoul", "OwnerId":"Alice","Price":"3500", "Timestamp":"2018-01-01T12:34:56Z"}`))
+	assert.Condition(t, responseFail(res))
+	assert.Equal(t, before, stub.State[key])
+
+	creator, err := scenario.Creator("Alice", "Org1MSP")
+	if assert.NoError(t, err) {
+		stub.Creator = creator
+		stub.SetTime(time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC))
+		res = stub.MockInvoke("tx-update", getBytes("UpdateHouse",
+			`{"Id":"1", "Address":"seoul", "OwnerId":"Alice","Price":"3500", "Timestamp":"2018-01-01T12:34:56Z"}`))
+		assert.Condition(t, responseOK(res), res.Message)
+	}
+
+	iter, err := stub.GetHistoryForKey(key)
+	if assert.NoError(t, err) {
+		mods := []string{}
+		for iter.HasNext() {
+			mod, _ := iter.Next()
+			mods = append(mods, mod.TxId)
+			if mod.TxId == "tx-update" {
+				ts, _ := ptypes.Timestamp(mod.Timestamp)
+				assert.Equal(t, 2018, ts.Year())
+				assert.Equal(t, time.February, ts.Month())
+			}
+		}
+		assert.Len(t, mods, 2)
+		assert.Equal(t, "tx-update", mods[1])
+	}
+}
+
+// OK1: rich queries select, sort, page and project documents
+func TestGetQueryResult_OK1(t *testing.T) {
+	stub := newHouseStub(t)
+	key1, _ := stub.CreateCompositeKey("House", []string{"1"})
+	key2, _ := stub.CreateCompositeKey("House", []string{"2"})
+
+	iter, err := stub.GetQueryResult(`{"selector":{"Data.OwnerId":"Alice","Data.Address":{"$exists":true}},
+		"sort":[{"Data.Price":"asc"}]}`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{key2, key1}, queryKeys(t, iter))
+	}
+
+	iter, err = stub.GetQueryResult(`{"selector":{"Data":{"Address":{"$regex":"^se"}}},"fields":["Data.Price"]}`)
+	if assert.NoError(t, err) && assert.True(t, iter.HasNext()) {
+		kv, _ := iter.Next()
+		assert.Equal(t, key1, kv.Key)
+		assert.JSONEq(t, `{"Data":{"Price":"3000"}}`, string(kv.Value))
+	}
+
+	iter, meta, err := stub.GetQueryResultWithPagination(`{"selector":{"Data.OwnerId":{"$in":["Alice","Bob"]}}}`, 1, "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{key1}, queryKeys(t, iter))
+		iter, meta, err = stub.GetQueryResultWithPagination(`{"selector":{"Data.OwnerId":{"$in":["Alice","Bob"]}}}`, 1, meta.Bookmark)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{key2}, queryKeys(t, iter))
+			assert.Empty(t, meta.Bookmark)
+		}
+	}
+
+	iter, meta, err = stub.GetStateByPartialCompositeKeyWithPagination("House", []string{}, 5, "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{key1, key2}, queryKeys(t, iter))
+		assert.Equal(t, int32(2), meta.FetchedRecordsCount)
+	}
+}
+
+// OK2: a page starts after the last key returned, even once earlier results
+// change
+func TestGetQueryResult_OK2(t *testing.T) {
+	stub := newHouseStub(t)
+	key1, _ := stub.CreateCompositeKey("House", []string{"1"})
+	key2, _ := stub.CreateCompositeKey("House", []string{"2"})
+
+	iter, meta, err := stub.GetStateByPartialCompositeKeyWithPagination("House", []string{}, 1, "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{key1}, queryKeys(t, iter))
+		assert.Equal(t, key1, meta.Bookmark)
+	}
+
+	key0, _ := stub.CreateCompositeKey("House", []string{"0"})
+	stub.State[key0] = stub.State[key1]
+	delete(stub.State, key1)
+	iter, meta, err = stub.GetStateByPartialCompositeKeyWithPagination("House", []string{}, 1, key1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{key2}, queryKeys(t, iter))
+		assert.Empty(t, meta.Bookmark)
+	}
+
+	// a sorted query cannot place a bookmark it no longer returns
+	query := `{"selector":{"Data.Price":{"$exists":true}},"sort":[{"Data.Price":"asc"}]}`
+	_, _, err = stub.GetQueryResultWithPagination(query, 1, key1)
+	assert.Error(t, err)
+	iter, _, err = stub.GetQueryResultWithPagination(query, 1, key2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{key0}, queryKeys(t, iter))
+	}
+}
+
+// NG1: malformed queries are rejected
+func TestGetQueryResult_NG1(t *testing.T) {
+	stub := newHouseStub(t)
+	for _, query := range []string{
+		`not json`,
+		`{"sort":["Data.Price"]}`,
+		`{"selector":{"Data.Price":{"$near":1}}}`,
+		`{"selector":{"$or":{"Data.Price":"1"}}}`,
+		`{"selector":{"Data.Price":{"$regex":"("}}}`,
+		`{"selector":{},"sort":[{"Data.Price":"up"}]}`,
+	} {
+		_, err := stub.GetQueryResult(query)
+		assert.Error(t, err, query)
+	}
+}
+
+// OK1: selector operators
+func TestMatchSelector_OK1(t *testing.T) {
+	var doc map[string]interface{}
+	json.Unmarshal([]byte(`{"Id":"1","Price":3000,"Tags":["new","sea"],
+		"Shares":[{"OwnerId":"Alice","Numerator":1},{"OwnerId":"Bob","Numerator":2}]}`), &doc)
+
+	for selector, want := range map[string]bool{
+		`{"Price":{"$gt":2000,"$lte":3000}}`:                                true,
+		`{"Price":{"$lt":3000}}`:                                            false,
+		`{"Price":{"$mod":[1000,0]}}`:                                       true,
+		`{"Missing":{"$ne":1}}`:                                             false,
+		`{"Missing":{"$exists":false}}`:                                     true,
+		`{"Tags":{"$all":["sea","new"]}}`:                                   true,
+		`{"Tags":{"$size":3}}`:                                              false,
+		`{"Shares":{"$elemMatch":{"OwnerId":"Bob","Numerator":{"$gt":1}}}}`: true,
+		`{"Shares":{"$allMatch":{"Numerator":{"$gt":1}}}}`:                  false,
+		`{"$or":[{"Id":"2"},{"Price":{"$type":"number"}}]}`:                 true,
+		`{"$nor":[{"Id":"1"}]}`:                                             false,
+		`{"Id":{"$not":{"$in":["2","3"]}}}`:                                 true,
+	} {
+		var selector0 map[string]interface{}
+		if assert.NoError(t, json.Unmarshal([]byte(selector), &selector0), selector) {
+			ok, err := matchSelector(selector0, doc)
+			if assert.NoError(t, err, selector) {
+				assert.Equal(t, want, ok, selector)
+			}
+		}
+	}
+}
+
+// OK1: private data, endorsement parameters and events commit with the transaction
+func TestPrivateData_OK1(t *testing.T) {
+	stub := New("store", nil)
+	stub.Collections = map[string]bool{"secrets": true}
+	ok := func(shim.ChaincodeStubInterface) pb.Response { return shim.Success(nil) }
+	fail := func(shim.ChaincodeStubInterface) pb.Response { return shim.Error("rejected") }
+
+	for _, call := range []func(shim.ChaincodeStubInterface) pb.Response{fail, ok} {
+		stub.run(util.GenerateUUID(), nil, func(s shim.ChaincodeStubInterface) pb.Response {
+			assert.NoError(t, s.PutPrivateData("secrets", "k", []byte("v")))
+			assert.Error(t, s.PutPrivateData("others", "k", []byte("v")))
+			assert.NoError(t, s.SetStateValidationParameter("k", []byte("policy")))
+			assert.NoError(t, s.SetEvent("stored", []byte("k")))
+			// reads do not see the writes of their own transaction
+			value, _ := s.GetPrivateData("secrets", "k")
+			assert.Nil(t, value)
+			return call(s)
+		})
+	}
+
+	value, err := stub.GetPrivateData("secrets", "k")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte("v"), value)
+	}
+	hash, err := stub.GetPrivateDataHash("secrets", "k")
+	if assert.NoError(t, err) {
+		assert.Len(t, hash, 32)
+	}
+	ep, _ := stub.GetStateValidationParameter("k")
+	assert.Equal(t, []byte("policy"), ep)
+	if assert.Len(t, stub.Events, 1) {
+		assert.Equal(t, "stored", stub.Events[0].EventName)
+	}
+}
@@ -0,0 +1,589 @@
+// Package memstub is an in-memory shim.ChaincodeStubInterface for unit tests.
+//
+// Unlike shim.MockStub it implements the whole interface: rich queries with a
+// Mango selector evaluator, pagination, per-key history, private data
+// collections, key-level endorsement parameters and events. Writes are
+// buffered like a peer's write set and committed only when the chaincode
+// answers with a success, so a failed transaction leaves the state untouched
+// and, as on a peer, reads do not see the writes of their own transaction.
+package memstub
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	compositeKeyNamespace = "\x00"
+	minUnicodeRuneValue   = '\u0000'     //U+0000
+	maxUnicodeRuneValue   = utf8.MaxRune //U+10FFFF
+	emptyKeySubstitute    = "\x01"       //start of range queries over simple keys
+)
+
+var _ shim.ChaincodeStubInterface = (*Stub)(nil)
+
+// Stub holds the committed state of one chaincode and runs its transactions
+type Stub struct {
+	Name      string
+	ChannelID string
+
+	// Creator is returned by GetCreator, Transient by GetTransient
+	Creator   []byte
+	Transient map[string][]byte
+	// Clock gives the timestamp of every new transaction; time.Now if nil
+	Clock func() time.Time
+	// Collections restricts private data to the named collections; any
+	// collection is accepted if nil
+	Collections map[string]bool
+	Decorations map[string][]byte
+	// Invokables are the chaincodes reachable through InvokeChaincode
+	Invokables map[string]*Stub
+
+	State              map[string][]byte
+	Private            map[string]map[string][]byte
+	Endorsement        map[string][]byte
+	PrivateEndorsement map[string]map[string][]byte
+	History            map[string][]*queryresult.KeyModification
+	Events             []*pb.ChaincodeEvent
+
+	cc shim.Chaincode
+	tx *transaction
+}
+
+// transaction is the write set of the running transaction
+type transaction struct {
+	id                 string
+	args               [][]byte
+	timestamp          *timestamp.Timestamp
+	writes             map[string][]byte //nil value deletes
+	private            map[string]map[string][]byte
+	endorsement        map[string][]byte
+	privateEndorsement map[string]map[string][]byte
+	event              *pb.ChaincodeEvent
+}
+
+// New returns an empty Stub running cc
+func New(name string, cc shim.Chaincode) *Stub {
+	return &Stub{
+		Name:               name,
+		Transient:          map[string][]byte{},
+		Decorations:        map[string][]byte{},
+		Invokables:         map[string]*Stub{},
+		State:              map[string][]byte{},
+		Private:            map[string]map[string][]byte{},
+		Endorsement:        map[string][]byte{},
+		PrivateEndorsement: map[string]map[string][]byte{},
+		History:            map[string][]*queryresult.KeyModification{},
+		cc:                 cc,
+	}
+}
+
+// SetTime makes every following transaction happen at t
+func (s *Stub) SetTime(t time.Time) {
+	s.Clock = func() time.Time { return t }
+}
+
+func (s *Stub) begin(txid string, args [][]byte) error {
+	now := time.Now()
+	if s.Clock != nil {
+		now = s.Clock()
+	}
+	ts, err := ptypes.TimestampProto(now)
+	if err != nil {
+		return err
+	}
+	s.tx = &transaction{
+		id:                 txid,
+		args:               args,
+		timestamp:          ts,
+		writes:             map[string][]byte{},
+		private:            map[string]map[string][]byte{},
+		endorsement:        map[string][]byte{},
+		privateEndorsement: map[string]map[string][]byte{},
+	}
+	return nil
+}
+
+// end commits the write set of the running transaction if res is a success
+func (s *Stub) end(res pb.Response) {
+	tx := s.tx
+	s.tx = nil
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return
+	}
+
+	for key, value := range tx.writes {
+		if value == nil {
+			delete(s.State, key)
+		} else {
+			s.State[key] = value
+		}
+		s.History[key] = append(s.History[key], &queryresult.KeyModification{
+			TxId:      tx.id,
+			Value:     value,
+			Timestamp: tx.timestamp,
+			IsDelete:  value == nil,
+		})
+	}
+	for collection, writes := range tx.private {
+		if s.Private[collection] == nil {
+			s.Private[collection] = map[string][]byte{}
+		}
+		for key, value := range writes {
+			if value == nil {
+				delete(s.Private[collection], key)
+			} else {
+				s.Private[collection][key] = value
+			}
+		}
+	}
+	for key, ep := range tx.endorsement {
+		s.Endorsement[key] = ep
+	}
+	for collection, eps := range tx.privateEndorsement {
+		if s.PrivateEndorsement[collection] == nil {
+			s.PrivateEndorsement[collection] = map[string][]byte{}
+		}
+		for key, ep := range eps {
+			s.PrivateEndorsement[collection][key] = ep
+		}
+	}
+	if tx.event != nil {
+		s.Events = append(s.Events, tx.event)
+	}
+}
+
+func (s *Stub) run(txid string, args [][]byte, call func(shim.ChaincodeStubInterface) pb.Response) pb.Response {
+	if s.tx != nil {
+		return shim.Error("a transaction is already running")
+	}
+	if err := s.begin(txid, args); err != nil {
+		return shim.Error(err.Error())
+	}
+	res := call(s)
+	s.end(res)
+	return res
+}
+
+// MockInit runs Init of the chaincode in a transaction
+func (s *Stub) MockInit(txid string, args [][]byte) pb.Response {
+	return s.run(txid, args, s.cc.Init)
+}
+
+// MockInvoke runs Invoke of the chaincode in a transaction
+func (s *Stub) MockInvoke(txid string, args [][]byte) pb.Response {
+	return s.run(txid, args, s.cc.Invoke)
+}
+
+func (s *Stub) requireTx() error {
+	if s.tx == nil {
+		return errors.New("no transaction is running")
+	}
+	return nil
+}
+
+func (s *Stub) GetArgs() [][]byte {
+	if s.tx == nil {
+		return nil
+	}
+	return s.tx.args
+}
+
+func (s *Stub) GetStringArgs() []string {
+	args := s.GetArgs()
+	strargs := make([]string, 0, len(args))
+	for _, arg := range args {
+		strargs = append(strargs, string(arg))
+	}
+	return strargs
+}
+
+func (s *Stub) GetFunctionAndParameters() (string, []string) {
+	allargs := s.GetStringArgs()
+	if len(allargs) == 0 {
+		return "", []string{}
+	}
+	return allargs[0], allargs[1:]
+}
+
+func (s *Stub) GetArgsSlice() ([]byte, error) {
+	res := []byte{}
+	for _, arg := range s.GetArgs() {
+		res = append(res, arg...)
+	}
+	return res, nil
+}
+
+func (s *Stub) GetTxID() string {
+	if s.tx == nil {
+		return ""
+	}
+	return s.tx.id
+}
+
+func (s *Stub) GetChannelID() string {
+	return s.ChannelID
+}
+
+// InvokeChaincode runs another chaincode of Invokables in a transaction of
+// its own, with the same transaction ID
+func (s *Stub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	if channel != "" {
+		chaincodeName = chaincodeName + "/" + channel
+	}
+	other, ok := s.Invokables[chaincodeName]
+	if !ok {
+		return shim.Error(fmt.Sprintf("chaincode %s is not reachable", chaincodeName))
+	}
+	return other.MockInvoke(s.GetTxID(), args)
+}
+
+func (s *Stub) GetState(key string) ([]byte, error) {
+	return s.State[key], nil
+}
+
+func (s *Stub) PutState(key string, value []byte) error {
+	if err := s.requireTx(); err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	s.tx.writes[key] = value
+	return nil
+}
+
+func (s *Stub) DelState(key string) error {
+	if err := s.requireTx(); err != nil {
+		return err
+	}
+	s.tx.writes[key] = nil
+	return nil
+}
+
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	if err := s.requireTx(); err != nil {
+		return err
+	}
+	s.tx.endorsement[key] = ep
+	return nil
+}
+
+func (s *Stub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.Endorsement[key], nil
+}
+
+// rangeKeys returns the sorted keys of state in [startKey, endKey); an empty
+// endKey has no upper bound
+func rangeKeys(state map[string][]byte, startKey, endKey string) []string {
+	keys := []string{}
+	for key := range state {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validateSimpleKeys(keys ...string) error {
+	for _, key := range keys {
+		if key != "" && key[0] == compositeKeyNamespace[0] {
+			return fmt.Errorf("first character of the key [%s] contains a null character which is not allowed", key)
+		}
+	}
+	return nil
+}
+
+func (s *Stub) stateRange(state map[string][]byte, startKey, endKey string) *iterator {
+	return &iterator{kvs: kvsOf(state, rangeKeys(state, startKey, endKey))}
+}
+
+func (s *Stub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	return s.stateRange(s.State, startKey, endKey), nil
+}
+
+func (s *Stub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32,
+	bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, nil, err
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	return paginate(kvsOf(s.State, rangeKeys(s.State, startKey, endKey)), pageSize, bookmark)
+}
+
+func partialCompositeKeyRange(objectType string, attributes []string) (string, string, error) {
+	startKey, err := createCompositeKey(objectType, attributes)
+	if err != nil {
+		return "", "", err
+	}
+	return startKey, startKey + string(maxUnicodeRuneValue), nil
+}
+
+func (s *Stub) GetStateByPartialCompositeKey(objectType string,
+	attributes []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return s.stateRange(s.State, startKey, endKey), nil
+}
+
+func (s *Stub) GetStateByPartialCompositeKeyWithPagination(objectType string, attributes []string,
+	pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paginate(kvsOf(s.State, rangeKeys(s.State, startKey, endKey)), pageSize, bookmark)
+}
+
+func validateCompositeKeyAttribute(str string) error {
+	if !utf8.ValidString(str) {
+		return fmt.Errorf("not a valid utf8 string: [%x]", str)
+	}
+	for index, runeValue := range str {
+		if runeValue == minUnicodeRuneValue || runeValue == maxUnicodeRuneValue {
+			return fmt.Errorf(`input contain unicode %#U starting at position [%d]. %#U and %#U are not allowed in the input attribute of a composite key`,
+				runeValue, index, minUnicodeRuneValue, maxUnicodeRuneValue)
+		}
+	}
+	return nil
+}
+
+func createCompositeKey(objectType string, attributes []string) (string, error) {
+	if err := validateCompositeKeyAttribute(objectType); err != nil {
+		return "", err
+	}
+	ck := compositeKeyNamespace + objectType + string(minUnicodeRuneValue)
+	for _, att := range attributes {
+		if err := validateCompositeKeyAttribute(att); err != nil {
+			return "", err
+		}
+		ck += att + string(minUnicodeRuneValue)
+	}
+	return ck, nil
+}
+
+func (s *Stub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return createCompositeKey(objectType, attributes)
+}
+
+func (s *Stub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) {
+		return "", nil, fmt.Errorf("not a composite key: %q", compositeKey)
+	}
+	parts := strings.Split(compositeKey[1:], string(minUnicodeRuneValue))
+	if len(parts) < 2 || parts[len(parts)-1] != "" {
+		return "", nil, fmt.Errorf("malformed composite key: %q", compositeKey)
+	}
+	return parts[0], parts[1 : len(parts)-1], nil
+}
+
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	kvs, err := runQuery(s.State, query)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator{kvs: kvs}, nil
+}
+
+func (s *Stub) GetQueryResultWithPagination(query string, pageSize int32,
+	bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	kvs, err := runQuery(s.State, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paginate(kvs, pageSize, bookmark)
+}
+
+// GetHistoryForKey returns the committed changes of key, oldest first
+func (s *Stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{mods: s.History[key]}, nil
+}
+
+func (s *Stub) checkCollection(collection string) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	if s.Collections != nil && !s.Collections[collection] {
+		return fmt.Errorf("collection %s is not defined", collection)
+	}
+	return nil
+}
+
+func (s *Stub) GetPrivateData(collection, key string) ([]byte, error) {
+	if err := s.checkCollection(collection); err != nil {
+		return nil, err
+	}
+	return s.Private[collection][key], nil
+}
+
+func (s *Stub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	value, err := s.GetPrivateData(collection, key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *Stub) PutPrivateData(collection string, key string, value []byte) error {
+	if err := s.requireTx(); err != nil {
+		return err
+	}
+	if err := s.checkCollection(collection); err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	if s.tx.private[collection] == nil {
+		s.tx.private[collection] = map[string][]byte{}
+	}
+	s.tx.private[collection][key] = value
+	return nil
+}
+
+func (s *Stub) DelPrivateData(collection, key string) error {
+	if err := s.requireTx(); err != nil {
+		return err
+	}
+	if err := s.checkCollection(collection); err != nil {
+		return err
+	}
+	if s.tx.private[collection] == nil {
+		s.tx.private[collection] = map[string][]byte{}
+	}
+	s.tx.private[collection][key] = nil
+	return nil
+}
+
+func (s *Stub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	if err := s.requireTx(); err != nil {
+		return err
+	}
+	if err := s.checkCollection(collection); err != nil {
+		return err
+	}
+	if s.tx.privateEndorsement[collection] == nil {
+		s.tx.privateEndorsement[collection] = map[string][]byte{}
+	}
+	s.tx.privateEndorsement[collection][key] = ep
+	return nil
+}
+
+func (s *Stub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	if err := s.checkCollection(collection); err != nil {
+		return nil, err
+	}
+	return s.PrivateEndorsement[collection][key], nil
+}
+
+func (s *Stub) GetPrivateDataByRange(collection, startKey,
+	endKey string) (shim.StateQueryIteratorInterface, error) {
+	if err := s.checkCollection(collection); err != nil {
+		return nil, err
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	return s.stateRange(s.Private[collection], startKey, endKey), nil
+}
+
+func (s *Stub) GetPrivateDataByPartialCompositeKey(collection, objectType string,
+	attributes []string) (shim.StateQueryIteratorInterface, error) {
+	if err := s.checkCollection(collection); err != nil {
+		return nil, err
+	}
+	startKey, endKey, err := partialCompositeKeyRange(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return s.stateRange(s.Private[collection], startKey, endKey), nil
+}
+
+func (s *Stub) GetPrivateDataQueryResult(collection,
+	query string) (shim.StateQueryIteratorInterface, error) {
+	if err := s.checkCollection(collection); err != nil {
+		return nil, err
+	}
+	kvs, err := runQuery(s.Private[collection], query)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator{kvs: kvs}, nil
+}
+
+func (s *Stub) GetCreator() ([]byte, error) {
+	return s.Creator, nil
+}
+
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	return s.Transient, nil
+}
+
+func (s *Stub) GetBinding() ([]byte, error) {
+	return nil, nil
+}
+
+func (s *Stub) GetDecorations() map[string][]byte {
+	return s.Decorations
+}
+
+func (s *Stub) GetSignedProposal() (*pb.SignedProposal, error) {
+	return &pb.SignedProposal{}, nil
+}
+
+func (s *Stub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	if err := s.requireTx(); err != nil {
+		return nil, err
+	}
+	return s.tx.timestamp, nil
+}
+
+// SetEvent sets the event of the running transaction; as on a peer, a
+// transaction emits at most one event and the last one set wins
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	if err := s.requireTx(); err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	s.tx.event = &pb.ChaincodeEvent{
+		ChaincodeId: s.Name,
+		TxId:        s.tx.id,
+		EventName:   name,
+		Payload:     payload,
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package cc
+
+import (
+	"housecontract/digest"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// RegistryDigest is the Merkle root over the stored Owner and House records,
+// as computed by package digest. It covers the stored bytes, so migrating
+// documents to a new schema version changes it.
+type RegistryDigest struct {
+	Algorithm string
+	Root      string
+	Owners    int
+	Houses    int
+	TxId      string
+	Timestamp time.Time
+}
+
+// RegistryExport is every Owner and House record together with their digest,
+// all read in one transaction
+type RegistryExport struct {
+	Digest  *RegistryDigest
+	Records []*digest.Record
+}
+
+// registryRecords reads the Owner and House records in key order
+func registryRecords(stub shim.ChaincodeStubInterface) ([]*digest.Record, *RegistryDigest, error) {
+	records := []*digest.Record{}
+	godigest := &RegistryDigest{Algorithm: digest.Algorithm, TxId: stub.GetTxID()}
+
+	for _, docType := range []string{prefixHouse, prefixOwner} {
+		iter, err := stub.GetStateByPartialCompositeKey(docType, []string{})
+		if err != nil {
+			return nil, nil, err
+		}
+		for iter.HasNext() {
+			kv, err := iter.Next()
+			if err != nil {
+				iter.Close()
+				return nil, nil, err
+			}
+			records = append(records, &digest.Record{Key: kv.Key, Value: string(kv.Value)})
+			if docType == prefixOwner {
+				godigest.Owners++
+			} else {
+				godigest.Houses++
+			}
+		}
+		iter.Close()
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		return nil, nil, err
+	}
+	godigest.Timestamp = now
+	godigest.Root = digest.Root(records)
+	return records, godigest, nil
+}
+
+func (t *HouseContractCC) GetRegistryDigest(stub shim.ChaincodeStubInterface) (*RegistryDigest, error) {
+	logger := shim.NewLogger("GetRegistryDigest")
+	logger.Info("GetRegistryDigest")
+
+	_, godigest, err := registryRecords(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	return godigest, nil
+}
+
+func (t *HouseContractCC) ExportRegistry(stub shim.ChaincodeStubInterface) (*RegistryExport, error) {
+	logger := shim.NewLogger("ExportRegistry")
+	logger.Info("ExportRegistry")
+
+	records, godigest, err := registryRecords(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	return &RegistryExport{godigest, records}, nil
+}
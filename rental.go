@@ -0,0 +1,701 @@
+package cc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	prefixLease       = "Lease"
+	prefixRentPayment = "RentPayment"
+)
+
+// fee in Config.FeePercentages charged on late rent when a Lease sets none
+const feeRentLate = "rent-late"
+
+const (
+	leaseProposed = "proposed"
+	leaseActive   = "active"
+)
+
+// Lease is a rental agreement of a House between its Owner and a tenant.
+// The landlord proposes it and the tenant accepts it.
+type Lease struct {
+	Id             string
+	HouseId        string
+	LandlordId     string //Owner of the House when the Lease was proposed
+	TenantId       string
+	Rent           int64 //per month
+	DueDay         int   //day of the month the rent is due, 1 to 28
+	Start          time.Time
+	End            time.Time //exclusive
+	GraceDays      int
+	LateFeePercent float64 //of the rent, charged once per late installment
+	Status         string
+	ProposedBy     string
+	Timestamp      time.Time
+	AcceptedBy     string
+	AcceptedAt     time.Time
+}
+
+// Overlaps tells whether both Leases let the same House at the same time
+func (l *Lease) Overlaps(other *Lease) bool {
+	return l.HouseId == other.HouseId && l.Start.Before(other.End) && other.Start.Before(l.End)
+}
+
+// RentPayment is rent paid on a Lease. It counts once the landlord
+// acknowledges to have received it: a payment the landlord side records is
+// confirmed as it is recorded, one the tenant side records when the landlord
+// side confirms it.
+type RentPayment struct {
+	LeaseId     string
+	TxId        string
+	Amount      int64
+	RecordedBy  string
+	Timestamp   time.Time
+	ConfirmedBy string
+	ConfirmedAt time.Time
+}
+
+// upgradeRentPayment confirms a payment of schema version 1, when only the
+// landlord side recorded payments
+func upgradeRentPayment(data json.RawMessage) (json.RawMessage, error) {
+	gopayment := new(RentPayment)
+	if err := json.Unmarshal(data, gopayment); err != nil {
+		return nil, err
+	}
+	gopayment.ConfirmedBy = gopayment.RecordedBy
+	gopayment.ConfirmedAt = gopayment.Timestamp
+	return json.Marshal(gopayment)
+}
+
+// Installment is the rent of one month. Rent still unpaid at the end of
+// DueDate plus the grace days is late and owes the late fee.
+type Installment struct {
+	DueDate     time.Time
+	Amount      int64
+	LateFee     int64
+	Paid        int64 //rent and late fee
+	Outstanding int64
+	PaidAt      time.Time //when fully paid
+}
+
+// Arrears is what a tenant owes on a Lease for installments due
+type Arrears struct {
+	LeaseId       string
+	HouseId       string
+	TenantId      string
+	LandlordId    string
+	Installments  int
+	OldestDueDate time.Time
+	LateFees      int64
+	Amount        int64 //rent and late fees outstanding
+	AsOf          time.Time
+}
+
+// dueDates lists the due dates of a Lease within its term
+func dueDates(golease *Lease) []time.Time {
+	start := time.Date(golease.Start.Year(), golease.Start.Month(), golease.Start.Day(), 0, 0, 0, 0, time.UTC)
+	due := time.Date(start.Year(), start.Month(), golease.DueDay, 0, 0, 0, 0, time.UTC)
+	if due.Before(start) {
+		due = due.AddDate(0, 1, 0)
+	}
+
+	dates := []time.Time{}
+	for ; due.Before(golease.End); due = due.AddDate(0, 1, 0) {
+		dates = append(dates, due)
+	}
+	return dates
+}
+
+// lateAt is when rent due at due turns late: the day after the grace days
+func lateAt(golease *Lease, due time.Time) time.Time {
+	return due.AddDate(0, 0, golease.GraceDays+1)
+}
+
+// rentSchedule applies the payments, oldest first, to the installments of a
+// Lease, oldest first, and charges the late fees due at now
+func rentSchedule(golease *Lease, gopayments []*RentPayment, now time.Time) []*Installment {
+	goinstallments := []*Installment{}
+	for _, due := range dueDates(golease) {
+		goinstallments = append(goinstallments, &Installment{DueDate: due, Amount: golease.Rent})
+	}
+
+	chargeLate := func(at time.Time) {
+		for _, goinstallment := range goinstallments {
+			if goinstallment.LateFee == 0 && goinstallment.Paid < goinstallment.Amount &&
+				!at.Before(lateAt(golease, goinstallment.DueDate)) {
+				goinstallment.LateFee = percentOf(goinstallment.Amount, golease.LateFeePercent)
+			}
+		}
+	}
+
+	sorted := make([]*RentPayment, len(gopayments))
+	copy(sorted, gopayments)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	for _, gopayment := range sorted {
+		chargeLate(gopayment.Timestamp)
+		remaining := gopayment.Amount
+		for _, goinstallment := range goinstallments {
+			owed := goinstallment.Amount + goinstallment.LateFee - goinstallment.Paid
+			if remaining == 0 || owed == 0 {
+				continue
+			}
+			if remaining < owed {
+				goinstallment.Paid += remaining
+				remaining = 0
+				continue
+			}
+			goinstallment.Paid += owed
+			goinstallment.PaidAt = gopayment.Timestamp
+			remaining -= owed
+		}
+	}
+	chargeLate(now)
+
+	for _, goinstallment := range goinstallments {
+		goinstallment.Outstanding = goinstallment.Amount + goinstallment.LateFee - goinstallment.Paid
+	}
+	return goinstallments
+}
+
+// arrears sums the installments of a Lease late at now and not paid
+func arrears(golease *Lease, goinstallments []*Installment, now time.Time) *Arrears {
+	goarrears := &Arrears{
+		LeaseId:    golease.Id,
+		HouseId:    golease.HouseId,
+		TenantId:   golease.TenantId,
+		LandlordId: golease.LandlordId,
+		AsOf:       now,
+	}
+	for _, goinstallment := range goinstallments {
+		if goinstallment.Outstanding == 0 || now.Before(lateAt(golease, goinstallment.DueDate)) {
+			continue
+		}
+		if goarrears.Installments == 0 {
+			goarrears.OldestDueDate = goinstallment.DueDate
+		}
+		goarrears.Installments++
+		goarrears.LateFees += goinstallment.LateFee
+		goarrears.Amount += goinstallment.Outstanding
+	}
+	return goarrears
+}
+
+func getLease(stub shim.ChaincodeStubInterface, id string) (*Lease, error) {
+	key, err := stub.CreateCompositeKey(prefixLease, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return nil, fmt.Errorf("Lease with Id = %s was not found", id)
+	}
+
+	golease := new(Lease)
+	err = unmarshalDoc(prefixLease, jsonBytes, golease)
+	if err != nil {
+		return nil, err
+	}
+	return golease, nil
+}
+
+// listLeases lists every Lease, proposed or active
+func listLeases(stub shim.ChaincodeStubInterface) ([]*Lease, error) {
+	iter, err := stub.GetStateByPartialCompositeKey(prefixLease, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	goleases := []*Lease{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		golease := new(Lease)
+		err = unmarshalDoc(prefixLease, kv.Value, golease)
+		if err != nil {
+			return nil, err
+		}
+		goleases = append(goleases, golease)
+	}
+	return goleases, nil
+}
+
+func getRentPayment(stub shim.ChaincodeStubInterface, leaseId string, txId string) (*RentPayment, error) {
+	key, err := stub.CreateCompositeKey(prefixRentPayment, []string{leaseId, txId})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return nil, fmt.Errorf("RentPayment with TxId = %s of Lease with Id = %s was not found", txId, leaseId)
+	}
+
+	gopayment := new(RentPayment)
+	err = unmarshalDoc(prefixRentPayment, jsonBytes, gopayment)
+	if err != nil {
+		return nil, err
+	}
+	return gopayment, nil
+}
+
+func listRentPayments(stub shim.ChaincodeStubInterface, leaseId string) ([]*RentPayment, error) {
+	iter, err := stub.GetStateByPartialCompositeKey(prefixRentPayment, []string{leaseId})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	gopayments := []*RentPayment{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		gopayment := new(RentPayment)
+		err = unmarshalDoc(prefixRentPayment, kv.Value, gopayment)
+		if err != nil {
+			return nil, err
+		}
+		gopayments = append(gopayments, gopayment)
+	}
+	return gopayments, nil
+}
+
+// checkNotLeased fails if an active Lease other than golease overlaps it
+func checkNotLeased(stub shim.ChaincodeStubInterface, golease *Lease) error {
+	goleases, err := listLeases(stub)
+	if err != nil {
+		return err
+	}
+	for _, other := range goleases {
+		if other.Id != golease.Id && other.Status == leaseActive && other.Overlaps(golease) {
+			return fmt.Errorf("House with Id = %s is leased by Lease with Id = %s until %s",
+				golease.HouseId, other.Id, other.End.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// leaseSchedule returns the schedule of an active Lease at the tx timestamp.
+// Only confirmed payments count.
+func leaseSchedule(stub shim.ChaincodeStubInterface, golease *Lease) ([]*Installment, time.Time, error) {
+	now, err := getTxTime(stub)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if golease.Status != leaseActive {
+		return nil, now, fmt.Errorf("Lease with Id = %s has not been accepted", golease.Id)
+	}
+	gopayments, err := listRentPayments(stub, golease.Id)
+	if err != nil {
+		return nil, now, err
+	}
+	confirmed := []*RentPayment{}
+	for _, gopayment := range gopayments {
+		if gopayment.ConfirmedBy != "" {
+			confirmed = append(confirmed, gopayment)
+		}
+	}
+	return rentSchedule(golease, confirmed, now), now, nil
+}
+
+// checkBalance fails if amount is more than what is outstanding on golease
+func checkBalance(goinstallments []*Installment, golease *Lease, amount int64) error {
+	balance := int64(0)
+	for _, goinstallment := range goinstallments {
+		balance += goinstallment.Outstanding
+	}
+	if amount > balance {
+		return fmt.Errorf("payment of %d exceeds the balance %d of Lease with Id = %s",
+			amount, balance, golease.Id)
+	}
+	return nil
+}
+
+// listArrears lists the arrears of the active Leases selects picks
+func listArrears(stub shim.ChaincodeStubInterface, selects func(*Lease) bool) ([]*Arrears, error) {
+	goleases, err := listLeases(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	goarrears := []*Arrears{}
+	for _, golease := range goleases {
+		if golease.Status != leaseActive || !selects(golease) {
+			continue
+		}
+		goinstallments, now, err := leaseSchedule(stub, golease)
+		if err != nil {
+			return nil, err
+		}
+		if owed := arrears(golease, goinstallments, now); owed.Amount > 0 {
+			goarrears = append(goarrears, owed)
+		}
+	}
+	sort.Slice(goarrears, func(i, j int) bool {
+		return goarrears[i].OldestDueDate.Before(goarrears[j].OldestDueDate)
+	})
+	return goarrears, nil
+}
+
+// Proposes a Lease of a House to a tenant. Owner or agent only.
+func (t *HouseContractCC) ProposeLease(stub shim.ChaincodeStubInterface, golease *Lease) error {
+	logger := shim.NewLogger("ProposeLease")
+	logger.Infof("ProposeLease: lease = %+v", golease)
+
+	gohouse, err := t.GetHouse(stub, golease.HouseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	invokerId, err := t.requireActsFor(stub, logger, gohouse.OwnerId, gohouse, operationLease)
+	if err != nil {
+		return err
+	}
+	if _, err := t.GetOwner(stub, golease.TenantId); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	var mes string
+	switch {
+	case golease.Id == "":
+		mes = "Lease Id is empty"
+	case golease.TenantId == gohouse.OwnerId:
+		mes = fmt.Sprintf("Owner with Id = %s may not lease their own House", golease.TenantId)
+	case golease.Rent <= 0:
+		mes = fmt.Sprintf("rent must be positive: %d", golease.Rent)
+	case golease.DueDay < 1 || golease.DueDay > 28:
+		mes = fmt.Sprintf("due day out of range 1 to 28: %d", golease.DueDay)
+	case !golease.Start.Before(golease.End):
+		mes = fmt.Sprintf("the term must end after it starts: %s to %s",
+			golease.Start.Format(time.RFC3339), golease.End.Format(time.RFC3339))
+	case golease.GraceDays < 0:
+		mes = fmt.Sprintf("negative grace period: %d days", golease.GraceDays)
+	case golease.LateFeePercent < 0 || golease.LateFeePercent > 100:
+		mes = fmt.Sprintf("late fee out of range: %g%%", golease.LateFeePercent)
+	case len(dueDates(golease)) == 0:
+		mes = fmt.Sprintf("no rent falls due between %s and %s",
+			golease.Start.Format(time.RFC3339), golease.End.Format(time.RFC3339))
+	}
+	if mes != "" {
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+
+	if _, err := getLease(stub, golease.Id); err == nil {
+		mes := fmt.Sprintf("Lease with Id = %s already exists", golease.Id)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	if err := checkNotLeased(stub, golease); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	if golease.LateFeePercent == 0 {
+		goconfig, err := getConfig(stub)
+		if err != nil {
+			logger.Warning(err.Error())
+			return err
+		}
+		golease.LateFeePercent = goconfig.FeePercentages[feeRentLate]
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	golease.LandlordId = gohouse.OwnerId
+	golease.Status = leaseProposed
+	golease.ProposedBy = invokerId
+	golease.Timestamp = now
+	golease.AcceptedBy = ""
+	golease.AcceptedAt = time.Time{}
+	err = putDoc(stub, prefixLease, []string{golease.Id}, golease)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Accepts a proposed Lease, which makes its rent fall due. Tenant or agent only.
+func (t *HouseContractCC) AcceptLease(stub shim.ChaincodeStubInterface, leaseId string) error {
+	logger := shim.NewLogger("AcceptLease")
+	logger.Infof("AcceptLease: Lease Id = %s", leaseId)
+
+	golease, err := getLease(stub, leaseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	invokerId, err := t.requireActsFor(stub, logger, golease.TenantId, nil, operationLease)
+	if err != nil {
+		return err
+	}
+	if golease.Status != leaseProposed {
+		mes := fmt.Sprintf("Lease with Id = %s is already %s", leaseId, golease.Status)
+		logger.Warning(mes)
+		return errors.New(mes)
+	}
+	if err := checkNotLeased(stub, golease); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	now, err := getTxTime(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+	golease.Status = leaseActive
+	golease.AcceptedBy = invokerId
+	golease.AcceptedAt = now
+	err = putDoc(stub, prefixLease, []string{golease.Id}, golease)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (t *HouseContractCC) GetLease(stub shim.ChaincodeStubInterface, id string) (*Lease, error) {
+	logger := shim.NewLogger("GetLease")
+	logger.Infof("GetLease: Id = %s", id)
+
+	golease, err := getLease(stub, id)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return golease, nil
+}
+
+// Records rent paid on an active Lease. Landlord, tenant or their agents
+// only. Rent the tenant side records counts once the landlord side confirms it.
+func (t *HouseContractCC) RecordRentPayment(stub shim.ChaincodeStubInterface,
+	leaseId string, amount int64) (*RentPayment, error) {
+	logger := shim.NewLogger("RecordRentPayment")
+	logger.Infof("RecordRentPayment: Lease Id = %s, amount = %d", leaseId, amount)
+
+	golease, err := getLease(stub, leaseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	gohouse, err := t.GetHouse(stub, golease.HouseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	invokerId, err := getInvokerId(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	landlord, err := t.actsFor(stub, invokerId, golease.LandlordId, gohouse, operationLease)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	tenant, err := t.actsFor(stub, invokerId, golease.TenantId, gohouse, operationLease)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if !landlord && !tenant {
+		mes := fmt.Sprintf("%s may not record rent of Lease with Id = %s", invokerId, leaseId)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	if amount <= 0 {
+		mes := fmt.Sprintf("amount must be positive: %d", amount)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+	goinstallments, now, err := leaseSchedule(stub, golease)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if err := checkBalance(goinstallments, golease, amount); err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	gopayment := &RentPayment{
+		LeaseId:    leaseId,
+		TxId:       stub.GetTxID(),
+		Amount:     amount,
+		RecordedBy: invokerId,
+		Timestamp:  now,
+	}
+	if landlord {
+		gopayment.ConfirmedBy = invokerId
+		gopayment.ConfirmedAt = now
+	}
+	err = putDoc(stub, prefixRentPayment, []string{gopayment.LeaseId, gopayment.TxId}, gopayment)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return gopayment, nil
+}
+
+// Confirms rent the tenant side recorded as paid, which then counts as paid
+// when it was recorded. Landlord or agent only.
+func (t *HouseContractCC) ConfirmRentPayment(stub shim.ChaincodeStubInterface,
+	leaseId string, txId string) (*RentPayment, error) {
+	logger := shim.NewLogger("ConfirmRentPayment")
+	logger.Infof("ConfirmRentPayment: Lease Id = %s, TxId = %s", leaseId, txId)
+
+	golease, err := getLease(stub, leaseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	gohouse, err := t.GetHouse(stub, golease.HouseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	invokerId, err := t.requireActsFor(stub, logger, golease.LandlordId, gohouse, operationLease)
+	if err != nil {
+		return nil, err
+	}
+
+	gopayment, err := getRentPayment(stub, leaseId, txId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if gopayment.ConfirmedBy != "" {
+		mes := fmt.Sprintf("RentPayment with TxId = %s of Lease with Id = %s is already confirmed",
+			txId, leaseId)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+	goinstallments, now, err := leaseSchedule(stub, golease)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if err := checkBalance(goinstallments, golease, gopayment.Amount); err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	gopayment.ConfirmedBy = invokerId
+	gopayment.ConfirmedAt = now
+	err = putDoc(stub, prefixRentPayment, []string{gopayment.LeaseId, gopayment.TxId}, gopayment)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return gopayment, nil
+}
+
+// Lists the rent recorded as paid on a Lease, confirmed or not
+func (t *HouseContractCC) ListRentPayments(stub shim.ChaincodeStubInterface,
+	leaseId string) ([]*RentPayment, error) {
+	logger := shim.NewLogger("ListRentPayments")
+	logger.Infof("ListRentPayments: Lease Id = %s", leaseId)
+
+	if _, err := getLease(stub, leaseId); err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	gopayments, err := listRentPayments(stub, leaseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	logger.Infof("%d %s found", len(gopayments), "RentPayment")
+	return gopayments, nil
+}
+
+// Lists the installments of an active Lease with the payments and late fees
+// as of the tx timestamp
+func (t *HouseContractCC) GetRentSchedule(stub shim.ChaincodeStubInterface,
+	leaseId string) ([]*Installment, error) {
+	logger := shim.NewLogger("GetRentSchedule")
+	logger.Infof("GetRentSchedule: Lease Id = %s", leaseId)
+
+	golease, err := getLease(stub, leaseId)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	goinstallments, _, err := leaseSchedule(stub, golease)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	logger.Infof("%d %s found", len(goinstallments), "Installment")
+	return goinstallments, nil
+}
+
+// Lists what a tenant owes on their Leases, oldest first
+func (t *HouseContractCC) ListTenantArrears(stub shim.ChaincodeStubInterface,
+	tenantId string) ([]*Arrears, error) {
+	logger := shim.NewLogger("ListTenantArrears")
+	logger.Infof("ListTenantArrears: tenant Id = %s", tenantId)
+
+	goarrears, err := listArrears(stub, func(golease *Lease) bool {
+		return golease.TenantId == tenantId
+	})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	logger.Infof("%d %s found", len(goarrears), "Arrears")
+	return goarrears, nil
+}
+
+// Lists what the tenants of a House owe, oldest first
+func (t *HouseContractCC) ListHouseArrears(stub shim.ChaincodeStubInterface,
+	houseId string) ([]*Arrears, error) {
+	logger := shim.NewLogger("ListHouseArrears")
+	logger.Infof("ListHouseArrears: House Id = %s", houseId)
+
+	goarrears, err := listArrears(stub, func(golease *Lease) bool {
+		return golease.HouseId == houseId
+	})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	logger.Infof("%d %s found", len(goarrears), "Arrears")
+	return goarrears, nil
+}
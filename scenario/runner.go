@@ -0,0 +1,186 @@
+package scenario
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Creator returns a serialized identity of mspid whose certificate CN is id,
+// as returned by GetCreator
+func Creator(id string, mspid string) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: id},
+		NotBefore:    time.Now().Add(-24 * time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	sid := &msp.SerializedIdentity{
+		Mspid:   mspid,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+	return proto.Marshal(sid)
+}
+
+// stepStub reports the creator and tx timestamp of the running step, which
+// shim.MockStub does not let us set
+type stepStub struct {
+	shim.ChaincodeStubInterface
+	creator   []byte
+	timestamp *timestamp.Timestamp
+}
+
+func (s *stepStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *stepStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	if s.timestamp == nil {
+		return s.ChaincodeStubInterface.GetTxTimestamp()
+	}
+	return s.timestamp, nil
+}
+
+// stepCC runs the chaincode under test as the invoker of the running step
+type stepCC struct {
+	cc        shim.Chaincode
+	creator   []byte
+	timestamp *timestamp.Timestamp
+}
+
+func (t *stepCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.cc.Init(&stepStub{stub, t.creator, t.timestamp})
+}
+
+func (t *stepCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.cc.Invoke(&stepStub{stub, t.creator, t.timestamp})
+}
+
+// Runner runs scenarios against fresh instances of a chaincode
+type Runner struct {
+	NewChaincode func() shim.Chaincode
+	creators     map[string][]byte
+}
+
+// NewRunner returns a Runner for the chaincode made by newChaincode
+func NewRunner(newChaincode func() shim.Chaincode) *Runner {
+	return &Runner{NewChaincode: newChaincode, creators: map[string][]byte{}}
+}
+
+// creator returns the identity of a step's invoker, reusing one certificate
+// per invoker so an identity stays the same across steps
+func (r *Runner) creator(gostep *Step) ([]byte, error) {
+	if gostep.Invoker == "" {
+		return nil, nil
+	}
+	mspid := gostep.MSPID
+	if mspid == "" {
+		mspid = DefaultMSPID
+	}
+	key := mspid + "/" + gostep.Invoker
+	if creator, ok := r.creators[key]; ok {
+		return creator, nil
+	}
+	creator, err := Creator(gostep.Invoker, mspid)
+	if err != nil {
+		return nil, err
+	}
+	r.creators[key] = creator
+	return creator, nil
+}
+
+// Run runs each scenario as a subtest of t. The steps of a scenario run in
+// order as nested subtests; the first failing step ends its scenario.
+func (r *Runner) Run(t *testing.T, goscenarios ...*Scenario) {
+	for _, goscenario := range goscenarios {
+		goscenario := goscenario
+		t.Run(goscenario.Name, func(t *testing.T) {
+			r.runScenario(t, goscenario)
+		})
+	}
+}
+
+func (r *Runner) runScenario(t *testing.T, goscenario *Scenario) {
+	scc := &stepCC{cc: r.NewChaincode()}
+	stub := shim.NewMockStub(goscenario.Name, scc)
+
+	var args [][]byte
+	if goscenario.Init != nil {
+		var err error
+		args, err = encodeArgs("init", goscenario.Init)
+		if err != nil {
+			t.Fatalf("init: %s", err)
+		}
+	}
+	res := stub.MockInit(util.GenerateUUID(), args)
+	if res.Status >= shim.ERRORTHRESHOLD {
+		t.Fatalf("init failed: %s", res.Message)
+	}
+
+	for i, gostep := range goscenario.Steps {
+		ok := t.Run(gostep.Label(i), func(t *testing.T) {
+			err := r.runStep(scc, stub, gostep)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+		if !ok {
+			return
+		}
+	}
+}
+
+func (r *Runner) runStep(scc *stepCC, stub *shim.MockStub, gostep *Step) error {
+	creator, err := r.creator(gostep)
+	if err != nil {
+		return err
+	}
+	scc.creator = creator
+
+	scc.timestamp = nil
+	if gostep.Timestamp != nil {
+		scc.timestamp, err = ptypes.TimestampProto(*gostep.Timestamp)
+		if err != nil {
+			return err
+		}
+	}
+
+	args, err := encodeArgs(gostep.Function, gostep.Args)
+	if err != nil {
+		return err
+	}
+	res := stub.MockInvoke(util.GenerateUUID(), args)
+	return gostep.Expect.Check(res)
+}
+
+// RunFiles loads the scenario files matching pattern and runs them as
+// subtests of t
+func RunFiles(t *testing.T, newChaincode func() shim.Chaincode, pattern string) {
+	goscenarios, err := LoadGlob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	NewRunner(newChaincode).Run(t, goscenarios...)
+}
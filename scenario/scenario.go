@@ -0,0 +1,212 @@
+// Package scenario runs declarative chaincode test scenarios on a
+// shim.MockStub.
+//
+// A scenario file (.json, .yaml or .yml) lists the steps of one test: the
+// function to invoke, its arguments, who invokes it and when, and what the
+// response must be. Every scenario runs as a Go subtest on a fresh MockStub,
+// so regression cases can be added without writing Go:
+//
+//	name: owner updates their house
+//	init: ['{"Registrars":["Registrar"],"Orgs":{"Registrar":"Org1MSP"}}']
+//	steps:
+//	  - function: AddOwner
+//	    args: [{Id: Alice, Org: Org1MSP}]
+//	  - function: UpdateHouse
+//	    invoker: Bob
+//	    args: ['{"Id":"1","Address":"seoul","OwnerId":"Alice","Price":"3500"}']
+//	    expect: {status: error, message: may not update}
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"gopkg.in/yaml.v3"
+)
+
+// Expected outcomes of a step
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// DefaultMSPID is the MSP of invokers whose step does not name one
+const DefaultMSPID = "Org1MSP"
+
+// Scenario is one test case: a chaincode Init followed by steps
+type Scenario struct {
+	Name        string        `json:"name" yaml:"name"`
+	Description string        `json:"description" yaml:"description"`
+	Init        []interface{} `json:"init" yaml:"init"` //arguments of Init, after the function name
+	Steps       []*Step       `json:"steps" yaml:"steps"`
+}
+
+// Step is one Invoke and the response expected from it
+type Step struct {
+	Name      string        `json:"name" yaml:"name"`
+	Invoker   string        `json:"invoker" yaml:"invoker"` //certificate CN of the creator; empty for no creator
+	MSPID     string        `json:"mspid" yaml:"mspid"`
+	Timestamp *time.Time    `json:"timestamp" yaml:"timestamp"` //tx timestamp; the wall clock if not set
+	Function  string        `json:"function" yaml:"function"`
+	Args      []interface{} `json:"args" yaml:"args"`
+	Expect    Expect        `json:"expect" yaml:"expect"`
+}
+
+// Expect describes the response a step must get. Unset fields are not checked.
+type Expect struct {
+	Status  string      `json:"status" yaml:"status"` //ok or error; ok if not set
+	Code    int32       `json:"code" yaml:"code"`     //exact response status
+	Message string      `json:"message" yaml:"message"`
+	Payload interface{} `json:"payload" yaml:"payload"`
+}
+
+// encodeArg turns a scenario argument into a chaincode argument. Strings are
+// passed as they are; any other value is passed as JSON.
+func encodeArg(arg interface{}) ([]byte, error) {
+	if s, ok := arg.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(arg)
+}
+
+func encodeArgs(function string, args []interface{}) ([][]byte, error) {
+	bytes := make([][]byte, 0, len(args)+1)
+	bytes = append(bytes, []byte(function))
+	for i, arg := range args {
+		b, err := encodeArg(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %s", i+1, err)
+		}
+		bytes = append(bytes, b)
+	}
+	return bytes, nil
+}
+
+// Label names the step in subtest names and failures
+func (s *Step) Label(i int) string {
+	if s.Name != "" {
+		return fmt.Sprintf("%d %s", i+1, s.Name)
+	}
+	return fmt.Sprintf("%d %s", i+1, s.Function)
+}
+
+// Check returns an error describing how res differs from the expectation
+func (e *Expect) Check(res pb.Response) error {
+	switch e.Status {
+	case "", StatusOK:
+		if res.Status >= shim.ERRORTHRESHOLD {
+			return fmt.Errorf("expected success, got status %d: %s", res.Status, res.Message)
+		}
+	case StatusError:
+		if res.Status < shim.ERRORTHRESHOLD {
+			return fmt.Errorf("expected an error, got status %d", res.Status)
+		}
+	default:
+		return fmt.Errorf("unknown expected status: %q", e.Status)
+	}
+
+	if e.Code != 0 && res.Status != e.Code {
+		return fmt.Errorf("expected status %d, got %d", e.Code, res.Status)
+	}
+	if e.Message != "" && !strings.Contains(res.Message, e.Message) {
+		return fmt.Errorf("expected message containing %q, got %q", e.Message, res.Message)
+	}
+	if e.Payload != nil {
+		return checkPayload(e.Payload, res.Payload)
+	}
+	return nil
+}
+
+// checkPayload compares payloads as JSON when both are JSON, as text otherwise
+func checkPayload(expected interface{}, payload []byte) error {
+	var want []byte
+	if s, ok := expected.(string); ok {
+		want = []byte(s)
+	} else {
+		b, err := json.Marshal(expected)
+		if err != nil {
+			return err
+		}
+		want = b
+	}
+
+	var wantValue, gotValue interface{}
+	if json.Unmarshal(want, &wantValue) == nil && json.Unmarshal(payload, &gotValue) == nil {
+		if !reflect.DeepEqual(wantValue, gotValue) {
+			return fmt.Errorf("expected payload %s, got %s", want, payload)
+		}
+		return nil
+	}
+	if string(want) != string(payload) {
+		return fmt.Errorf("expected payload %q, got %q", want, payload)
+	}
+	return nil
+}
+
+// Parse reads a scenario in the format given by the file extension of name
+func Parse(name string, data []byte) (*Scenario, error) {
+	goscenario := new(Scenario)
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		err = json.Unmarshal(data, goscenario)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, goscenario)
+	default:
+		return nil, fmt.Errorf("%s: unknown scenario format", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+
+	if goscenario.Name == "" {
+		goscenario.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	if len(goscenario.Steps) == 0 {
+		return nil, fmt.Errorf("%s: a scenario needs at least one step", name)
+	}
+	for i, gostep := range goscenario.Steps {
+		if gostep.Function == "" {
+			return nil, fmt.Errorf("%s: step %d has no function", name, i+1)
+		}
+	}
+	return goscenario, nil
+}
+
+// Load reads a scenario file
+func Load(path string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// LoadGlob reads the scenario files matching pattern
+func LoadGlob(pattern string) ([]*Scenario, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("no scenario matches " + pattern)
+	}
+
+	goscenarios := []*Scenario{}
+	for _, path := range paths {
+		goscenario, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		goscenarios = append(goscenarios, goscenario)
+	}
+	return goscenarios, nil
+}
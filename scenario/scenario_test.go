@@ -0,0 +1,77 @@
+package scenario
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlScenario = `
+name: add an owner
+init: ['{"Registrars":["Registrar"]}']
+steps:
+  - function: AddOwner
+    invoker: Registrar
+    timestamp: 2018-01-01T12:34:56Z
+    args: [{Id: Alice}]
+    expect:
+      payload: {Id: Alice}
+`
+
+// OK1: YAML and JSON scenarios decode alike
+func TestParse_OK1(t *testing.T) {
+	goscenario, err := Parse("owner.yaml", []byte(yamlScenario))
+	if assert.NoError(t, err) && assert.Len(t, goscenario.Steps, 1) {
+		gostep := goscenario.Steps[0]
+		assert.Equal(t, "Registrar", gostep.Invoker)
+		assert.Equal(t, 2018, gostep.Timestamp.Year())
+		args, err := encodeArgs(gostep.Function, gostep.Args)
+		if assert.NoError(t, err) {
+			assert.Equal(t, `{"Id":"Alice"}`, string(args[1]))
+		}
+	}
+
+	goscenario, err = Parse("owner.json", []byte(
+		`{"steps":[{"function":"GetOwner","args":["\"Alice\""],"expect":{"status":"error"}}]}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "owner", goscenario.Name)
+		assert.Equal(t, StatusError, goscenario.Steps[0].Expect.Status)
+	}
+}
+
+// NG1: scenarios without steps or functions, or of unknown formats
+func TestParse_NG1(t *testing.T) {
+	_, err := Parse("empty.yaml", []byte(`name: empty`))
+	assert.Error(t, err)
+	_, err = Parse("nofunc.json", []byte(`{"steps":[{"args":["1"]}]}`))
+	assert.Error(t, err)
+	_, err = Parse("steps.txt", []byte(yamlScenario))
+	assert.Error(t, err)
+}
+
+// OK1: payloads compare as JSON
+func TestCheck_OK1(t *testing.T) {
+	res := pb.Response{Status: shim.OK, Payload: []byte(`{"Id":"Alice","Type":"person"}`)}
+	e := &Expect{Payload: map[string]interface{}{"Type": "person", "Id": "Alice"}}
+	assert.NoError(t, e.Check(res))
+	e = &Expect{Payload: `{ "Type":"person", "Id":"Alice" }`}
+	assert.NoError(t, e.Check(res))
+
+	res = pb.Response{Status: shim.ERROR, Message: "Owner with Id = Alice does not exist"}
+	e = &Expect{Status: StatusError, Code: shim.ERROR, Message: "does not exist"}
+	assert.NoError(t, e.Check(res))
+}
+
+// NG1: mismatching status, message or payload
+func TestCheck_NG1(t *testing.T) {
+	res := pb.Response{Status: shim.OK, Payload: []byte(`{"Id":"Alice"}`)}
+	assert.Error(t, (&Expect{Status: StatusError}).Check(res))
+	assert.Error(t, (&Expect{Payload: `{"Id":"Bob"}`}).Check(res))
+	assert.Error(t, (&Expect{Status: "maybe"}).Check(res))
+
+	res = pb.Response{Status: shim.ERROR, Message: "invalid house"}
+	assert.Error(t, (&Expect{}).Check(res))
+	assert.Error(t, (&Expect{Status: StatusError, Message: "frozen"}).Check(res))
+}
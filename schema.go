@@ -0,0 +1,352 @@
+package cc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// legacyVersion is the schema version of documents stored as bare JSON,
+// before documents were wrapped with their version
+const legacyVersion = 1
+
+// schemaVersions holds the current schema version of every document type.
+// Document types are the composite key prefixes they are stored under.
+var schemaVersions = map[string]int{
+	prefixOwner:           3, //version 3 enrolls the Owner's Id with its Org
+	prefixHouse:           4, //version 3 is filed under its Owner in the OwnerHouse index, version 4 has a whole Price
+	prefixRole:            2,
+	prefixHouseEdit:       2,
+	prefixConfig:          1,
+	prefixHouseIndex:      1,
+	prefixTaxSchedule:     1,
+	prefixTransferReceipt: 1,
+	prefixAppraisal:       1,
+	prefixDocument:        1,
+	prefixApproval:        2, //version 2 records the agreed Price of a transfer
+	prefixHold:            1,
+	prefixEstate:          1,
+	prefixSignatory:       1,
+	prefixSignatoryChange: 1,
+	prefixDelegation:      1,
+	prefixBroker:          1,
+	prefixListing:         1,
+	prefixReceivable:      1,
+	prefixLease:           1,
+	prefixRentPayment:     2, //version 2 records who confirmed the payment
+	prefixEnrollment:      1,
+}
+
+// upgraders[docType][v] converts the data of version v to version v+1.
+// Versions without an upgrader keep their data as it is.
+var upgraders = map[string]map[int]func(json.RawMessage) (json.RawMessage, error){
+	prefixHouse:       {3: upgradeHouse},
+	prefixRentPayment: {1: upgradeRentPayment},
+}
+
+// reindexers[docType] files a document MigrateBatch rewrites in the indexes
+// of its type, which documents stored before an index existed are missing
+var reindexers = map[string]func(shim.ChaincodeStubInterface, json.RawMessage) error{
+	prefixOwner: reindexOwner,
+	prefixHouse: reindexHouse,
+}
+
+// document wraps every stored value with its schema version
+type document struct {
+	Version int
+	Data    json.RawMessage
+}
+
+// marshalDoc wraps v in a document of the current schema version
+func marshalDoc(docType string, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&document{schemaVersions[docType], data})
+}
+
+// parseDoc returns the schema version and the data of a stored value
+func parseDoc(jsonBytes []byte) (int, json.RawMessage, error) {
+	doc := new(document)
+	err := json.Unmarshal(jsonBytes, doc)
+	if err != nil {
+		return 0, nil, err
+	}
+	if doc.Version == 0 || doc.Data == nil {
+		return legacyVersion, jsonBytes, nil
+	}
+	return doc.Version, doc.Data, nil
+}
+
+// upgradeDoc brings the data of a stored value to the current schema version
+func upgradeDoc(docType string, jsonBytes []byte) (json.RawMessage, int, error) {
+	version, data, err := parseDoc(jsonBytes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	current := schemaVersions[docType]
+	if version > current {
+		return nil, 0, fmt.Errorf("%s of schema version %d is newer than this chaincode (%d)",
+			docType, version, current)
+	}
+	for v := version; v < current; v++ {
+		if upgrade := upgraders[docType][v]; upgrade != nil {
+			data, err = upgrade(data)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	return data, version, nil
+}
+
+// unmarshalDoc decodes a stored value of any schema version into v
+func unmarshalDoc(docType string, jsonBytes []byte, v interface{}) error {
+	data, _, err := upgradeDoc(docType, jsonBytes)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// putDoc stores v under the composite key docType + attributes
+func putDoc(stub shim.ChaincodeStubInterface, docType string, attributes []string,
+	v interface{}) error {
+	key, err := stub.CreateCompositeKey(docType, attributes)
+	if err != nil {
+		return err
+	}
+
+	jsondoc, err := marshalDoc(docType, v)
+	if err != nil {
+		return err
+	}
+
+	return stub.PutState(key, jsondoc)
+}
+
+// StaleKeys is a page of the keys of documents stored in an older schema
+// version. Bookmark is passed to the next call until it is empty.
+type StaleKeys struct {
+	Keys     []string
+	Bookmark string
+}
+
+// MigrationResult reports what MigrateBatch did. Unenrolled lists the Owners
+// it migrated that have no organization yet, for a registrar to Enroll.
+type MigrationResult struct {
+	Scanned    int
+	Migrated   int
+	Unenrolled []string
+}
+
+// MigrationStatus counts the stored documents of a type at a schema version
+type MigrationStatus struct {
+	DocType string
+	Version int
+	Current bool
+	Count   int
+}
+
+// migrationDocTypes lists the document types in ledger key order
+func migrationDocTypes() []string {
+	docTypes := []string{}
+	for docType := range schemaVersions {
+		docTypes = append(docTypes, docType)
+	}
+	sort.Strings(docTypes)
+	return docTypes
+}
+
+// Lists the keys of the documents of docType stored in an older schema
+// version, looking at pageSize documents from bookmark on. The pages are for
+// MigrateBatch: Fabric pages only queries that do not write.
+func (t *HouseContractCC) ListStaleKeys(stub shim.ChaincodeStubInterface,
+	docType string, bookmark string, pageSize int) (*StaleKeys, error) {
+	logger := shim.NewLogger("ListStaleKeys")
+	logger.Infof("ListStaleKeys: docType = %s, bookmark = %q, pageSize = %d", docType, bookmark, pageSize)
+
+	if _, found := schemaVersions[docType]; !found {
+		mes := fmt.Sprintf("unknown document type: %q", docType)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+	if pageSize < 1 {
+		mes := fmt.Sprintf("page size must be positive: %d", pageSize)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	iter, metadata, err := stub.GetStateByPartialCompositeKeyWithPagination(docType, []string{},
+		int32(pageSize), bookmark)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+	if iter == nil || metadata == nil {
+		mes := "paged queries are not supported by this peer"
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+	defer iter.Close()
+
+	stale := &StaleKeys{Keys: []string{}, Bookmark: metadata.Bookmark}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		version, _, err := parseDoc(kv.Value)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		if version != schemaVersions[docType] {
+			stale.Keys = append(stale.Keys, kv.Key)
+		}
+	}
+
+	logger.Infof("%d of %d documents stale", len(stale.Keys), metadata.FetchedRecordsCount)
+	return stale, nil
+}
+
+// Rewrites the stored documents under keys in the current schema version,
+// as listed by ListStaleKeys. Documents already current are left alone.
+// Admin only.
+func (t *HouseContractCC) MigrateBatch(stub shim.ChaincodeStubInterface,
+	keys []string) (*MigrationResult, error) {
+	logger := shim.NewLogger("MigrateBatch")
+	logger.Infof("MigrateBatch: %d keys", len(keys))
+
+	if _, err := requireRole(stub, logger, roleAdmin); err != nil {
+		return nil, err
+	}
+	if err := checkBatchSize(logger, len(keys)); err != nil {
+		return nil, err
+	}
+
+	result := &MigrationResult{Unenrolled: []string{}}
+	indexed := 0
+	for _, key := range keys {
+		docType, _, err := stub.SplitCompositeKey(key)
+		if _, found := schemaVersions[docType]; err != nil || !found {
+			mes := fmt.Sprintf("not a document key: %q", key)
+			logger.Warning(mes)
+			return nil, errors.New(mes)
+		}
+
+		jsonBytes, err := stub.GetState(key)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		if jsonBytes == nil {
+			continue
+		}
+		result.Scanned++
+
+		data, version, err := upgradeDoc(docType, jsonBytes)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		if version == schemaVersions[docType] {
+			continue
+		}
+
+		jsondoc, err := json.Marshal(&document{schemaVersions[docType], data})
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		err = stub.PutState(key, jsondoc)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		if reindex := reindexers[docType]; reindex != nil {
+			if err := reindex(stub, data); err != nil {
+				logger.Warning(err.Error())
+				return nil, err
+			}
+		}
+		if docType == prefixHouse && version < ownerHouseIndexVersion {
+			indexed++
+		}
+		if docType == prefixOwner {
+			goowner := new(Owner)
+			if err := json.Unmarshal(data, goowner); err != nil {
+				logger.Warning(err.Error())
+				return nil, err
+			}
+			if goowner.Org == "" {
+				result.Unenrolled = append(result.Unenrolled, goowner.Id)
+			}
+		}
+		result.Migrated++
+	}
+
+	if err := housesIndexed(stub, indexed); err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	logger.Infof("%d of %d documents migrated", result.Migrated, result.Scanned)
+	return result, nil
+}
+
+// Counts the stored documents per type and schema version
+func (t *HouseContractCC) GetMigrationStatus(stub shim.ChaincodeStubInterface) ([]*MigrationStatus,
+	error) {
+	logger := shim.NewLogger("GetMigrationStatus")
+	logger.Info("GetMigrationStatus")
+
+	statuses := []*MigrationStatus{}
+	for _, docType := range migrationDocTypes() {
+		iter, err := stub.GetStateByPartialCompositeKey(docType, []string{})
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+
+		counts := map[int]int{}
+		for iter.HasNext() {
+			kv, err := iter.Next()
+			if err != nil {
+				iter.Close()
+				logger.Warning(err.Error())
+				return nil, err
+			}
+			version, _, err := parseDoc(kv.Value)
+			if err != nil {
+				iter.Close()
+				logger.Warning(err.Error())
+				return nil, err
+			}
+			counts[version]++
+		}
+		iter.Close()
+
+		versions := []int{}
+		for version := range counts {
+			versions = append(versions, version)
+		}
+		sort.Ints(versions)
+		for _, version := range versions {
+			statuses = append(statuses, &MigrationStatus{
+				DocType: docType,
+				Version: version,
+				Current: version == schemaVersions[docType],
+				Count:   counts[version],
+			})
+		}
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,567 @@
+package cc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// OwnerStore keeps the Owners of the registry
+type OwnerStore interface {
+	// GetOwner returns nil if there is no Owner with the Id
+	GetOwner(id string) (*Owner, error)
+	PutOwner(goowner *Owner) error
+	// ListOwners lists the Owners in Id order
+	ListOwners() ([]*Owner, error)
+}
+
+// HouseStore keeps the Houses of the registry
+type HouseStore interface {
+	// GetHouse returns nil if there is no House with the Id
+	GetHouse(id string) (*House, error)
+	PutHouse(gohouse *House) error
+	// ListHouses lists the Houses in Id order
+	ListHouses() ([]*House, error)
+	// ListOwnerHouses lists the Houses whose OwnerId is ownerId, in Id order
+	ListOwnerHouses(ownerId string) ([]*House, error)
+}
+
+func ownerKey(stub shim.ChaincodeStubInterface, id string) (string, error) {
+	return stub.CreateCompositeKey(prefixOwner, []string{id})
+}
+
+func houseKey(stub shim.ChaincodeStubInterface, id string) (string, error) {
+	return stub.CreateCompositeKey(prefixHouse, []string{id})
+}
+
+// prefixOwnerHouse indexes the Houses by OwnerId. Index entries hold no
+// document, only the key.
+const prefixOwnerHouse = "OwnerHouse"
+
+func ownerHouseKey(stub shim.ChaincodeStubInterface, ownerId string, houseId string) (string, error) {
+	return stub.CreateCompositeKey(prefixOwnerHouse, []string{ownerId, houseId})
+}
+
+// ownerHouseIndexVersion is the first House schema version filed in the
+// OwnerHouse index
+const ownerHouseIndexVersion = 3
+
+// prefixHouseIndex records how far the OwnerHouse index is built
+const prefixHouseIndex = "HouseIndex"
+
+// HouseIndex counts the Houses stored before the OwnerHouse index existed
+// that are not filed in it yet. Until none are left, Houses are looked up by
+// Owner with a scan. A count too high only keeps the scan until the next
+// Init counts again.
+type HouseIndex struct {
+	Unindexed int
+}
+
+// getHouseIndex returns nil if the state of the index was never recorded
+func getHouseIndex(stub shim.ChaincodeStubInterface) (*HouseIndex, error) {
+	key, err := stub.CreateCompositeKey(prefixHouseIndex, []string{})
+	if err != nil {
+		return nil, err
+	}
+	jsonBytes, err := stub.GetState(key)
+	if err != nil || jsonBytes == nil {
+		return nil, err
+	}
+	goindex := new(HouseIndex)
+	if err := unmarshalDoc(prefixHouseIndex, jsonBytes, goindex); err != nil {
+		return nil, err
+	}
+	return goindex, nil
+}
+
+// recordHouseIndex counts the Houses missing from the OwnerHouse index,
+// unless the index is already complete
+func recordHouseIndex(stub shim.ChaincodeStubInterface) error {
+	goindex, err := getHouseIndex(stub)
+	if err != nil {
+		return err
+	}
+	if goindex != nil && goindex.Unindexed == 0 {
+		return nil
+	}
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixHouse, []string{})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	goindex = new(HouseIndex)
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		version, _, err := parseDoc(kv.Value)
+		if err != nil {
+			return err
+		}
+		if version < ownerHouseIndexVersion {
+			goindex.Unindexed++
+		}
+	}
+	return putDoc(stub, prefixHouseIndex, []string{}, goindex)
+}
+
+// housesIndexed notes that count Houses missing from the OwnerHouse index
+// have been filed in it
+func housesIndexed(stub shim.ChaincodeStubInterface, count int) error {
+	if count == 0 {
+		return nil
+	}
+	goindex, err := getHouseIndex(stub)
+	if err != nil || goindex == nil || goindex.Unindexed == 0 {
+		return err
+	}
+	goindex.Unindexed -= count
+	if goindex.Unindexed < 0 {
+		goindex.Unindexed = 0
+	}
+	return putDoc(stub, prefixHouseIndex, []string{}, goindex)
+}
+
+// reindexHouse files a migrated House under its Owner
+func reindexHouse(stub shim.ChaincodeStubInterface, data json.RawMessage) error {
+	gohouse := new(House)
+	if err := json.Unmarshal(data, gohouse); err != nil {
+		return err
+	}
+	return indexHouse(stub, gohouse)
+}
+
+// upgradeHouse writes the Price of a House stored before prices were checked
+// as a whole amount where it reads as one, e.g. "3,000" as "3000". Other
+// prices are left for a registrar to correct.
+func upgradeHouse(data json.RawMessage) (json.RawMessage, error) {
+	gohouse := new(House)
+	if err := json.Unmarshal(data, gohouse); err != nil {
+		return nil, err
+	}
+	whole := *gohouse
+	whole.Price = strings.Map(func(r rune) rune {
+		if r == ',' || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, gohouse.Price)
+	if _, err := parsePrice(&whole); err == nil {
+		gohouse.Price = whole.Price
+	}
+	return json.Marshal(gohouse)
+}
+
+// reindexOwner enrolls the Id of a migrated Owner with its organization
+func reindexOwner(stub shim.ChaincodeStubInterface, data json.RawMessage) error {
+	goowner := new(Owner)
+	if err := json.Unmarshal(data, goowner); err != nil {
+		return err
+	}
+	if goowner.Org == "" {
+		return nil
+	}
+	return enroll(stub, goowner.Id, goowner.Org)
+}
+
+// indexHouse files a House under its Owner
+func indexHouse(stub shim.ChaincodeStubInterface, gohouse *House) error {
+	key, err := ownerHouseKey(stub, gohouse.OwnerId, gohouse.Id)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, []byte{0})
+}
+
+// LedgerStore keeps Owners and Houses in the world state as versioned
+// documents
+type LedgerStore struct {
+	stub shim.ChaincodeStubInterface
+}
+
+func NewLedgerStore(stub shim.ChaincodeStubInterface) *LedgerStore {
+	return &LedgerStore{stub}
+}
+
+// getDoc decodes the document under key into v and tells whether it exists
+func (s *LedgerStore) getDoc(docType string, key string, v interface{}) (bool, error) {
+	jsonBytes, err := s.stub.GetState(key)
+	if err != nil {
+		return false, err
+	}
+	if jsonBytes == nil {
+		return false, nil
+	}
+	return true, unmarshalDoc(docType, jsonBytes, v)
+}
+
+// listDocs decodes every document of docType, calling add with each
+func (s *LedgerStore) listDocs(docType string, add func(jsonBytes []byte) error) error {
+	iter, err := s.stub.GetStateByPartialCompositeKey(docType, []string{})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		if err := add(kv.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LedgerStore) GetOwner(id string) (*Owner, error) {
+	key, err := ownerKey(s.stub, id)
+	if err != nil {
+		return nil, err
+	}
+	goowner := new(Owner)
+	found, err := s.getDoc(prefixOwner, key, goowner)
+	if err != nil || !found {
+		return nil, err
+	}
+	return goowner, nil
+}
+
+// PutOwner stores an Owner and enrolls its Id with the Owner's organization.
+// Owners stored before organizations were required have none to enroll with
+// until a registrar enrolls them.
+func (s *LedgerStore) PutOwner(goowner *Owner) error {
+	if goowner.Org != "" {
+		if err := enroll(s.stub, goowner.Id, goowner.Org); err != nil {
+			return err
+		}
+	}
+	return putDoc(s.stub, prefixOwner, []string{goowner.Id}, goowner)
+}
+
+func (s *LedgerStore) ListOwners() ([]*Owner, error) {
+	goowners := []*Owner{}
+	err := s.listDocs(prefixOwner, func(jsonBytes []byte) error {
+		goowner := new(Owner)
+		if err := unmarshalDoc(prefixOwner, jsonBytes, goowner); err != nil {
+			return err
+		}
+		goowners = append(goowners, goowner)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return goowners, nil
+}
+
+func (s *LedgerStore) GetHouse(id string) (*House, error) {
+	key, err := houseKey(s.stub, id)
+	if err != nil {
+		return nil, err
+	}
+	gohouse := new(House)
+	found, err := s.getDoc(prefixHouse, key, gohouse)
+	if err != nil || !found {
+		return nil, err
+	}
+	return gohouse, nil
+}
+
+// PutHouse stores the House and moves it in the index to its current Owner
+func (s *LedgerStore) PutHouse(gohouse *House) error {
+	key, err := houseKey(s.stub, gohouse.Id)
+	if err != nil {
+		return err
+	}
+	jsonBytes, err := s.stub.GetState(key)
+	if err != nil {
+		return err
+	}
+	var current *House
+	if jsonBytes != nil {
+		version, _, err := parseDoc(jsonBytes)
+		if err != nil {
+			return err
+		}
+		if version < ownerHouseIndexVersion {
+			if err := housesIndexed(s.stub, 1); err != nil {
+				return err
+			}
+		}
+		current = new(House)
+		if err := unmarshalDoc(prefixHouse, jsonBytes, current); err != nil {
+			return err
+		}
+	}
+	if current != nil && current.OwnerId != gohouse.OwnerId {
+		key, err := ownerHouseKey(s.stub, current.OwnerId, current.Id)
+		if err != nil {
+			return err
+		}
+		if err := s.stub.DelState(key); err != nil {
+			return err
+		}
+	}
+	if err := indexHouse(s.stub, gohouse); err != nil {
+		return err
+	}
+	return putDoc(s.stub, prefixHouse, []string{gohouse.Id}, gohouse)
+}
+
+func (s *LedgerStore) ListHouses() ([]*House, error) {
+	return s.listHouses(func(*House) bool { return true })
+}
+
+// ListOwnerHouses reads the Houses the index files under ownerId, or scans
+// all Houses while some are not filed in the index yet
+func (s *LedgerStore) ListOwnerHouses(ownerId string) ([]*House, error) {
+	goindex, err := getHouseIndex(s.stub)
+	if err != nil {
+		return nil, err
+	}
+	if goindex == nil || goindex.Unindexed > 0 {
+		return s.listHouses(func(gohouse *House) bool { return gohouse.OwnerId == ownerId })
+	}
+
+	iter, err := s.stub.GetStateByPartialCompositeKey(prefixOwnerHouse, []string{ownerId})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	gohouses := []*House{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := s.stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		gohouse, err := s.GetHouse(attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		if gohouse == nil || gohouse.OwnerId != ownerId {
+			return nil, fmt.Errorf("the Owner index is out of date at %s", kv.Key)
+		}
+		gohouses = append(gohouses, gohouse)
+	}
+	return gohouses, nil
+}
+
+func (s *LedgerStore) listHouses(keep func(*House) bool) ([]*House, error) {
+	gohouses := []*House{}
+	err := s.listDocs(prefixHouse, func(jsonBytes []byte) error {
+		gohouse := new(House)
+		if err := unmarshalDoc(prefixHouse, jsonBytes, gohouse); err != nil {
+			return err
+		}
+		if keep(gohouse) {
+			gohouses = append(gohouses, gohouse)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gohouses, nil
+}
+
+// MemoryStore keeps Owners and Houses in memory, for tests and off-chain
+// tools. It hands out copies, so callers cannot change what it holds.
+type MemoryStore struct {
+	owners      map[string]*Owner
+	houses      map[string]*House
+	ownerHouses map[string]map[string]bool //House Ids by OwnerId
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		owners:      map[string]*Owner{},
+		houses:      map[string]*House{},
+		ownerHouses: map[string]map[string]bool{},
+	}
+}
+
+func copyHouse(gohouse *House) *House {
+	c := *gohouse
+	if gohouse.Shares != nil {
+		c.Shares = make([]*Share, 0, len(gohouse.Shares))
+		for _, share := range gohouse.Shares {
+			s := *share
+			c.Shares = append(c.Shares, &s)
+		}
+	}
+	return &c
+}
+
+func (s *MemoryStore) GetOwner(id string) (*Owner, error) {
+	goowner, ok := s.owners[id]
+	if !ok {
+		return nil, nil
+	}
+	c := *goowner
+	return &c, nil
+}
+
+func (s *MemoryStore) PutOwner(goowner *Owner) error {
+	c := *goowner
+	s.owners[goowner.Id] = &c
+	return nil
+}
+
+func (s *MemoryStore) ListOwners() ([]*Owner, error) {
+	ids := make([]string, 0, len(s.owners))
+	for id := range s.owners {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	goowners := []*Owner{}
+	for _, id := range ids {
+		c := *s.owners[id]
+		goowners = append(goowners, &c)
+	}
+	return goowners, nil
+}
+
+func (s *MemoryStore) GetHouse(id string) (*House, error) {
+	gohouse, ok := s.houses[id]
+	if !ok {
+		return nil, nil
+	}
+	return copyHouse(gohouse), nil
+}
+
+// PutHouse stores the House and moves it in the index to its current Owner
+func (s *MemoryStore) PutHouse(gohouse *House) error {
+	if current, ok := s.houses[gohouse.Id]; ok {
+		delete(s.ownerHouses[current.OwnerId], gohouse.Id)
+	}
+	s.houses[gohouse.Id] = copyHouse(gohouse)
+	if s.ownerHouses[gohouse.OwnerId] == nil {
+		s.ownerHouses[gohouse.OwnerId] = map[string]bool{}
+	}
+	s.ownerHouses[gohouse.OwnerId][gohouse.Id] = true
+	return nil
+}
+
+func (s *MemoryStore) housesOf(ids map[string]*House) []*House {
+	keys := make([]string, 0, len(ids))
+	for id := range ids {
+		keys = append(keys, id)
+	}
+	sort.Strings(keys)
+
+	gohouses := []*House{}
+	for _, id := range keys {
+		gohouses = append(gohouses, copyHouse(ids[id]))
+	}
+	return gohouses
+}
+
+func (s *MemoryStore) ListHouses() ([]*House, error) {
+	return s.housesOf(s.houses), nil
+}
+
+func (s *MemoryStore) ListOwnerHouses(ownerId string) ([]*House, error) {
+	owned := map[string]*House{}
+	for id := range s.ownerHouses[ownerId] {
+		owned[id] = s.houses[id]
+	}
+	return s.housesOf(owned), nil
+}
+
+// RegisterOwner adds a new Owner
+func RegisterOwner(owners OwnerStore, goowner *Owner) error {
+	if err := checkOwnerType(goowner); err != nil {
+		return err
+	}
+	if err := checkOwnerOrg(goowner); err != nil {
+		return err
+	}
+
+	current, err := owners.GetOwner(goowner.Id)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		return fmt.Errorf("an Owner with Id = %s alerady exists", goowner.Id)
+	}
+
+	return owners.PutOwner(goowner)
+}
+
+// RegisterHouse adds a new House of an existing Owner and returns the Owner.
+// A new House has a single Owner: co-ownership only comes from estates.
+func RegisterHouse(owners OwnerStore, houses HouseStore, gohouse *House) (*Owner, error) {
+	gohouse.Shares = nil
+	if _, err := parsePrice(gohouse); err != nil {
+		return nil, err
+	}
+
+	current, err := houses.GetHouse(gohouse.Id)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return nil, fmt.Errorf("House with Id = %s already exists", gohouse.Id)
+	}
+
+	goowner, err := owners.GetOwner(gohouse.OwnerId)
+	if err != nil {
+		return nil, err
+	}
+	if goowner == nil {
+		return nil, errors.New("Validation of the House failed")
+	}
+
+	err = houses.PutHouse(gohouse)
+	if err != nil {
+		return nil, err
+	}
+	return goowner, nil
+}
+
+// Reassign hands gohouse over whole to the existing Owner newownerId and
+// returns that Owner. Every holder of the House must be among consenting, the
+// Owners who agreed to the transfer. Nothing is stored: the caller does once
+// its own checks pass.
+func Reassign(owners OwnerStore, gohouse *House, newownerId string, consenting []string) (*Owner, error) {
+	buyer, err := owners.GetOwner(newownerId)
+	if err != nil {
+		return nil, err
+	}
+	if buyer == nil {
+		return nil, fmt.Errorf("new Owner with Id = %s was not found", newownerId)
+	}
+
+	for _, share := range holders(gohouse) {
+		agreed := false
+		for _, id := range consenting {
+			if id == share.OwnerId {
+				agreed = true
+			}
+		}
+		if !agreed {
+			return nil, fmt.Errorf("%s holds House with Id = %s and has not agreed to its transfer",
+				share.OwnerId, gohouse.Id)
+		}
+	}
+
+	gohouse.OwnerId = newownerId
+	gohouse.Shares = nil
+	return buyer, nil
+}
@@ -0,0 +1,364 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	prefixTaxSchedule          = "TaxSchedule"
+	prefixTransferReceipt      = "TransferReceipt"
+	prefixTransferReceiptOwner = "TransferReceiptOwner" //index: owner -> receipt
+)
+
+const roleTaxAuthority = "taxauthority"
+
+// feeRegistry names the registry fee in Config.FeePercentages
+const feeRegistry = "registry"
+
+// TaxBracket applies RatePercent to the whole price of Houses priced up to
+// UpTo. The last bracket may have UpTo = 0 for "no upper bound".
+type TaxBracket struct {
+	UpTo        int64
+	RatePercent float64
+}
+
+// TaxSchedule holds the acquisition tax rules, set by the tax authority
+type TaxSchedule struct {
+	Brackets                  []TaxBracket
+	FirstHomeMaxPrice         int64   //first homes priced up to this get the reduction
+	FirstHomeReductionPercent float64 //100 exempts first homes completely
+	CorporateSurchargePercent float64 //added to the rate for corporate buyers
+}
+
+// TransferReceipt records the tax and fee owed for a transfer
+type TransferReceipt struct {
+	Id                        string //transaction Id
+	HouseId                   string
+	SellerId                  string
+	BuyerId                   string
+	Price                     int64
+	TaxRatePercent            float64
+	FirstHome                 bool
+	FirstHomeReductionPercent float64
+	CorporateSurchargePercent float64
+	Tax                       int64
+	RegistryFeePercent        float64
+	RegistryFee               int64
+	Total                     int64
+	Documents                 []string //SHA-256 of the documents the transfer relied on
+	ApprovalId                string   `json:",omitempty"` //the Approval of a high value transfer
+	AgentId                   string   `json:",omitempty"` //who acted for the seller, if not the seller in person
+	Timestamp                 time.Time
+}
+
+func validateTaxSchedule(goschedule *TaxSchedule) error {
+	var last int64
+	for i, bracket := range goschedule.Brackets {
+		if bracket.RatePercent < 0 || bracket.RatePercent > 100 {
+			return fmt.Errorf("bracket %d: rate out of range: %g%%", i, bracket.RatePercent)
+		}
+		if bracket.UpTo == 0 {
+			if i != len(goschedule.Brackets)-1 {
+				return fmt.Errorf("bracket %d: only the last bracket may be unbounded", i)
+			}
+			continue
+		}
+		if bracket.UpTo <= last {
+			return fmt.Errorf("bracket %d: upper bounds must increase", i)
+		}
+		last = bracket.UpTo
+	}
+	if goschedule.FirstHomeReductionPercent < 0 || goschedule.FirstHomeReductionPercent > 100 {
+		return fmt.Errorf("first home reduction out of range: %g%%", goschedule.FirstHomeReductionPercent)
+	}
+	if goschedule.CorporateSurchargePercent < 0 || goschedule.CorporateSurchargePercent > 100 {
+		return fmt.Errorf("corporate surcharge out of range: %g%%", goschedule.CorporateSurchargePercent)
+	}
+	return nil
+}
+
+// rate returns the tax rate of the bracket the price falls in
+func (s *TaxSchedule) rate(price int64) float64 {
+	for _, bracket := range s.Brackets {
+		if bracket.UpTo == 0 || price <= bracket.UpTo {
+			return bracket.RatePercent
+		}
+	}
+	return 0
+}
+
+// percentOf returns percent of amount, rounded to the nearest unit
+func percentOf(amount int64, percent float64) int64 {
+	return int64(math.Round(float64(amount) * percent / 100))
+}
+
+// parsePrice reads House.Price as a whole amount
+func parsePrice(gohouse *House) (int64, error) {
+	price, err := strconv.ParseInt(gohouse.Price, 10, 64)
+	if err != nil || price < 0 {
+		return 0, fmt.Errorf("invalid Price of House with Id = %s, a whole amount is expected: %q",
+			gohouse.Id, gohouse.Price)
+	}
+	return price, nil
+}
+
+func getTaxSchedule(stub shim.ChaincodeStubInterface) (*TaxSchedule, error) {
+	key, err := stub.CreateCompositeKey(prefixTaxSchedule, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+
+	goschedule := &TaxSchedule{Brackets: []TaxBracket{}}
+	if jsonBytes == nil {
+		return goschedule, nil
+	}
+	err = unmarshalDoc(prefixTaxSchedule, jsonBytes, goschedule)
+	if err != nil {
+		return nil, err
+	}
+	return goschedule, nil
+}
+
+// countOwnerHouses counts the Houses currently owned by ownerId
+func countOwnerHouses(stub shim.ChaincodeStubInterface, ownerId string) (int, error) {
+	gohouses, err := NewLedgerStore(stub).ListOwnerHouses(ownerId)
+	if err != nil {
+		return 0, err
+	}
+	return len(gohouses), nil
+}
+
+// computeTransferReceipt evaluates the tax schedule and the registry fee for
+// the transfer of gohouse from sellerId to buyer
+func computeTransferReceipt(stub shim.ChaincodeStubInterface, gohouse *House,
+	sellerId string, buyer *Owner) (*TransferReceipt, error) {
+	price, err := parsePrice(gohouse)
+	if err != nil {
+		return nil, err
+	}
+
+	goschedule, err := getTaxSchedule(stub)
+	if err != nil {
+		return nil, err
+	}
+	goconfig, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	owned, err := countOwnerHouses(stub, buyer.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &TransferReceipt{
+		HouseId:            gohouse.Id,
+		SellerId:           sellerId,
+		BuyerId:            buyer.Id,
+		Price:              price,
+		TaxRatePercent:     goschedule.rate(price),
+		FirstHome:          owned == 0 && !buyer.IsCorporate(),
+		RegistryFeePercent: goconfig.FeePercentages[feeRegistry],
+	}
+	if buyer.IsCorporate() {
+		receipt.CorporateSurchargePercent = goschedule.CorporateSurchargePercent
+	}
+	if receipt.FirstHome && price <= goschedule.FirstHomeMaxPrice {
+		receipt.FirstHomeReductionPercent = goschedule.FirstHomeReductionPercent
+	}
+
+	tax := percentOf(price, receipt.TaxRatePercent+receipt.CorporateSurchargePercent)
+	receipt.Tax = tax - percentOf(tax, receipt.FirstHomeReductionPercent)
+	receipt.RegistryFee = percentOf(price, receipt.RegistryFeePercent)
+	receipt.Total = receipt.Tax + receipt.RegistryFee
+
+	return receipt, nil
+}
+
+// addTransferReceipt stores a receipt and indexes it by seller and buyer
+func addTransferReceipt(stub shim.ChaincodeStubInterface, receipt *TransferReceipt) error {
+	now, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	receipt.Id = stub.GetTxID()
+	receipt.Timestamp = now
+
+	err = putDoc(stub, prefixTransferReceipt, []string{receipt.Id}, receipt)
+	if err != nil {
+		return err
+	}
+
+	for _, ownerId := range []string{receipt.SellerId, receipt.BuyerId} {
+		key, err := stub.CreateCompositeKey(prefixTransferReceiptOwner,
+			[]string{ownerId, receipt.Id})
+		if err != nil {
+			return err
+		}
+		err = stub.PutState(key, []byte{0x00})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getTransferReceipt(stub shim.ChaincodeStubInterface, id string) (*TransferReceipt, error) {
+	key, err := stub.CreateCompositeKey(prefixTransferReceipt, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBytes == nil {
+		return nil, fmt.Errorf("TransferReceipt with Id = %s was not found", id)
+	}
+
+	receipt := new(TransferReceipt)
+	err = unmarshalDoc(prefixTransferReceipt, jsonBytes, receipt)
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// Replaces the tax schedule. Tax authority only.
+func (t *HouseContractCC) UpdateTaxSchedule(stub shim.ChaincodeStubInterface,
+	goschedule *TaxSchedule) error {
+	logger := shim.NewLogger("UpdateTaxSchedule")
+	logger.Infof("UpdateTaxSchedule: schedule = %+v", goschedule)
+
+	if _, err := requireRole(stub, logger, roleTaxAuthority); err != nil {
+		return err
+	}
+
+	if err := validateTaxSchedule(goschedule); err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	err := putDoc(stub, prefixTaxSchedule, []string{}, goschedule)
+	if err != nil {
+		logger.Warning(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Returns the current tax schedule
+func (t *HouseContractCC) GetTaxSchedule(stub shim.ChaincodeStubInterface) (*TaxSchedule, error) {
+	logger := shim.NewLogger("GetTaxSchedule")
+	logger.Info("GetTaxSchedule")
+
+	goschedule, err := getTaxSchedule(stub)
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	return goschedule, nil
+}
+
+// Lists the receipts of transfers an Owner sold or bought in, oldest first
+func (t *HouseContractCC) ListOwnerTransferReceipts(stub shim.ChaincodeStubInterface,
+	ownerId string) ([]*TransferReceipt, error) {
+	logger := shim.NewLogger("ListOwnerTransferReceipts")
+	logger.Infof("ListOwnerTransferReceipts: Owner Id = %s", ownerId)
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixTransferReceiptOwner, []string{ownerId})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	receipts := []*TransferReceipt{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		_, attributes, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		receipt, err := getTransferReceipt(stub, attributes[1])
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	sort.Slice(receipts, func(i, j int) bool {
+		return receipts[i].Timestamp.Before(receipts[j].Timestamp)
+	})
+
+	logger.Infof("%d %s found", len(receipts), "TransferReceipt")
+	return receipts, nil
+}
+
+// Lists the receipts of transfers made in [from, to), oldest first
+func (t *HouseContractCC) ListTransferReceipts(stub shim.ChaincodeStubInterface,
+	from time.Time, to time.Time) ([]*TransferReceipt, error) {
+	logger := shim.NewLogger("ListTransferReceipts")
+	logger.Infof("ListTransferReceipts: from = %s, to = %s", from, to)
+
+	if !from.Before(to) {
+		mes := fmt.Sprintf("empty period: from %s to %s", from, to)
+		logger.Warning(mes)
+		return nil, errors.New(mes)
+	}
+
+	iter, err := stub.GetStateByPartialCompositeKey(prefixTransferReceipt, []string{})
+	if err != nil {
+		logger.Warning(err.Error())
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	receipts := []*TransferReceipt{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		receipt := new(TransferReceipt)
+		err = unmarshalDoc(prefixTransferReceipt, kv.Value, receipt)
+		if err != nil {
+			logger.Warning(err.Error())
+			return nil, err
+		}
+		if !receipt.Timestamp.Before(from) && receipt.Timestamp.Before(to) {
+			receipts = append(receipts, receipt)
+		}
+	}
+
+	sort.Slice(receipts, func(i, j int) bool {
+		return receipts[i].Timestamp.Before(receipts[j].Timestamp)
+	})
+
+	logger.Infof("%d %s found", len(receipts), "TransferReceipt")
+	return receipts, nil
+}